@@ -15,7 +15,7 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	appl, err := app.New(ctx)
+	appl, err := app.New(ctx, os.Args[1:])
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to initialize app", "err", err)
 		os.Exit(1)
@@ -7,22 +7,26 @@ import (
 	"os"
 
 	"go-services/backend/internal/config"
+	libconfig "go-services/library/config"
 	"go-services/library/kafka"
+	"go-services/library/kafka/dedup"
 )
 
 type App struct {
-	Log         *slog.Logger
-	repository  *repository
-	kafkaClient *kafka.Client
+	Log          *slog.Logger
+	repository   *repository
+	kafkaClient  *kafka.Client
+	deduplicator *dedup.Deduplicator
 }
 
-func New(ctx context.Context) (*App, error) {
+func New(ctx context.Context, args []string) (*App, error) {
 	log := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
-	cfg, err := config.New()
+	cfg, err := config.New(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize config: %w", err)
 	}
+	log.InfoContext(ctx, "configuration loaded", "config", libconfig.LogValue(cfg))
 
 	repository, err := newRepository(ctx, cfg)
 	if err != nil {
@@ -35,16 +39,18 @@ func New(ctx context.Context) (*App, error) {
 		return nil, fmt.Errorf("failed to initialize services: %w", err)
 	}
 
-	kafkaClient, err := newKafkaConsumer(cfg, service)
+	deduplicator := newDeduplicator(log, cfg, repository)
+	kafkaClient, err := newKafkaConsumer(ctx, log, cfg, service, deduplicator)
 	if err != nil {
 		repository.Close()
 		return nil, fmt.Errorf("failed to initialize kafka consumer: %w", err)
 	}
 
 	return &App{
-		Log:         log,
-		repository:  repository,
-		kafkaClient: kafkaClient,
+		Log:          log,
+		repository:   repository,
+		kafkaClient:  kafkaClient,
+		deduplicator: deduplicator,
 	}, nil
 }
 
@@ -53,6 +59,14 @@ func (a *App) Run(ctx context.Context) error {
 		return fmt.Errorf("app kafka consumer is not initialized")
 	}
 
+	if a.deduplicator != nil {
+		go func() {
+			if err := a.deduplicator.Run(ctx); err != nil {
+				a.Log.ErrorContext(ctx, "processed message pruning failed", "err", err)
+			}
+		}()
+	}
+
 	return a.kafkaClient.Consumer.Run(ctx)
 }
 
@@ -1,24 +1,52 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
 
 	"go-services/backend/internal/config"
 	"go-services/backend/internal/user"
 	"go-services/library/kafka"
+	"go-services/library/kafka/dedup"
+	"go-services/library/kafka/schemaregistry"
+	"go-services/library/transactor"
 )
 
-func newKafkaConsumer(cfg *config.Config, svc *service) (*kafka.Client, error) {
+func newKafkaConsumer(
+	ctx context.Context,
+	log *slog.Logger,
+	cfg *config.Config,
+	svc *service,
+	deduplicator *dedup.Deduplicator,
+) (*kafka.Client, error) {
+	codec, err := newUserEventCodec(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	password := cfg.Kafka.Password.Func(log, "kafka password")
+	credentials := func(ctx context.Context) (string, string, error) {
+		pass, err := password(ctx)
+		return cfg.Kafka.Username, pass, err
+	}
+
 	kafkaClient, err := kafka.New(
 		cfg.Kafka.BrokerURLs,
 		cfg.Kafka.ConsumerGroupID,
-		kafka.WithAuth(cfg.Kafka.Username, cfg.Kafka.Password, kafka.AuthMechanismScram512),
+		kafka.WithAuthProvider(credentials, kafka.AuthMechanismScram512),
+		kafka.WithOrdering(kafka.OrderingPartition),
+		kafka.WithRetry(kafka.DefaultRetryPolicy()),
+		kafka.WithDeadLetterTopic(cfg.Kafka.DeadLetterTopic),
+		kafka.WithHandlerMiddleware(kafka.Recover(log), kafka.Logging(log), deduplicator.Middleware),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize kafka client: %w", err)
 	}
 
-	eventConsumer := user.NewEventConsumer(svc.UserEventCommandService)
+	eventConsumer := user.NewEventConsumer(svc.UserEventCommandService, codec)
 	if err := kafkaClient.Consumer.AddTopic(cfg.Kafka.UserEventTopic, eventConsumer.HandleRecord); err != nil {
 		kafkaClient.Close()
 		return nil, fmt.Errorf("failed to register user event topic handler: %w", err)
@@ -26,3 +54,38 @@ func newKafkaConsumer(cfg *config.Config, svc *service) (*kafka.Client, error) {
 
 	return kafkaClient, nil
 }
+
+// newUserEventCodec returns the codec of user events. With a schema registry
+// configured, events are validated against the latest JSON Schema of the
+// user event topic; otherwise they are plain JSON.
+func newUserEventCodec(ctx context.Context, cfg *config.Config) (kafka.Codec[user.Event], error) {
+	if cfg.Kafka.SchemaRegistryURL == "" {
+		return kafka.JSONCodec[user.Event]{}, nil
+	}
+
+	registry, err := schemaregistry.NewClient(cfg.Kafka.SchemaRegistryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize schema registry client: %w", err)
+	}
+	codec, err := schemaregistry.LookupJSONCodec[user.Event](
+		ctx,
+		registry,
+		schemaregistry.ValueSubject(cfg.Kafka.UserEventTopic),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user event schema: %w", err)
+	}
+	return codec, nil
+}
+
+// newDeduplicator returns the deduplicator that makes the user event handler
+// idempotent. Processed event IDs are recorded in the kafka_processed_messages
+// table, scoped to the consumer group.
+func newDeduplicator(log *slog.Logger, cfg *config.Config, repo *repository) *dedup.Deduplicator {
+	return dedup.New(
+		log,
+		transactor.NewPGTransactor(log, repo.dbPool),
+		transactor.NewTxAccessor[pgx.Tx](),
+		cfg.Kafka.ConsumerGroupID,
+	)
+}
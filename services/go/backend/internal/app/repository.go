@@ -18,7 +18,7 @@ type repository struct {
 }
 
 func newRepository(ctx context.Context, cfg *config.Config) (*repository, error) {
-	dbPool, err := pgxpool.New(ctx, cfg.DB.ConnectionURL)
+	dbPool, err := pgxpool.New(ctx, string(cfg.DB.ConnectionURL))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect db: %w", err)
 	}
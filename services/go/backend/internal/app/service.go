@@ -19,10 +19,11 @@ type service struct {
 func newService(log *slog.Logger, cfg *config.Config, repo *repository) (*service, error) {
 	userProvider, err := keycloak.NewClient(
 		keycloak.Config{
-			BaseURL:      cfg.Keycloak.BaseURL,
-			Realm:        cfg.Keycloak.Realm,
-			ClientID:     cfg.Keycloak.ClientID,
-			ClientSecret: cfg.Keycloak.ClientSecret,
+			BaseURL:          cfg.Keycloak.BaseURL,
+			Realm:            cfg.Keycloak.Realm,
+			ClientID:         cfg.Keycloak.ClientID,
+			ClientSecret:     "",
+			ClientSecretFunc: cfg.Keycloak.ClientSecret.Func(log, "keycloak client secret"),
 		},
 		http.DefaultClient,
 	)
@@ -1,4 +1,5 @@
 // Package config provides application configuration loading
-// from environment variables. It validates required settings
-// and builds structured configuration objects used by the app.
+// from environment variables, an optional configuration file and
+// command line flags. It validates required settings and builds
+// structured configuration objects used by the app.
 package config
@@ -26,10 +26,14 @@ type tokenProvider interface {
 }
 
 type Config struct {
-	BaseURL      string
-	Realm        string
-	ClientID     string
-	ClientSecret string
+	// ClientSecretFunc, if set, is called for every token request instead of
+	// using ClientSecret, so a rotated client secret is used without a
+	// restart.
+	ClientSecretFunc func(ctx context.Context) (string, error)
+	BaseURL          string
+	Realm            string
+	ClientID         string
+	ClientSecret     string
 }
 
 func (c Config) Validate() error {
@@ -43,7 +47,7 @@ func (c Config) Validate() error {
 	if strings.TrimSpace(c.ClientID) == "" {
 		missing = append(missing, "ClientID")
 	}
-	if strings.TrimSpace(c.ClientSecret) == "" {
+	if strings.TrimSpace(c.ClientSecret) == "" && c.ClientSecretFunc == nil {
 		missing = append(missing, "ClientSecret")
 	}
 	if len(missing) > 0 {
@@ -99,10 +103,34 @@ func NewClient(cfg Config, httpClient *http.Client) (*Client, error) {
 		ClientSecret: cfg.ClientSecret,
 		TokenURL:     cfg.TokenURL(),
 	}
+	if cfg.ClientSecretFunc != nil {
+		return newClient(cfg, httpClient, &secretTokenProvider{
+			config: *oauth2Config,
+			secret: cfg.ClientSecretFunc,
+		}), nil
+	}
 
 	return newClient(cfg, httpClient, oauth2Config), nil
 }
 
+// secretTokenProvider requests client credentials tokens with the client
+// secret current at the time of each request.
+type secretTokenProvider struct {
+	secret func(ctx context.Context) (string, error)
+	config clientcredentials.Config
+}
+
+func (p *secretTokenProvider) Token(ctx context.Context) (*oauth2.Token, error) {
+	secret, err := p.secret(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client secret: %w", err)
+	}
+
+	config := p.config
+	config.ClientSecret = secret
+	return config.Token(ctx)
+}
+
 func newClient(cfg Config, httpClient httpDoer, tokenProvider tokenProvider) *Client {
 	return &Client{
 		cfg:           cfg,
@@ -64,7 +64,7 @@ func TestGetUserByID(t *testing.T) {
 	defer ts.Close()
 
 	client := newClient(
-		Config{BaseURL: ts.URL, Realm: "playground", ClientID: "", ClientSecret: ""},
+		Config{BaseURL: ts.URL, Realm: "playground", ClientID: "", ClientSecret: "", ClientSecretFunc: nil},
 		ts.Client(),
 		fakeTokenProvider{token: &oauth2.Token{AccessToken: "access-token"}, err: nil},
 	)
@@ -80,7 +80,7 @@ func TestGetUserByID_TokenError(t *testing.T) {
 	require.NoError(t, err, "failed to generate user id")
 
 	client := newClient(
-		Config{BaseURL: "http://keycloak", Realm: "playground", ClientID: "", ClientSecret: ""},
+		Config{BaseURL: "http://keycloak", Realm: "playground", ClientID: "", ClientSecret: "", ClientSecretFunc: nil},
 		http.DefaultClient,
 		fakeTokenProvider{token: nil, err: errors.New("boom")},
 	)
@@ -103,7 +103,7 @@ func TestGetUserByID_NotFound(t *testing.T) {
 	defer ts.Close()
 
 	client := newClient(
-		Config{BaseURL: ts.URL, Realm: "playground", ClientID: "", ClientSecret: ""},
+		Config{BaseURL: ts.URL, Realm: "playground", ClientID: "", ClientSecret: "", ClientSecretFunc: nil},
 		ts.Client(),
 		fakeTokenProvider{token: &oauth2.Token{AccessToken: "access-token"}, err: nil},
 	)
@@ -127,7 +127,7 @@ func TestGetUserByID_InvalidPayload(t *testing.T) {
 	defer ts.Close()
 
 	client := newClient(
-		Config{BaseURL: ts.URL, Realm: "playground", ClientID: "", ClientSecret: ""},
+		Config{BaseURL: ts.URL, Realm: "playground", ClientID: "", ClientSecret: "", ClientSecretFunc: nil},
 		ts.Client(),
 		fakeTokenProvider{token: &oauth2.Token{AccessToken: "access-token"}, err: nil},
 	)
@@ -139,3 +139,35 @@ func TestGetUserByID_InvalidPayload(t *testing.T) {
 		assert.Equal(t, appErr.Code, apperror.CodeSerializationError, "app error code")
 	}
 }
+
+func TestNewClient_ClientSecretFunc(t *testing.T) {
+	var gotSecrets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, secret, _ := r.BasicAuth()
+		gotSecrets = append(gotSecrets, secret)
+		w.Header().Set("Content-Type", "application/json")
+		_, writeErr := w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":300}`))
+		require.NoError(t, writeErr, "failed to write response")
+	}))
+	defer ts.Close()
+
+	secret := "first"
+	client, err := NewClient(Config{
+		BaseURL:      ts.URL,
+		Realm:        "playground",
+		ClientID:     "backend",
+		ClientSecret: "",
+		ClientSecretFunc: func(context.Context) (string, error) {
+			return secret, nil
+		},
+	}, ts.Client())
+	require.NoError(t, err, "failed to create client")
+
+	_, err = client.tokenProvider.Token(t.Context())
+	require.NoError(t, err, "first token")
+	secret = "rotated"
+	_, err = client.tokenProvider.Token(t.Context())
+	require.NoError(t, err, "second token")
+
+	assert.Equal(t, gotSecrets, []string{"first", "rotated"}, "client secrets used")
+}
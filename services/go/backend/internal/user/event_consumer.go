@@ -2,10 +2,10 @@ package user
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 
 	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/kafka"
 )
 
 type eventProcessor interface {
@@ -13,18 +13,18 @@ type eventProcessor interface {
 }
 
 type EventConsumer struct {
-	processor eventProcessor
+	handler kafka.Handler
 }
 
-func NewEventConsumer(processor eventProcessor) *EventConsumer {
-	return &EventConsumer{processor: processor}
+// NewEventConsumer returns a consumer that decodes user events with codec,
+// usually kafka.JSONCodec or a schema registry codec, and passes them to
+// processor.
+func NewEventConsumer(processor eventProcessor, codec kafka.Codec[Event]) *EventConsumer {
+	return &EventConsumer{
+		handler: kafka.NewTypedHandler(codec, processor.ProcessEvent, kafka.DecodeFailureDeadLetter),
+	}
 }
 
 func (c *EventConsumer) HandleRecord(ctx context.Context, record *kgo.Record) error {
-	var event Event
-	if err := json.Unmarshal(record.Value, &event); err != nil {
-		return fmt.Errorf("failed to decode user event: %w", err)
-	}
-
-	return c.processor.ProcessEvent(ctx, event)
+	return c.handler(ctx, record)
 }
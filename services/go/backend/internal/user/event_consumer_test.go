@@ -11,6 +11,7 @@ import (
 
 	"go-services/backend/internal/user"
 	"go-services/library/assert"
+	"go-services/library/kafka"
 	"go-services/library/require"
 )
 
@@ -30,7 +31,7 @@ func TestEventConsumerHandleRecord(t *testing.T) {
 			err:      nil,
 			received: nil,
 		}
-		consumer := user.NewEventConsumer(processor)
+		consumer := user.NewEventConsumer(processor, kafka.JSONCodec[user.Event]{})
 
 		userID, err := uuid.FromString("00000000-0000-0000-0000-000000000123")
 		require.NoError(t, err, "failed to parse uuid")
@@ -54,11 +55,12 @@ func TestEventConsumerHandleRecord(t *testing.T) {
 		consumer := user.NewEventConsumer(&fakeEventProcessor{
 			err:      nil,
 			received: nil,
-		})
+		}, kafka.JSONCodec[user.Event]{})
 
 		err := consumer.HandleRecord(context.Background(), &kgo.Record{Value: []byte(`{`)})
 
-		require.ErrorContains(t, err, "failed to decode user event", "expected decode error")
+		require.ErrorContains(t, err, "failed to decode record value as user.Event", "expected decode error")
+		assert.False(t, kafka.IsRetryable(err), "decode errors are not retried")
 	})
 
 	t.Run("returns processor error", func(t *testing.T) {
@@ -66,7 +68,7 @@ func TestEventConsumerHandleRecord(t *testing.T) {
 		consumer := user.NewEventConsumer(&fakeEventProcessor{
 			err:      wantErr,
 			received: nil,
-		})
+		}, kafka.JSONCodec[user.Event]{})
 
 		err := consumer.HandleRecord(context.Background(), &kgo.Record{
 			Value: []byte(`{"eventType":"USER_EVENT","operation":"CREATE","userId":"00000000-0000-0000-0000-000000000123"}`),
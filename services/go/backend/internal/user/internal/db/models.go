@@ -6,8 +6,15 @@ package db
 
 import (
 	uuid "github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type KafkaProcessedMessage struct {
+	Consumer    string
+	MessageID   string
+	ProcessedAt pgtype.Timestamptz
+}
+
 type User struct {
 	ID        uuid.UUID
 	FirstName string
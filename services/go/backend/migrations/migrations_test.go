@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+// dedupMigration is the deduplicator's migration, which the backend copies so
+// sqlc and goose see the kafka_processed_messages table.
+const dedupMigration = "20261019130000_create_kafka_processed_messages_table.sql"
+
+func TestDedupMigrationInSync(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("..", "..", "library", "kafka", "dedup", "migrations", dedupMigration))
+	require.NoError(t, err, "failed to read the dedup migration")
+
+	got, err := embedMigrations.ReadFile(dedupMigration)
+	require.NoError(t, err, "failed to read the backend's copy")
+
+	assert.Equal(t, string(got), string(want), "backend copy of %s must match library/kafka/dedup/migrations", dedupMigration)
+}
@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"os"
 
-	"go-services/bff/internal/api/middleware"
 	"go-services/bff/internal/app"
 )
 
@@ -15,42 +14,22 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	appl, err := app.New(ctx)
+	appl, err := app.New(ctx, os.Args[1:])
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to initialize app", "err", err)
 		os.Exit(1)
 	}
 
-	cors, err := middleware.CORS(appl.Log, appl.Config.FrontendBaseURL)
+	server, err := appl.Server()
 	if err != nil {
-		appl.Log.ErrorContext(ctx, "failed to initialize cors middleware", "err", err)
+		appl.Log.ErrorContext(ctx, "failed to initialize http server", "err", err)
 		os.Exit(1)
 	}
-	errMw := middleware.Error(appl.Log)
-
-	chain := middleware.NewChain()
-	chain.Add(
-		middleware.Recover(appl.Log),
-		middleware.Logging(appl.Log),
-		cors,
-		middleware.RequireJSON(appl.Log),
-	)
-
-	mux := http.NewServeMux()
-	// Auth routes
-	mux.HandleFunc("GET /auth/login", errMw(appl.Handler.AuthCommandHandler.LoginHandler))
-	mux.HandleFunc("GET /auth/callback", errMw(appl.Handler.AuthCommandHandler.CallbackHandler))
-	mux.HandleFunc("GET /auth/logout", appl.Handler.AuthCommandHandler.LogutoutHandler)
-	// mux.HandleFunc("POST /auth/logout", corsMiddleware(sessionHandler.LogoutHandler))
-	// mux.HandleFunc("/auth/refresh", corsMiddleware(sessionHandler.RefreshHandler))
-
-	// Protected routes
-	// http.HandleFunc("/api/protected", sessionHandler.ProtectedHandler)
 
 	addr := fmt.Sprintf(":%v", appl.Config.ServerPort)
 	appl.Log.InfoContext(ctx, "BFF server starting", "port", appl.Config.ServerPort)
 
-	if err := http.ListenAndServe(addr, chain.Apply(mux)); err != nil {
+	if err := http.ListenAndServe(addr, server); err != nil {
 		appl.Log.ErrorContext(ctx, "server failed:", "err", err)
 		os.Exit(1)
 	}
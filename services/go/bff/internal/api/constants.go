@@ -1,8 +1,21 @@
 package api
 
+import "time"
+
+// SessionLifetime is how long a session lasts after sign in. It bounds the
+// session cookies and the stored session, including its CSRF token.
+const SessionLifetime = 24 * time.Hour
+
 const (
 	SessionTokenCookieName = "session_token"
 	AccessTokenCookieName  = "access_token"
+	// CSRFTokenCookieName is the cookie carrying the session's CSRF token. It
+	// is readable by scripts so the frontend can echo it in CSRFTokenHeader.
+	CSRFTokenCookieName = "csrf_token"
+
+	ContentTypeJSON        = "application/json"
+	ContentTypeProblemJSON = "application/problem+json"
 
-	ContentTypeJSON = "application/json"
+	RequestIDHeader = "X-Request-Id"
+	CSRFTokenHeader = "X-CSRF-Token"
 )
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"go-services/library/apperror"
+)
+
+// DecodeJSON strictly decodes the JSON body of r into dst and validates the
+// result with Validate.
+//
+// Decoding rejects unknown fields and trailing data after the first JSON
+// value. Syntax and type errors are returned as apperror.CodeInvalidFormat
+// errors, with the offending field in the message where encoding/json reports
+// one; validation failures are returned as apperror.CodeInvalidInput errors
+// carrying one FieldViolation per invalid field. Errors caused by the body
+// size limit of the RequireJSON middleware are preserved, so the Error
+// middleware still answers with 413.
+//
+// Example:
+//
+//	func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) error {
+//		var req CreateOrderRequest
+//		if err := api.DecodeJSON(r, &req); err != nil {
+//			return err
+//		}
+//		...
+//	}
+func DecodeJSON(r *http.Request, dst any) error {
+	if err := decodeJSONBody(r, dst); err != nil {
+		return err
+	}
+
+	return Validate(dst)
+}
+
+// decodeJSONBody strictly decodes the JSON body of r into dst without
+// validating it.
+func decodeJSONBody(r *http.Request, dst any) error {
+	if r.Body == nil {
+		return apperror.New(apperror.CodeInvalidFormat, "request body is required")
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return apperror.New(apperror.CodeInvalidFormat, "request body must contain a single JSON value")
+	}
+
+	return nil
+}
+
+// decodeError converts an encoding/json error into an apperror.
+func decodeError(err error) error {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+	// encoding/json has no error type for unknown fields.
+	unknownField, isUnknownField := strings.CutPrefix(err.Error(), "json: unknown field ")
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return apperror.Wrap(apperror.CodeTooLarge, err, "request body must not exceed %d bytes", maxBytesErr.Limit)
+	case errors.Is(err, io.EOF):
+		return apperror.New(apperror.CodeInvalidFormat, "request body is required")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apperror.Wrap(apperror.CodeInvalidFormat, err, "request body contains malformed JSON")
+	case errors.As(err, &syntaxErr):
+		return apperror.Wrap(
+			apperror.CodeInvalidFormat,
+			err,
+			"request body contains malformed JSON at offset %d",
+			syntaxErr.Offset,
+		)
+	case errors.As(err, &typeErr):
+		return apperror.Wrap(
+			apperror.CodeInvalidFormat,
+			err,
+			"field %q must be of type %s",
+			typeErr.Field,
+			typeErr.Type,
+		)
+	case isUnknownField:
+		return apperror.Wrap(apperror.CodeInvalidFormat, err, "unknown field %s", unknownField)
+	default:
+		return apperror.Wrap(apperror.CodeInvalidFormat, err, "request body is not valid JSON")
+	}
+}
@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := map[string]struct {
+		body        string
+		wantCode    apperror.ErrorCode
+		wantMessage string
+		wantErr     bool
+	}{
+		"valid body": {
+			body:        `{"customer":"ada","priority":"low","items":[{"sku":"a","quantity":1}]}`,
+			wantCode:    "",
+			wantMessage: "",
+			wantErr:     false,
+		},
+		"empty body": {
+			body:        ``,
+			wantCode:    apperror.CodeInvalidFormat,
+			wantMessage: "request body is required",
+			wantErr:     true,
+		},
+		"malformed json": {
+			body:        `{"customer":}`,
+			wantCode:    apperror.CodeInvalidFormat,
+			wantMessage: "request body contains malformed JSON at offset 13",
+			wantErr:     true,
+		},
+		"truncated json": {
+			body:        `{"customer":"ada"`,
+			wantCode:    apperror.CodeInvalidFormat,
+			wantMessage: "request body contains malformed JSON",
+			wantErr:     true,
+		},
+		"wrong field type": {
+			body:        `{"customer":42}`,
+			wantCode:    apperror.CodeInvalidFormat,
+			wantMessage: `field "customer" must be of type string`,
+			wantErr:     true,
+		},
+		"unknown field": {
+			body:        `{"customer":"ada","discount":10}`,
+			wantCode:    apperror.CodeInvalidFormat,
+			wantMessage: `unknown field "discount"`,
+			wantErr:     true,
+		},
+		"trailing data": {
+			body:        `{"customer":"ada"} {}`,
+			wantCode:    apperror.CodeInvalidFormat,
+			wantMessage: "request body must contain a single JSON value",
+			wantErr:     true,
+		},
+		"validation failure": {
+			body:        `{"customer":"ada","items":[]}`,
+			wantCode:    apperror.CodeInvalidInput,
+			wantMessage: "request validation failed",
+			wantErr:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+
+			var got createOrderRequest
+			err := api.DecodeJSON(req, &got)
+			if !tt.wantErr {
+				assert.NoError(t, err, "DecodeJSON")
+				return
+			}
+
+			appErr, ok := apperror.As(err)
+			require.True(t, ok, "DecodeJSON should return an *AppError, got %v", err)
+			assert.Equal(t, appErr.Code, tt.wantCode, "error code")
+			assert.Equal(t, appErr.Msg, tt.wantMessage, "error message")
+		})
+	}
+}
+
+func TestDecodeJSONBodyLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"customer":"a very long name"}`))
+	req.Body = http.MaxBytesReader(rec, req.Body, 8)
+
+	var got createOrderRequest
+	err := api.DecodeJSON(req, &got)
+
+	var maxBytesErr *http.MaxBytesError
+	assert.True(t, errors.As(err, &maxBytesErr), "body limit error should be preserved, got %v", err)
+	appErr, ok := apperror.As(err)
+	require.True(t, ok, "DecodeJSON should return an *AppError, got %v", err)
+	assert.Equal(t, appErr.Code, apperror.CodeTooLarge, "error code")
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"go-services/library/apperror"
+)
+
+// Struct tags binding request fields to path and query parameters.
+const (
+	pathTag  = "path"
+	queryTag = "query"
+)
+
+// handleConfig holds the settings for Handle.
+type handleConfig struct {
+	status int
+}
+
+// HandleOption customizes a handler created by Handle.
+type HandleOption func(*handleConfig)
+
+// WithStatus sets the status code of successful responses. It defaults to
+// 200 OK; http.StatusNoContent writes no body at all.
+func WithStatus(status int) HandleOption {
+	return func(c *handleConfig) {
+		c.status = status
+	}
+}
+
+// Handle adapts a typed function into an http.HandlerFunc.
+//
+// Behavior:
+//  1. A new Req is populated from the request:
+//     - If the request has a body, it is strictly decoded as JSON (see
+//     DecodeJSON): unknown fields and trailing data are rejected, and bodies
+//     over the RequireJSON limit fail with 413.
+//     - Fields tagged `path:"name"` are set from r.PathValue(name).
+//     - Fields tagged `query:"name"` are set from the URL query; slice
+//     fields receive every value of a repeated parameter.
+//     Path and query fields should also be tagged `json:"-"` so they cannot
+//     be supplied in the body.
+//  2. The populated Req is validated with Validate. Unparsable path or query
+//     values and failed rules are reported together as field violations.
+//  3. fn is called with the request context.
+//  4. On success, the result is written as a SuccessResponse with the
+//     configured status code. Any error, from binding or from fn, is written
+//     by HandleError, exactly like the Error middleware does.
+//
+// Path and query fields may be strings, booleans, integers, floats, types
+// implementing encoding.TextUnmarshaler, or pointers and (for query
+// parameters) slices of those.
+//
+// Example:
+//
+//	type GetOrderRequest struct {
+//		ID     string `json:"-" path:"id"      validate:"required"`
+//		Expand bool   `json:"-" query:"expand"`
+//	}
+//
+//	mux.HandleFunc("GET /orders/{id}", api.Handle(log,
+//		func(ctx context.Context, req GetOrderRequest) (OrderResponse, error) {
+//			return svc.GetOrder(ctx, req.ID, req.Expand)
+//		},
+//	))
+//
+//	mux.HandleFunc("POST /orders", api.Handle(log, svc.CreateOrder, api.WithStatus(http.StatusCreated)))
+func Handle[Req, Resp any](
+	log *slog.Logger,
+	fn func(ctx context.Context, req Req) (Resp, error),
+	opts ...HandleOption,
+) http.HandlerFunc {
+	cfg := &handleConfig{status: http.StatusOK}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := bindRequest(r, &req); err != nil {
+			HandleError(log, w, r, err)
+			return
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			HandleError(log, w, r, err)
+			return
+		}
+
+		if cfg.status == http.StatusNoContent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err := SendJSON(w, cfg.status, resp); err != nil {
+			log.ErrorContext(r.Context(), "failed to encode response", "err", err)
+		}
+	}
+}
+
+// bindRequest decodes the body, path and query parameters of r into dst and
+// validates the result.
+func bindRequest(r *http.Request, dst any) error {
+	if r.ContentLength != 0 || len(r.TransferEncoding) > 0 {
+		if err := decodeJSONBody(r, dst); err != nil {
+			return err
+		}
+	}
+
+	var violations []apperror.FieldViolation
+	bindParams(r, reflect.ValueOf(dst).Elem(), &violations)
+	if err := validateInto(dst, &violations); err != nil {
+		return err
+	}
+
+	// A parameter that failed to parse is left at its zero value, so its
+	// rules may fail too. Only the parse failure is worth reporting.
+	seen := make(map[string]bool, len(violations))
+	violations = slices.DeleteFunc(violations, func(violation apperror.FieldViolation) bool {
+		duplicate := seen[violation.Field]
+		seen[violation.Field] = true
+		return duplicate
+	})
+
+	return apperror.NewValidation(violations...)
+}
+
+// bindParams sets the fields of v tagged with path or query from r. Values
+// that cannot be parsed are appended to violations.
+func bindParams(r *http.Request, v reflect.Value, violations *[]apperror.FieldViolation) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	query := r.URL.Query()
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		var values []string
+		name := field.Tag.Get(pathTag)
+		if name != "" {
+			if value := r.PathValue(name); value != "" {
+				values = []string{value}
+			}
+		} else if name = field.Tag.Get(queryTag); name != "" {
+			values = query[name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setParam(v.Field(i), values); err != nil {
+			*violations = append(*violations, apperror.FieldViolation{
+				Field:   name,
+				Message: err.Error(),
+				Code:    apperror.CodeInvalidFormat,
+			})
+		}
+	}
+}
+
+// setParam parses values into field. Only slice fields accept more than one
+// value; for other fields the first value wins.
+func setParam(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !implementsTextUnmarshaler(field) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setScalar(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setScalar(field, values[0])
+}
+
+// implementsTextUnmarshaler reports whether a pointer to v implements
+// encoding.TextUnmarshaler.
+func implementsTextUnmarshaler(v reflect.Value) bool {
+	return reflect.PointerTo(v.Type()).Implements(reflect.TypeFor[encoding.TextUnmarshaler]())
+}
+
+// setScalar parses a single parameter value into v.
+func setScalar(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setScalar(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if implementsTextUnmarshaler(v) {
+		unmarshaler, _ := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("must be a valid %s", v.Type().Name())
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported parameter type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go-services/library/apperror"
+)
+
+// HandleError logs err and writes the matching error response for r.
+//
+// *apperror.AppError values are answered with their own status code, error
+// code, message, field violations and metadata; 4xx errors are logged at WARN
+// and 5xx errors at ERROR. An error wrapping *http.MaxBytesError always maps
+// to 413 Content Too Large. Any other error is logged at ERROR and answered
+// with a generic 500 so internal details never reach the client.
+//
+// middleware.Error and Handle both report errors through HandleError, so
+// every handler fails the same way.
+func HandleError(log *slog.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	// A body read that hit the RequireJSON size limit is always a
+	// 413, whatever the handler wrapped it in.
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = apperror.Wrap(
+			apperror.CodeTooLarge,
+			err,
+			"request body must not exceed %d bytes", maxBytesErr.Limit,
+		)
+	}
+
+	if appErr, ok := apperror.As(err); ok {
+		statusCode := appErr.ToHTTPStatus()
+		if statusCode >= 500 {
+			log.ErrorContext(
+				ctx,
+				"backend error",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"code", appErr.Code,
+				"error", err,
+			)
+		} else {
+			log.WarnContext(ctx, "client error",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", statusCode,
+				"code", appErr.Code,
+				"msg", appErr.Msg,
+			)
+		}
+
+		SendErrorBodyLog(r, log, w, statusCode, NewErrorResponseBody(appErr))
+		return
+	}
+
+	log.ErrorContext(
+		ctx,
+		"unhandled error",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"error", err,
+	)
+	SendErrorLog(
+		r,
+		log,
+		w,
+		http.StatusInternalServerError,
+		apperror.CodeInternalError,
+		"internal server error",
+	)
+}
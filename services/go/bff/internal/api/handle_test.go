@@ -0,0 +1,189 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+type updateOrderRequest struct {
+	Priority *int     `json:"-"        query:"priority"`
+	ID       string   `json:"-"        path:"id"     validate:"required"`
+	Customer string   `json:"customer" validate:"required,max=8"`
+	Tags     []string `json:"-"        query:"tag"`
+	Version  int      `json:"-"        query:"version" validate:"min=1"`
+}
+
+type updateOrderResponse struct {
+	Priority *int     `json:"priority"`
+	ID       string   `json:"id"`
+	Customer string   `json:"customer"`
+	Tags     []string `json:"tags"`
+	Version  int      `json:"version"`
+}
+
+func updateOrder(_ context.Context, req updateOrderRequest) (updateOrderResponse, error) {
+	if req.Customer == "conflict" {
+		return updateOrderResponse{}, apperror.New(apperror.CodeConflict, "order was modified")
+	}
+	if req.Customer == "boom" {
+		return updateOrderResponse{}, errors.New("database exploded")
+	}
+
+	return updateOrderResponse{
+		Priority: req.Priority,
+		ID:       req.ID,
+		Customer: req.Customer,
+		Tags:     req.Tags,
+		Version:  req.Version,
+	}, nil
+}
+
+func TestHandle(t *testing.T) {
+	priority := 3
+
+	tests := map[string]struct {
+		wantResponse   *updateOrderResponse
+		target         string
+		body           string
+		wantCode       apperror.ErrorCode
+		wantViolations []apperror.FieldViolation
+		wantStatusCode int
+	}{
+		"binds body path and query": {
+			target: "/orders/42?version=2&priority=3&tag=a&tag=b",
+			body:   `{"customer":"ada"}`,
+			wantResponse: &updateOrderResponse{
+				Priority: &priority,
+				ID:       "42",
+				Customer: "ada",
+				Tags:     []string{"a", "b"},
+				Version:  2,
+			},
+			wantCode:       "",
+			wantViolations: nil,
+			wantStatusCode: http.StatusOK,
+		},
+		"collects violations from every source": {
+			target:       "/orders/42?version=abc&priority=high",
+			body:         `{"customer":"a very long name"}`,
+			wantResponse: nil,
+			wantCode:     apperror.CodeInvalidInput,
+			wantViolations: []apperror.FieldViolation{
+				{Field: "priority", Message: "must be an integer", Code: apperror.CodeInvalidFormat},
+				{Field: "version", Message: "must be an integer", Code: apperror.CodeInvalidFormat},
+				{Field: "customer", Message: "must be at most 8 characters", Code: apperror.CodeOutOfRange},
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"missing body fails validation": {
+			target:       "/orders/42?version=1",
+			body:         ``,
+			wantResponse: nil,
+			wantCode:     apperror.CodeInvalidInput,
+			wantViolations: []apperror.FieldViolation{
+				{Field: "customer", Message: "is required", Code: apperror.CodeMissingField},
+			},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"unknown body field is rejected": {
+			target:         "/orders/42?version=1",
+			body:           `{"customer":"ada","id":"43"}`,
+			wantResponse:   nil,
+			wantCode:       apperror.CodeInvalidFormat,
+			wantViolations: nil,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"app error from handler": {
+			target:         "/orders/42?version=1",
+			body:           `{"customer":"conflict"}`,
+			wantResponse:   nil,
+			wantCode:       apperror.CodeConflict,
+			wantViolations: nil,
+			wantStatusCode: http.StatusConflict,
+		},
+		"unhandled error from handler": {
+			target:         "/orders/42?version=1",
+			body:           `{"customer":"boom"}`,
+			wantResponse:   nil,
+			wantCode:       apperror.CodeInternalError,
+			wantViolations: nil,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, _ := testlogger.New()
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /orders/{id}", api.Handle(log, updateOrder))
+
+			req := httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, rec.Code, tt.wantStatusCode, "status code")
+
+			if tt.wantResponse != nil {
+				var got struct {
+					Data    updateOrderResponse `json:"data"`
+					Success bool                `json:"success"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), "decode success response")
+				assert.True(t, got.Success, "success flag")
+				assert.Equal(t, got.Data, *tt.wantResponse, "response data")
+				return
+			}
+
+			var got api.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), "decode error response")
+			assert.Equal(t, got.Error.Code, tt.wantCode, "error code")
+			assert.Equal(t, got.Error.Violations, tt.wantViolations, "violations")
+		})
+	}
+}
+
+func TestHandleWithStatus(t *testing.T) {
+	log, _ := testlogger.New()
+	noop := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+
+	t.Run("created", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		api.Handle(log, noop, api.WithStatus(http.StatusCreated))(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+		assert.Equal(t, rec.Code, http.StatusCreated, "status code")
+		assert.Equal(t, rec.Header().Get("Content-Type"), api.ContentTypeJSON, "content type")
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		api.Handle(log, noop, api.WithStatus(http.StatusNoContent))(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+		assert.Equal(t, rec.Code, http.StatusNoContent, "status code")
+		assert.Equal(t, rec.Body.Len(), 0, "body length")
+	})
+}
+
+func TestHandleBodyLimit(t *testing.T) {
+	log, _ := testlogger.New()
+	handler := api.Handle(log, updateOrder)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/orders/42", strings.NewReader(`{"customer":"ada"}`))
+	req.Body = http.MaxBytesReader(rec, req.Body, 8)
+
+	handler(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusRequestEntityTooLarge, "status code")
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -18,28 +17,62 @@ func SendJSON(w http.ResponseWriter, status int, data any) error {
 
 // SendError writes a structured JSON error response.
 func SendError(w http.ResponseWriter, status int, code apperror.ErrorCode, message string) error {
+	return sendErrorBody(w, status, ErrorResponseBody{
+		Metadata:   nil,
+		Code:       code,
+		Message:    message,
+		Violations: nil,
+	})
+}
+
+// sendErrorBody writes body wrapped in the JSON error envelope.
+func sendErrorBody(w http.ResponseWriter, status int, body ErrorResponseBody) error {
 	w.Header().Set("Content-Type", ContentTypeJSON)
 	w.WriteHeader(status)
 
 	return json.NewEncoder(w).Encode(ErrorResponse{
 		Success: false,
-		Error: ErrorResponseBody{
-			Code:    code,
-			Message: message,
-		},
+		Error:   body,
 	})
 }
 
+// SendProblem writes an RFC 9457 Problem Details error response.
+func SendProblem(w http.ResponseWriter, problem ProblemDetails) error {
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.WriteHeader(problem.Status)
+
+	return json.NewEncoder(w).Encode(problem)
+}
+
+// SendErrorLog writes an error response for r in the format chosen by
+// NegotiateErrorFormat and logs encoding failures instead of returning them.
 func SendErrorLog(
-	ctx context.Context,
+	r *http.Request,
 	log *slog.Logger,
 	w http.ResponseWriter,
 	status int,
 	code apperror.ErrorCode,
 	message string,
 ) {
-	err := SendError(w, status, code, message)
+	SendErrorBodyLog(r, log, w, status, ErrorResponseBody{
+		Metadata:   nil,
+		Code:       code,
+		Message:    message,
+		Violations: nil,
+	})
+}
+
+// SendErrorBodyLog is like SendErrorLog but writes a complete error body,
+// including field violations and metadata.
+func SendErrorBodyLog(r *http.Request, log *slog.Logger, w http.ResponseWriter, status int, body ErrorResponseBody) {
+	var err error
+	switch NegotiateErrorFormat(r) {
+	case ErrorFormatProblem:
+		err = SendProblem(w, NewProblemDetails(r, status, body))
+	default:
+		err = sendErrorBody(w, status, body)
+	}
 	if err != nil {
-		log.ErrorContext(ctx, "failed to encode response", "err", err)
+		log.ErrorContext(r.Context(), "failed to encode response", "err", err)
 	}
 }
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// IsJSONMediaType reports whether a Content-Type value denotes a JSON
+// document. Parameters such as charset are ignored, and structured syntax
+// suffix types (RFC 6839) like "application/problem+json" or
+// "application/merge-patch+json" are treated as JSON.
+func IsJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return isJSONType(mediaType)
+}
+
+// AcceptsJSON reports whether the given Accept header values allow a JSON
+// response.
+//
+// Each media range is parsed together with its parameters and q-value
+// (RFC 9110, section 12.5.1). A missing or empty header accepts anything. The
+// most specific matching range decides, so "application/json;q=0, */*" rejects
+// JSON while "text/html, application/*;q=0.5" accepts it. JSON suffix types
+// such as "application/problem+json" do not match: success responses are
+// plain application/json, and a client accepting only Problem Details
+// expects errors.
+func AcceptsJSON(accept []string) bool {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return true
+	}
+
+	const noMatch = -1
+	bestSpecificity := noMatch
+	bestQuality := 0.0
+	for _, mr := range ranges {
+		specificity := noMatch
+		switch {
+		case mr.mediaType == ContentTypeJSON:
+			specificity = 2
+		case mr.mediaType == "application/*":
+			specificity = 1
+		case mr.mediaType == "*/*":
+			specificity = 0
+		}
+		if specificity == noMatch {
+			continue
+		}
+
+		if specificity > bestSpecificity ||
+			(specificity == bestSpecificity && mr.quality > bestQuality) {
+			bestSpecificity = specificity
+			bestQuality = mr.quality
+		}
+	}
+
+	return bestSpecificity != noMatch && bestQuality > 0
+}
+
+// mediaRange is a single, parsed element of an Accept header.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses comma-separated Accept header values. Malformed ranges
+// are skipped rather than failing the whole header.
+func parseAccept(values []string) []mediaRange {
+	var ranges []mediaRange
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				parsed, err := strconv.ParseFloat(q, 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					continue
+				}
+				quality = parsed
+			}
+
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	return ranges
+}
+
+// isJSONType reports whether an already parsed, lower-cased media type is
+// application/json or an application/*+json suffix type.
+func isJSONType(mediaType string) bool {
+	if mediaType == ContentTypeJSON {
+		return true
+	}
+
+	subtype, ok := strings.CutPrefix(mediaType, "application/")
+	return ok && strings.HasSuffix(subtype, "+json") && len(subtype) > len("+json")
+}
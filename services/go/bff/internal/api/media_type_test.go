@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/library/assert"
+)
+
+func TestIsJSONMediaType(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		want        bool
+	}{
+		"plain json":                  {contentType: "application/json", want: true},
+		"json with charset":           {contentType: "application/json; charset=utf-8", want: true},
+		"upper case json":             {contentType: "Application/JSON", want: true},
+		"problem json":                {contentType: "application/problem+json", want: true},
+		"merge patch json":            {contentType: "application/merge-patch+json", want: true},
+		"vendor json with params":     {contentType: "application/vnd.api+json; version=2", want: true},
+		"bare suffix is not json":     {contentType: "application/+json", want: false},
+		"text json suffix":            {contentType: "text/x+json", want: false},
+		"plain text":                  {contentType: "text/plain", want: false},
+		"json prefix is not enough":   {contentType: "application/jsonp", want: false},
+		"empty content type":          {contentType: "", want: false},
+		"malformed content type":      {contentType: "application/json; charset", want: false},
+		"form urlencoded":             {contentType: "application/x-www-form-urlencoded", want: false},
+		"json in parameter is not ok": {contentType: "text/plain; format=application/json", want: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, api.IsJSONMediaType(tt.contentType), tt.want, "IsJSONMediaType(%q)", tt.contentType)
+		})
+	}
+}
+
+func TestAcceptsJSON(t *testing.T) {
+	tests := map[string]struct {
+		accept []string
+		want   bool
+	}{
+		"no header":                         {accept: nil, want: true},
+		"empty header":                      {accept: []string{""}, want: true},
+		"exact json":                        {accept: []string{"application/json"}, want: true},
+		"json among others":                 {accept: []string{"application/json, text/plain"}, want: true},
+		"any type":                          {accept: []string{"*/*"}, want: true},
+		"application wildcard":              {accept: []string{"text/html, application/*;q=0.5"}, want: true},
+		"problem json only":                 {accept: []string{"application/problem+json"}, want: false},
+		"json refused but problem json":     {accept: []string{"application/json;q=0, application/problem+json"}, want: false},
+		"json with charset":                 {accept: []string{"application/json; charset=utf-8"}, want: true},
+		"multiple header values":            {accept: []string{"text/html", "application/json;q=0.1"}, want: true},
+		"html only":                         {accept: []string{"text/html"}, want: false},
+		"json explicitly refused":           {accept: []string{"application/json;q=0"}, want: false},
+		"json refused despite wildcard":     {accept: []string{"application/json;q=0, */*"}, want: false},
+		"wildcard refused but json allowed": {accept: []string{"*/*;q=0, application/json"}, want: true},
+		"only malformed ranges":             {accept: []string{"application/json;q=abc"}, want: true},
+		"malformed range is skipped":        {accept: []string{"application/json;q=abc, text/html"}, want: false},
+		"out of range q-value is ignored":   {accept: []string{"application/json;q=2, */*"}, want: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, api.AcceptsJSON(tt.accept), tt.want, "AcceptsJSON(%q)", tt.accept)
+		})
+	}
+}
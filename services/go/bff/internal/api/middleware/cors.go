@@ -1,30 +1,129 @@
 package middleware
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"go-services/bff/internal/api"
 	"go-services/library/apperror"
 )
 
+var (
+	defaultCORSMethods = []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+	}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization"}
+)
+
+// CORSPolicy describes which cross-origin requests the BFF accepts.
+//
+// AllowedOrigins entries are either exact origins ("https://app.example.com")
+// or wildcard subdomain patterns ("https://*.example.com"). A wildcard only
+// matches subdomains, never the apex domain itself, and the scheme and port
+// must still match exactly.
+//
+// Routes overrides the allowed methods and headers for requests whose path
+// starts with the given prefix. When several prefixes match, the longest one
+// wins. Origins, exposed headers, max age and credentials are shared by all
+// routes.
+type CORSPolicy struct {
+	// Routes maps a path prefix (e.g. "/auth/") to a route-specific rule.
+	Routes map[string]CORSRouteRule
+	// AllowedOrigins lists exact origins or wildcard subdomain patterns.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods allowed in preflight requests.
+	// Defaults to GET, POST, PUT, PATCH, DELETE and OPTIONS.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed in preflight requests.
+	// Defaults to Content-Type and Authorization.
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers readable by browser scripts.
+	ExposedHeaders []string
+	// MaxAge controls how long browsers may cache a preflight response.
+	// Zero omits the Access-Control-Max-Age header.
+	MaxAge time.Duration
+	// AllowCredentials sets Access-Control-Allow-Credentials: true.
+	AllowCredentials bool
+}
+
+// CORSRouteRule overrides the allowed methods and headers for a path prefix.
+// Empty slices fall back to the policy-wide values.
+type CORSRouteRule struct {
+	AllowedMethods []string
+	AllowedHeaders []string
+}
+
+// originPattern is a parsed AllowedOrigins entry.
+type originPattern struct {
+	scheme string
+	host   string
+	port   string
+	// wildcard reports whether host is a "*." subdomain pattern; host then
+	// holds the suffix including the leading dot (".example.com").
+	wildcard bool
+}
+
+// corsRule is a CORSRouteRule with policy defaults applied and header names
+// canonicalized for lookups.
+type corsRule struct {
+	headerLookup  map[string]struct{}
+	methodsHeader string
+	headersHeader string
+	methods       []string
+}
+
+// compiledCORSPolicy is the immutable, pre-computed form of a CORSPolicy used
+// on the request path.
+type compiledCORSPolicy struct {
+	routes         map[string]corsRule
+	defaultRule    corsRule
+	exposedHeaders string
+	maxAge         string
+	origins        []originPattern
+	routePrefixes  []string
+	credentials    bool
+}
+
 // CORS returns a middleware that enforces Cross-Origin Resource Sharing (CORS)
 // and protects against cross-origin request forgery (CSRF-like) attacks.
 //
-// It performs two main functions:
-//  1. Sets CORS headers on every response to allow trusted origins.
-//  2. Wraps the request handler with additional origin validation using
-//     http.NewCrossOriginProtection(), denying requests from untrusted origins.
+// It performs three main functions:
+//  1. Answers CORS preflight requests (OPTIONS with Origin and
+//     Access-Control-Request-Method headers). Allowed preflights receive
+//     HTTP 204 (No Content) with the negotiated Access-Control-* headers;
+//     preflights for a disallowed origin, method or header are rejected with
+//     HTTP 403 Forbidden. Plain OPTIONS requests are passed to the next handler.
+//  2. Echoes the request Origin in Access-Control-Allow-Origin when it matches
+//     the policy, and always adds "Vary: Origin" so caches keep responses for
+//     different origins apart.
+//  3. Wraps the request handler with http.NewCrossOriginProtection(), denying
+//     unsafe requests from origins that the policy does not trust.
 //
-// The middleware automatically handles OPTIONS preflight requests
-// by returning HTTP 204 (No Content).
-//
-// If a request originates from an untrusted domain, it responds with
-// HTTP 403 Forbidden and a JSON error payload using api.SendErrorLog.
+// If a request is rejected, it responds with HTTP 403 Forbidden and a JSON
+// error payload using api.SendErrorLog.
 //
 // Example:
 //
-//	cors, err := middleware.CORS(log, "https://frontend.example.com")
+//	cors, err := middleware.CORS(log, middleware.CORSPolicy{
+//	    AllowedOrigins:   []string{"https://frontend.example.com", "https://*.preview.example.com"},
+//	    ExposedHeaders:   []string{"X-Request-Id"},
+//	    MaxAge:           10 * time.Minute,
+//	    AllowCredentials: true,
+//	    Routes: map[string]middleware.CORSRouteRule{
+//	        "/auth/": {AllowedMethods: []string{http.MethodGet}},
+//	    },
+//	})
 //	if err != nil {
 //	    log.Error("failed to initialize CORS middleware", "err", err)
 //	    os.Exit(1)
@@ -37,18 +136,22 @@ import (
 //
 // Parameters:
 //   - log: the structured logger (slog.Logger) used for error reporting.
-//   - trustedOrigin: the allowed frontend base URL for cross-origin requests.
+//   - policy: the origins, methods and headers allowed for cross-origin requests.
 //
 // Returns:
 //   - A middleware function that wraps an http.Handler to apply CORS rules.
-//   - An error if the provided trustedOrigin is invalid.
+//   - An error if the policy contains an invalid origin, or combines the "*"
+//     origin with AllowCredentials.
 //
-// The returned middleware sets these headers:
+// An allowed preflight from https://frontend.example.com receives:
 //
-//	Access-Control-Allow-Origin: <trustedOrigin>
+//	HTTP/1.1 204 No Content
+//	Access-Control-Allow-Origin: https://frontend.example.com
 //	Access-Control-Allow-Methods: GET, POST, PUT, PATCH, DELETE, OPTIONS
 //	Access-Control-Allow-Headers: Content-Type, Authorization
 //	Access-Control-Allow-Credentials: true
+//	Access-Control-Max-Age: 600
+//	Vary: Origin, Access-Control-Request-Method, Access-Control-Request-Headers
 //
 // Example response when the origin is not allowed:
 //
@@ -58,39 +161,285 @@ import (
 //	  "code": "FORBIDDEN",
 //	  "message": "CORS origin not allowed"
 //	}
-func CORS(log *slog.Logger, trustedOrigin string) (func(http.Handler) http.Handler, error) {
-	csrp := http.NewCrossOriginProtection()
-	if err := csrp.AddTrustedOrigin(trustedOrigin); err != nil {
+func CORS(log *slog.Logger, policy CORSPolicy) (func(http.Handler) http.Handler, error) {
+	compiled, err := compileCORSPolicy(policy)
+	if err != nil {
 		return nil, err
 	}
+
+	csrp := http.NewCrossOriginProtection()
+	for _, origin := range policy.AllowedOrigins {
+		if strings.Contains(origin, "*") {
+			// Wildcards are matched by the policy itself before the request
+			// reaches the cross-origin protection.
+			continue
+		}
+		if err := csrp.AddTrustedOrigin(origin); err != nil {
+			return nil, err
+		}
+	}
 	csrp.SetDenyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		api.SendErrorLog(
-			r.Context(),
-			log,
-			w,
-			http.StatusForbidden,
-			apperror.CodeForbidden,
-			"CORS origin not allowed",
-		)
+		sendCORSError(log, w, r, "CORS origin not allowed")
 	}))
 
 	return func(next http.Handler) http.Handler {
+		protected := csrp.Handler(next)
+
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Set CORS headers
-			w.Header().Set("Access-Control-Allow-Origin", trustedOrigin)
-			w.Header().Set("Access-Control-Allow-Methods",
-				"GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && compiled.allowsOrigin(origin)
+
+			if isPreflight(r) {
+				compiled.handlePreflight(log, w, r, allowed)
 				return
 			}
 
-			// Apply cross-origin protection, then continue to next handler
-			csrp.Handler(next).ServeHTTP(w, r)
+			if !allowed {
+				protected.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			if compiled.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if compiled.exposedHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", compiled.exposedHeaders)
+			}
+
+			// The origin is trusted by the policy (possibly through a wildcard
+			// that CrossOriginProtection cannot express), so skip the check.
+			next.ServeHTTP(w, r)
 		})
 	}, nil
 }
+
+// isPreflight reports whether r is a CORS preflight request rather than a
+// plain OPTIONS request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func (p *compiledCORSPolicy) handlePreflight(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	originAllowed bool,
+) {
+	h := w.Header()
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	if !originAllowed {
+		sendCORSError(log, w, r, "CORS origin not allowed")
+		return
+	}
+
+	rule := p.ruleFor(r.URL.Path)
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !slices.Contains(rule.methods, method) {
+		sendCORSError(log, w, r, "CORS method not allowed")
+		return
+	}
+
+	for _, header := range parseHeaderList(r.Header.Values("Access-Control-Request-Headers")) {
+		if _, ok := rule.headerLookup[http.CanonicalHeaderKey(header)]; !ok {
+			sendCORSError(log, w, r, "CORS header not allowed")
+			return
+		}
+	}
+
+	h.Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	h.Set("Access-Control-Allow-Methods", rule.methodsHeader)
+	if rule.headersHeader != "" {
+		h.Set("Access-Control-Allow-Headers", rule.headersHeader)
+	}
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *compiledCORSPolicy) allowsOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+
+	for _, pattern := range p.origins {
+		if pattern.host == "*" {
+			return true
+		}
+		if pattern.scheme != scheme || pattern.port != port {
+			continue
+		}
+		if pattern.wildcard {
+			if strings.HasSuffix(host, pattern.host) && len(host) > len(pattern.host) {
+				return true
+			}
+			continue
+		}
+		if pattern.host == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ruleFor returns the rule of the longest route prefix matching path, or the
+// policy-wide rule when no prefix matches.
+func (p *compiledCORSPolicy) ruleFor(path string) corsRule {
+	for _, prefix := range p.routePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return p.routes[prefix]
+		}
+	}
+	return p.defaultRule
+}
+
+func compileCORSPolicy(policy CORSPolicy) (*compiledCORSPolicy, error) {
+	if len(policy.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("cors policy must allow at least one origin")
+	}
+
+	origins := make([]originPattern, 0, len(policy.AllowedOrigins))
+	for _, origin := range policy.AllowedOrigins {
+		pattern, err := parseOriginPattern(origin)
+		if err != nil {
+			return nil, err
+		}
+		if pattern.host == "*" && policy.AllowCredentials {
+			return nil, fmt.Errorf("cors origin %q cannot be combined with credentials", origin)
+		}
+		origins = append(origins, pattern)
+	}
+
+	methods := policy.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := policy.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	defaultRule := newCORSRule(methods, headers)
+
+	routes := make(map[string]corsRule, len(policy.Routes))
+	prefixes := make([]string, 0, len(policy.Routes))
+	for prefix, route := range policy.Routes {
+		routeMethods := route.AllowedMethods
+		if len(routeMethods) == 0 {
+			routeMethods = methods
+		}
+		routeHeaders := route.AllowedHeaders
+		if len(routeHeaders) == 0 {
+			routeHeaders = headers
+		}
+		routes[prefix] = newCORSRule(routeMethods, routeHeaders)
+		prefixes = append(prefixes, prefix)
+	}
+	// Longest prefix first so the most specific route wins.
+	slices.SortFunc(prefixes, func(a, b string) int { return len(b) - len(a) })
+
+	maxAge := ""
+	if policy.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
+
+	return &compiledCORSPolicy{
+		routes:         routes,
+		origins:        origins,
+		routePrefixes:  prefixes,
+		defaultRule:    defaultRule,
+		exposedHeaders: strings.Join(policy.ExposedHeaders, ", "),
+		maxAge:         maxAge,
+		credentials:    policy.AllowCredentials,
+	}, nil
+}
+
+func newCORSRule(methods, headers []string) corsRule {
+	lookup := make(map[string]struct{}, len(headers))
+	for _, header := range headers {
+		lookup[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	return corsRule{
+		methods:       methods,
+		headerLookup:  lookup,
+		methodsHeader: strings.Join(methods, ", "),
+		headersHeader: strings.Join(headers, ", "),
+	}
+}
+
+// parseOriginPattern validates an AllowedOrigins entry. Origins must be of the
+// form scheme://host[:port] without path, query or fragment.
+func parseOriginPattern(origin string) (originPattern, error) {
+	if origin == "*" {
+		return originPattern{scheme: "", host: "*", port: "", wildcard: false}, nil
+	}
+
+	scheme, rest, ok := strings.Cut(origin, "://")
+	if !ok {
+		return originPattern{}, fmt.Errorf("invalid cors origin %q: must be scheme://host[:port]", origin)
+	}
+	rest, wildcard := strings.CutPrefix(rest, "*.")
+	if strings.Contains(rest, "*") {
+		return originPattern{}, fmt.Errorf("invalid cors origin %q: wildcard must be a leading \"*.\"", origin)
+	}
+
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil {
+		return originPattern{}, fmt.Errorf("invalid cors origin %q: %w", origin, err)
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return originPattern{}, fmt.Errorf("invalid cors origin %q: must be scheme://host[:port]", origin)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if wildcard {
+		host = "." + host
+	}
+
+	return originPattern{
+		scheme:   strings.ToLower(u.Scheme),
+		host:     host,
+		port:     u.Port(),
+		wildcard: wildcard,
+	}, nil
+}
+
+// parseHeaderList splits comma-separated header values into trimmed names.
+func parseHeaderList(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func sendCORSError(log *slog.Logger, w http.ResponseWriter, r *http.Request, message string) {
+	api.SendErrorLog(
+		r,
+		log,
+		w,
+		http.StatusForbidden,
+		apperror.CodeForbidden,
+		message,
+	)
+}
@@ -6,19 +6,32 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"go-services/bff/internal/api/middleware"
 	"go-services/library/assert"
+	"go-services/library/require"
 )
 
 func TestCORS(t *testing.T) {
 	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	trustedOrigin := "https://example.com"
 
-	corsMiddleware, err := middleware.CORS(log, trustedOrigin)
-	if err != nil {
-		t.Fatalf("failed to create CORS middleware: %v", err)
-	}
+	corsMiddleware, err := middleware.CORS(log, middleware.CORSPolicy{
+		Routes: map[string]middleware.CORSRouteRule{
+			"/auth/": {
+				AllowedMethods: []string{http.MethodGet},
+				AllowedHeaders: nil,
+			},
+		},
+		AllowedOrigins:   []string{trustedOrigin, "https://*.preview.example.com"},
+		AllowedMethods:   nil,
+		AllowedHeaders:   nil,
+		ExposedHeaders:   []string{"X-Request-Id"},
+		MaxAge:           10 * time.Minute,
+		AllowCredentials: true,
+	})
+	require.NoError(t, err, "failed to create CORS middleware")
 
 	handlerCalled := false
 	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -31,44 +44,183 @@ func TestCORS(t *testing.T) {
 
 	handler := corsMiddleware(testHandler)
 
-	wantHeaders := map[string]string{
-		"Access-Control-Allow-Origin":      trustedOrigin,
-		"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
-		"Access-Control-Allow-Headers":     "Content-Type, Authorization",
-		"Access-Control-Allow-Credentials": "true",
-	}
-
 	tests := map[string]struct {
+		headers        map[string]string
+		wantHeaders    map[string]string
 		method         string
-		origin         string
+		path           string
 		wantBody       string
 		wantStatus     int
 		shouldCallNext bool
 	}{
 		"GET request with trusted origin": {
-			method:         http.MethodGet,
-			origin:         trustedOrigin,
+			method:  http.MethodGet,
+			path:    "/test",
+			headers: map[string]string{"Origin": trustedOrigin},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      trustedOrigin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+				"Access-Control-Allow-Methods":     "",
+				"Vary":                             "Origin",
+			},
 			wantStatus:     http.StatusOK,
 			wantBody:       "OK",
 			shouldCallNext: true,
 		},
-		"OPTIONS preflight request": {
-			method:         http.MethodOptions,
-			origin:         trustedOrigin,
-			wantStatus:     http.StatusNoContent,
-			wantBody:       "",
-			shouldCallNext: false,
-		},
 		"POST request with trusted origin": {
-			method:         http.MethodPost,
-			origin:         trustedOrigin,
+			method:  http.MethodPost,
+			path:    "/test",
+			headers: map[string]string{"Origin": trustedOrigin},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": trustedOrigin,
+			},
+			wantStatus:     http.StatusOK,
+			wantBody:       "OK",
+			shouldCallNext: true,
+		},
+		"POST request with wildcard subdomain origin": {
+			method:  http.MethodPost,
+			path:    "/test",
+			headers: map[string]string{"Origin": "https://pr-42.preview.example.com"},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://pr-42.preview.example.com",
+			},
+			wantStatus:     http.StatusOK,
+			wantBody:       "OK",
+			shouldCallNext: true,
+		},
+		"GET request with untrusted origin has no allow origin": {
+			method:  http.MethodGet,
+			path:    "/test",
+			headers: map[string]string{"Origin": "https://evil.com"},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
 			wantStatus:     http.StatusOK,
 			wantBody:       "OK",
 			shouldCallNext: true,
 		},
+		"POST request with untrusted origin is denied": {
+			method:  http.MethodPost,
+			path:    "/test",
+			headers: map[string]string{"Origin": "https://evil.com"},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantStatus:     http.StatusForbidden,
+			wantBody:       `{"error":{"code":"FORBIDDEN","message":"CORS origin not allowed"},"success":false}` + "\n",
+			shouldCallNext: false,
+		},
+		"wildcard does not match apex domain": {
+			method:  http.MethodPost,
+			path:    "/test",
+			headers: map[string]string{"Origin": "https://preview.example.com"},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantStatus:     http.StatusForbidden,
+			wantBody:       `{"error":{"code":"FORBIDDEN","message":"CORS origin not allowed"},"success":false}` + "\n",
+			shouldCallNext: false,
+		},
 		"GET request without origin header": {
-			method:         http.MethodGet,
-			origin:         "",
+			method:  http.MethodGet,
+			path:    "/test",
+			headers: nil,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantStatus:     http.StatusOK,
+			wantBody:       "OK",
+			shouldCallNext: true,
+		},
+		"OPTIONS preflight request": {
+			method: http.MethodOptions,
+			path:   "/test",
+			headers: map[string]string{
+				"Origin":                         trustedOrigin,
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "content-type",
+			},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      trustedOrigin,
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+				"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+			wantStatus:     http.StatusNoContent,
+			wantBody:       "",
+			shouldCallNext: false,
+		},
+		"OPTIONS preflight with route specific methods": {
+			method: http.MethodOptions,
+			path:   "/auth/login",
+			headers: map[string]string{
+				"Origin":                        trustedOrigin,
+				"Access-Control-Request-Method": http.MethodGet,
+			},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  trustedOrigin,
+				"Access-Control-Allow-Methods": "GET",
+			},
+			wantStatus:     http.StatusNoContent,
+			wantBody:       "",
+			shouldCallNext: false,
+		},
+		"OPTIONS preflight with method not allowed for route": {
+			method: http.MethodOptions,
+			path:   "/auth/login",
+			headers: map[string]string{
+				"Origin":                        trustedOrigin,
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+			wantStatus:     http.StatusForbidden,
+			wantBody:       `{"error":{"code":"FORBIDDEN","message":"CORS method not allowed"},"success":false}` + "\n",
+			shouldCallNext: false,
+		},
+		"OPTIONS preflight with header not allowed": {
+			method: http.MethodOptions,
+			path:   "/test",
+			headers: map[string]string{
+				"Origin":                         trustedOrigin,
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "Content-Type, X-Custom",
+			},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantStatus:     http.StatusForbidden,
+			wantBody:       `{"error":{"code":"FORBIDDEN","message":"CORS header not allowed"},"success":false}` + "\n",
+			shouldCallNext: false,
+		},
+		"OPTIONS preflight from untrusted origin": {
+			method: http.MethodOptions,
+			path:   "/test",
+			headers: map[string]string{
+				"Origin":                        "https://evil.com",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+			wantStatus:     http.StatusForbidden,
+			wantBody:       `{"error":{"code":"FORBIDDEN","message":"CORS origin not allowed"},"success":false}` + "\n",
+			shouldCallNext: false,
+		},
+		"plain OPTIONS request reaches the handler": {
+			method:  http.MethodOptions,
+			path:    "/test",
+			headers: nil,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "",
+			},
 			wantStatus:     http.StatusOK,
 			wantBody:       "OK",
 			shouldCallNext: true,
@@ -78,9 +230,9 @@ func TestCORS(t *testing.T) {
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
 			handlerCalled = false
-			req := httptest.NewRequest(tt.method, "/test", nil)
-			if tt.origin != "" {
-				req.Header.Set("Origin", tt.origin)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
 			}
 
 			rr := httptest.NewRecorder()
@@ -88,7 +240,7 @@ func TestCORS(t *testing.T) {
 
 			assert.Equal(t, rr.Code, tt.wantStatus, "wrong status code")
 
-			for key, want := range wantHeaders {
+			for key, want := range tt.wantHeaders {
 				got := rr.Header().Get(key)
 				assert.Equal(t, got, want, "wrong header value for header %s", key)
 			}
@@ -98,3 +250,55 @@ func TestCORS(t *testing.T) {
 		})
 	}
 }
+
+func TestCORSPolicyValidation(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := map[string]struct {
+		wantErr     string
+		origins     []string
+		credentials bool
+	}{
+		"no origins": {
+			origins:     nil,
+			credentials: false,
+			wantErr:     "must allow at least one origin",
+		},
+		"origin with path": {
+			origins:     []string{"https://example.com/app"},
+			credentials: false,
+			wantErr:     "must be scheme://host[:port]",
+		},
+		"origin without scheme": {
+			origins:     []string{"example.com"},
+			credentials: false,
+			wantErr:     "must be scheme://host[:port]",
+		},
+		"wildcard in the middle": {
+			origins:     []string{"https://app.*.example.com"},
+			credentials: false,
+			wantErr:     `wildcard must be a leading "*."`,
+		},
+		"any origin with credentials": {
+			origins:     []string{"*"},
+			credentials: true,
+			wantErr:     "cannot be combined with credentials",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := middleware.CORS(log, middleware.CORSPolicy{
+				Routes:           nil,
+				AllowedOrigins:   tt.origins,
+				AllowedMethods:   nil,
+				AllowedHeaders:   nil,
+				ExposedHeaders:   nil,
+				MaxAge:           0,
+				AllowCredentials: tt.credentials,
+			})
+
+			assert.ErrorContains(t, err, tt.wantErr, "policy validation error")
+		})
+	}
+}
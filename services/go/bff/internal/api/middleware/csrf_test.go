@@ -0,0 +1,177 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/bff/internal/api/middleware"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/testlogger"
+)
+
+// csrfStore is a CSRFTokenStore backed by a map of session ids to tokens.
+type csrfStore struct {
+	err    error
+	tokens map[string]string
+}
+
+func (s csrfStore) CSRFToken(_ context.Context, sessionID string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.tokens[sessionID], nil
+}
+
+func TestCSRF(t *testing.T) {
+	store := csrfStore{err: nil, tokens: map[string]string{"session-1": "token-1", "session-2": ""}}
+
+	tests := map[string]struct {
+		store        csrfStore
+		method       string
+		session      string
+		cookie       string
+		header       string
+		wantMessage  string
+		wantStatus   int
+		wantNextCall bool
+	}{
+		"safe method passes without token": {
+			store:        store,
+			method:       http.MethodGet,
+			session:      "session-1",
+			cookie:       "",
+			header:       "",
+			wantStatus:   http.StatusOK,
+			wantMessage:  "",
+			wantNextCall: true,
+		},
+		"request without session cookie passes": {
+			store:        store,
+			method:       http.MethodPost,
+			session:      "",
+			cookie:       "",
+			header:       "",
+			wantStatus:   http.StatusOK,
+			wantMessage:  "",
+			wantNextCall: true,
+		},
+		"matching token passes": {
+			store:        store,
+			method:       http.MethodPost,
+			session:      "session-1",
+			cookie:       "token-1",
+			header:       "token-1",
+			wantStatus:   http.StatusOK,
+			wantMessage:  "",
+			wantNextCall: true,
+		},
+		"missing header is rejected": {
+			store:        store,
+			method:       http.MethodDelete,
+			session:      "session-1",
+			cookie:       "token-1",
+			header:       "",
+			wantStatus:   http.StatusForbidden,
+			wantMessage:  "missing CSRF token",
+			wantNextCall: false,
+		},
+		"header not matching cookie is rejected": {
+			store:        store,
+			method:       http.MethodPut,
+			session:      "session-1",
+			cookie:       "token-1",
+			header:       "other",
+			wantStatus:   http.StatusForbidden,
+			wantMessage:  "CSRF token mismatch",
+			wantNextCall: false,
+		},
+		"missing cookie is rejected": {
+			store:        store,
+			method:       http.MethodPatch,
+			session:      "session-1",
+			cookie:       "",
+			header:       "token-1",
+			wantStatus:   http.StatusForbidden,
+			wantMessage:  "CSRF token mismatch",
+			wantNextCall: false,
+		},
+		"token of another session is rejected": {
+			store:        store,
+			method:       http.MethodPost,
+			session:      "session-1",
+			cookie:       "stolen",
+			header:       "stolen",
+			wantStatus:   http.StatusForbidden,
+			wantMessage:  "CSRF token mismatch",
+			wantNextCall: false,
+		},
+		"session without token is rejected": {
+			store:        store,
+			method:       http.MethodPost,
+			session:      "session-2",
+			cookie:       "forged",
+			header:       "forged",
+			wantStatus:   http.StatusForbidden,
+			wantMessage:  "CSRF token mismatch",
+			wantNextCall: false,
+		},
+		"unknown session is unauthorized": {
+			store:        csrfStore{err: apperror.New(apperror.CodeUnauthorized, "session not found"), tokens: nil},
+			method:       http.MethodPost,
+			session:      "gone",
+			cookie:       "token-1",
+			header:       "token-1",
+			wantStatus:   http.StatusUnauthorized,
+			wantMessage:  "session not found",
+			wantNextCall: false,
+		},
+		"store failure is internal error": {
+			store:        csrfStore{err: errors.New("kv unavailable"), tokens: nil},
+			method:       http.MethodPost,
+			session:      "session-1",
+			cookie:       "token-1",
+			header:       "token-1",
+			wantStatus:   http.StatusInternalServerError,
+			wantMessage:  "internal server error",
+			wantNextCall: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, _ := testlogger.New()
+			nextCalled := false
+			handler := middleware.CSRF(log, tt.store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			}))
+
+			req := httptest.NewRequest(tt.method, "/api/orders", nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: api.SessionTokenCookieName, Value: tt.session})
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: api.CSRFTokenCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(api.CSRFTokenHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, rec.Code, tt.wantStatus, "status code")
+			assert.Equal(t, nextCalled, tt.wantNextCall, "next handler called")
+			if tt.wantMessage != "" {
+				assert.StringContains(t, rec.Body.String(), tt.wantMessage, "error message")
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				assert.StringContains(t, rec.Body.String(), string(apperror.CodeForbidden), "error code")
+			}
+		})
+	}
+}
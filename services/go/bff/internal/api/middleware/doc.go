@@ -6,11 +6,21 @@
 //
 // Overview of available middleware:
 //
+//   - RequestID: assigns each request an id (reusing X-Request-Id when valid).
+//   - ErrorFormat: selects the default error format, either the JSON envelope
+//     or RFC 9457 Problem Details (application/problem+json).
 //   - Recover: gracefully recovers from panics and returns a 500 response.
 //   - Error: wraps handlers that return errors and converts them to JSON responses.
 //   - Logging: logs request and response details using structured logging (slog).
-//   - CORS: sets proper CORS headers for cross-origin requests.
-//   - RequireJSON: ensures that incoming requests use the "application/json" Content-Type.
+//   - CORS: applies a CORSPolicy (multiple origins, wildcard subdomains and
+//     per-route rules) to cross-origin and preflight requests.
+//   - RequireJSON: negotiates JSON media types (parameters, q-values and +json
+//     suffix types) and enforces a request body size limit.
+//   - SecurityHeaders: sets HSTS, X-Content-Type-Options, Referrer-Policy,
+//     frame protection and Cross-Origin-Opener-Policy; NoStore disables
+//     caching for routes handling credentials or sessions.
+//   - CSRF: validates double-submit CSRF tokens bound to the session on
+//     unsafe, cookie-authenticated requests.
 //   - Auth: performs authentication and authorization based on request headers or tokens.
 //
 // The middleware can be composed using the chain utility (chain.go)
@@ -18,7 +28,10 @@
 //
 // Example usage:
 //
-//	cors, err := middleware.CORS(log, "https://www.trustedorigin.com")
+//	cors, err := middleware.CORS(log, middleware.CORSPolicy{
+//	    AllowedOrigins:   []string{"https://www.trustedorigin.com"},
+//	    AllowCredentials: true,
+//	})
 //	if err != nil {
 //	    slog.Error("failed to initialize CORS middleware", "err", err)
 //	    os.Exit(1)
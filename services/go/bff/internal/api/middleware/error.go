@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"go-services/bff/internal/api"
-	"go-services/library/apperror"
 )
 
 type (
@@ -21,7 +20,7 @@ type (
 //	func(w http.ResponseWriter, r *http.Request) error
 //
 // and converts returned errors into structured JSON responses
-// using the api.SendErrorLog helper. The middleware distinguishes between
+// using the api.HandleError helper. The middleware distinguishes between
 // expected client-side errors (4xx) and unexpected server-side errors (5xx),
 // applying appropriate logging levels and response codes.
 //
@@ -33,7 +32,11 @@ type (
 //
 //   - Logs with level ERROR for 5xx server errors.
 //
-//   - Sends an error response using the status code, error code, and message.
+//   - Sends an error response using the status code, error code, and message,
+//     plus any field violations and metadata carried by the error.
+//
+//   - If the error wraps an *http.MaxBytesError* (the request body exceeded
+//     the RequireJSON limit), it is treated as apperror.CodeTooLarge (413).
 //
 //   - If the error is not an *apperror.AppError*:
 //
@@ -60,47 +63,7 @@ func Error(log *slog.Logger) errorHandlerWrapper {
 	return func(handler handlerFuncWithError) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			if err := handler(w, r); err != nil {
-				ctx := r.Context()
-				if appErr, ok := apperror.As(err); ok {
-					statusCode := appErr.ToHTTPStatus()
-					if statusCode >= 500 {
-						log.ErrorContext(
-							ctx,
-							"backend error",
-							"method", r.Method,
-							"path", r.URL.Path,
-							"code", appErr.Code,
-							"error", err,
-						)
-					} else {
-						log.WarnContext(r.Context(), "client error",
-							"method", r.Method,
-							"path", r.URL.Path,
-							"status", statusCode,
-							"code", appErr.Code,
-							"msg", appErr.Msg,
-						)
-					}
-
-					api.SendErrorLog(ctx, log, w, statusCode, appErr.Code, appErr.Msg)
-					return
-				}
-
-				log.ErrorContext(
-					ctx,
-					"unhandled error",
-					"method", r.Method,
-					"path", r.URL.Path,
-					"error", err,
-				)
-				api.SendErrorLog(
-					ctx,
-					log,
-					w,
-					http.StatusInternalServerError,
-					apperror.CodeInternalError,
-					"internal server error",
-				)
+				api.HandleError(log, w, r, err)
 			}
 		}
 	}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-services/bff/internal/api"
+)
+
+// ErrorFormat returns an HTTP middleware that sets the default format used for
+// error responses written by the middleware in this package.
+//
+// Behavior:
+//   - api.ErrorFormatEnvelope keeps the {"success":false,"error":{...}}
+//     envelope.
+//   - api.ErrorFormatProblem emits RFC 9457 Problem Details with the
+//     "application/problem+json" media type.
+//
+// Independently of the default, a client that prefers
+// "application/problem+json" over "application/json" in its Accept header
+// always receives Problem Details, and one that prefers "application/json"
+// always receives the envelope (see api.NegotiateErrorFormat).
+//
+// Example:
+//
+//	chain := middleware.NewChain().Add(
+//		middleware.RequestID(),
+//		middleware.ErrorFormat(api.ErrorFormatProblem),
+//		middleware.Recover(logger),
+//	)
+func ErrorFormat(format api.ErrorFormat) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(api.WithErrorFormat(r.Context(), format)))
+		})
+	}
+}
@@ -1,15 +1,18 @@
 package middleware_test
 
 import (
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"go-services/bff/internal/api"
 	"go-services/bff/internal/api/middleware"
 	"go-services/library/apperror"
 	"go-services/library/assert"
+	"go-services/library/require"
 	"go-services/library/testlogger"
 )
 
@@ -91,6 +94,24 @@ func TestError(t *testing.T) {
 				"error":  genericErr,
 			},
 		},
+		"max bytes error - 413 content too large": {
+			handler: func(w http.ResponseWriter, r *http.Request) error {
+				return apperror.Wrap(
+					apperror.CodeInvalidFormat,
+					&http.MaxBytesError{Limit: 16},
+					"failed to decode request body",
+				)
+			},
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+			wantLogLevel:   slog.LevelWarn,
+			wantLogged:     true,
+			wantMessage:    "client error",
+			wantFields: map[string]any{
+				"status": int64(413),
+				"code":   apperror.CodeTooLarge,
+				"msg":    "request body must not exceed 16 bytes",
+			},
+		},
 		"app error - 503 service unavailable": {
 			handler: func(w http.ResponseWriter, r *http.Request) error {
 				return apperror.New(apperror.CodeServiceUnavailable, "service temporarily unavailable")
@@ -144,7 +165,13 @@ func TestError(t *testing.T) {
 func TestErrorBoundary499(t *testing.T) {
 	// Test the boundary between 4xx and 5xx errors (status 499 should log as warn)
 	handler := func(w http.ResponseWriter, r *http.Request) error {
-		return &apperror.AppError{Code: apperror.CodeTooManyRequests, Msg: "too many request", Err: nil}
+		return &apperror.AppError{
+			Code:       apperror.CodeTooManyRequests,
+			Msg:        "too many request",
+			Err:        nil,
+			Metadata:   nil,
+			Violations: nil,
+		}
 	}
 
 	log, logCapture := testlogger.New()
@@ -265,3 +292,53 @@ func TestErrorMixedLevels(t *testing.T) {
 		logAssert.AtIndex(i, tt.wantLevel, tt.wantMessage, "log for request %d", i)
 	}
 }
+
+func TestErrorFormatProblem(t *testing.T) {
+	log, _ := testlogger.New()
+	handler := middleware.RequestID()(middleware.ErrorFormat(api.ErrorFormatProblem)(
+		http.HandlerFunc(middleware.Error(log)(func(w http.ResponseWriter, r *http.Request) error {
+			return apperror.New(apperror.CodeNotFound, "resource not found")
+		})),
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(api.RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusNotFound, "status code")
+	assert.Equal(t, rec.Header().Get("Content-Type"), api.ContentTypeProblemJSON, "content type")
+
+	var got api.ProblemDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), "decode problem details")
+	assert.Equal(t, got.Status, http.StatusNotFound, "problem status")
+	assert.Equal(t, got.Code, apperror.CodeNotFound, "problem code")
+	assert.Equal(t, got.Detail, "resource not found", "problem detail")
+	assert.Equal(t, got.RequestID, "req-1", "problem request id")
+}
+
+func TestErrorViolations(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.NewValidation(
+			apperror.FieldViolation{Field: "name", Message: "is required", Code: apperror.CodeMissingField},
+			apperror.FieldViolation{Field: "age", Message: "must be at least 18", Code: apperror.CodeOutOfRange},
+		)
+	}
+
+	log, _ := testlogger.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	rec := httptest.NewRecorder()
+
+	middleware.Error(log)(handler)(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusBadRequest, "status code")
+
+	var got api.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), "decode error envelope")
+	assert.Equal(t, got.Error.Code, apperror.CodeInvalidInput, "error code")
+	assert.Equal(t, got.Error.Violations, []apperror.FieldViolation{
+		{Field: "name", Message: "is required", Code: apperror.CodeMissingField},
+		{Field: "age", Message: "must be at least 18", Code: apperror.CodeOutOfRange},
+	}, "violations")
+}
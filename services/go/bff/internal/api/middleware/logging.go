@@ -8,29 +8,6 @@ import (
 	"strings"
 )
 
-// responseWriter is a wrapper to capture status code and body
-type responseWriter struct {
-	http.ResponseWriter
-	body       *bytes.Buffer
-	statusCode int
-}
-
-// WriteHeader overrides the default WriteHeader method to record the
-// HTTP status code before writing it to the underlying ResponseWriter.
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-
-// Write overrides the default Write method to capture the response body
-// while still passing it through to the underlying ResponseWriter.
-//
-// It stores the written bytes in the internal buffer for later logging.
-func (rw *responseWriter) Write(b []byte) (int, error) {
-	rw.body.Write(b)
-	return rw.ResponseWriter.Write(b)
-}
-
 // Logging returns an HTTP middleware that logs both incoming requests
 // and outgoing responses using the provided slog.Logger.
 //
@@ -99,11 +76,7 @@ func Logging(log *slog.Logger) func(http.Handler) http.Handler {
 				)
 			}
 
-			rw := &responseWriter{
-				ResponseWriter: w,
-				statusCode:     http.StatusOK,
-				body:           &bytes.Buffer{},
-			}
+			rw := newResponseWriter(w, true)
 
 			next.ServeHTTP(rw, r)
 
@@ -1,13 +1,56 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
 
 	"go-services/bff/internal/api"
 	"go-services/library/apperror"
 )
 
+// PanicReport describes a panic recovered by the Recover middleware.
+type PanicReport struct {
+	// Value is the value passed to panic.
+	Value any
+	// RequestID is the id assigned by the RequestID middleware, if any.
+	RequestID string
+	// Method is the HTTP method of the request.
+	Method string
+	// Route is the http.ServeMux pattern that matched the request. The mux
+	// sets it on the request it receives, so it is only known when the
+	// middleware between Recover and the mux pass the request on unchanged,
+	// rather than replacing it with r.WithContext. Otherwise, and for
+	// requests no pattern matched, Route is the URL path.
+	Route string
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// PanicReporter receives every panic recovered by the Recover middleware,
+// for example to forward it to an error tracking service. It is called
+// synchronously, before the error response is written.
+type PanicReporter func(ctx context.Context, report PanicReport)
+
+// recoverConfig holds the settings for Recover.
+type recoverConfig struct {
+	reporter PanicReporter
+}
+
+// RecoverOption customizes the Recover middleware.
+type RecoverOption func(*recoverConfig)
+
+// WithPanicReporter registers a PanicReporter that is called for every
+// recovered panic.
+func WithPanicReporter(reporter PanicReporter) RecoverOption {
+	return func(c *recoverConfig) {
+		c.reporter = reporter
+	}
+}
+
 // Recover returns an HTTP middleware that recovers from panics
 // in downstream handlers, logs the panic, and sends a standardized
 // internal server error response to the client.
@@ -16,11 +59,20 @@ import (
 // unexpected panics in request handlers. When a panic occurs,
 // it performs the following steps:
 //
-//  1. Logs the panic message and stack trace at the error level
-//     using the provided slog.Logger.
-//  2. Sends a structured error response with HTTP 500 (Internal Server Error)
-//     using api.SendErrorLog, with the error code set to
-//     apperror.CodeInternalError.
+//  1. Re-panics with http.ErrAbortHandler untouched, since that panic is
+//     the documented way for a handler to abort a response and is handled
+//     quietly by net/http.
+//  2. Logs the panic value and stack trace at the error level using the
+//     provided slog.Logger, together with the request id, method, path and
+//     matched route.
+//  3. Passes a PanicReport to the reporter configured with
+//     WithPanicReporter, if any.
+//  4. If the handler has not written anything yet, sends a structured error
+//     response with HTTP 500 (Internal Server Error) using api.SendErrorLog,
+//     with the error code set to apperror.CodeInternalError.
+//     If the response was already committed, a second body would corrupt
+//     it, so the connection is aborted with http.ErrAbortHandler instead and
+//     the client sees a truncated response.
 //
 // Example:
 //
@@ -31,7 +83,11 @@ import (
 //			panic("unexpected error")
 //		})
 //
-//		handler := middleware.Recover(logger)(mux)
+//		handler := middleware.Recover(logger, middleware.WithPanicReporter(
+//			func(ctx context.Context, report middleware.PanicReport) {
+//				tracker.CaptureException(ctx, report)
+//			},
+//		))(mux)
 //		http.ListenAndServe(":8080", handler)
 //	}
 //
@@ -41,7 +97,13 @@ import (
 //	  "time": "2025-11-04T00:00:00Z",
 //	  "level": "ERROR",
 //	  "msg": "panic recovered",
-//	  "err": "unexpected error"
+//	  "err": "unexpected error",
+//	  "request_id": "4f1c0b8e9d2a4c7b8e6f5a3d2c1b0a99",
+//	  "method": "GET",
+//	  "path": "/panic",
+//	  "route": "/panic",
+//	  "response_written": false,
+//	  "stack": "goroutine 7 [running]:\n..."
 //	}
 //
 // If a panic occurs, the client receives:
@@ -53,26 +115,69 @@ import (
 //
 // This middleware should typically be one of the outermost layers
 // in the HTTP middleware chain, ensuring that all panics from inner
-// handlers are caught and logged.
-func Recover(log *slog.Logger) func(http.Handler) http.Handler {
+// handlers are caught and logged. Place it after RequestID so the request
+// id is available, and after any middleware replacing the request, so the
+// route matched by the mux is available too.
+func Recover(log *slog.Logger, opts ...RecoverOption) func(http.Handler) http.Handler {
+	cfg := &recoverConfig{reporter: nil}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := newResponseWriter(w, false)
 			defer func() {
-				if rec := recover(); rec != nil {
-					ctx := r.Context()
-					log.ErrorContext(ctx, "panic recovered", "err", rec)
-					api.SendErrorLog(
-						ctx,
-						log,
-						w,
-						http.StatusInternalServerError,
-						apperror.CodeInternalError,
-						"internal server error",
-					)
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				// By now the mux has set r.Pattern if it received r itself.
+				ctx := r.Context()
+				report := PanicReport{
+					Value:     rec,
+					RequestID: api.RequestIDFromContext(ctx),
+					Method:    r.Method,
+					Route:     r.Pattern,
+					Stack:     debug.Stack(),
+				}
+				if report.Route == "" {
+					report.Route = r.URL.Path
+				}
+
+				log.ErrorContext(
+					ctx,
+					"panic recovered",
+					"err", fmt.Sprint(rec),
+					"request_id", report.RequestID,
+					"method", report.Method,
+					"path", r.URL.Path,
+					"route", report.Route,
+					"response_written", rw.Written(),
+					"stack", string(report.Stack),
+				)
+				if cfg.reporter != nil {
+					cfg.reporter(ctx, report)
+				}
+
+				if rw.Written() {
+					panic(http.ErrAbortHandler)
 				}
+				api.SendErrorLog(
+					r,
+					log,
+					rw,
+					http.StatusInternalServerError,
+					apperror.CodeInternalError,
+					"internal server error",
+				)
 			}()
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(rw, r)
 		})
 	}
 }
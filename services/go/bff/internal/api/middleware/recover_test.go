@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"go-services/bff/internal/api"
 	"go-services/library/assert"
+	"go-services/library/require"
 	"go-services/library/testlogger"
 )
 
@@ -65,3 +68,117 @@ func TestRecoverMiddleware(t *testing.T) {
 			Count(0, "should not log for normal request")
 	})
 }
+
+func TestRecoverMiddlewareContext(t *testing.T) {
+	log, logCapture := testlogger.New()
+
+	var reports []PanicReport
+	reporter := func(ctx context.Context, report PanicReport) {
+		reports = append(reports, report)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		panic("order exploded")
+	})
+	handler := RequestID()(Recover(log, WithPanicReporter(reporter))(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set(api.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusInternalServerError, "http status")
+	testlogger.Assert(t, logCapture.GetOutput()).
+		Count(1, "should log the recovered panic once").
+		AtIndex(0, slog.LevelError, "panic recovered", "panic recovered message").
+		HasField(0, "request_id", "req-42", "request id").
+		HasField(0, "method", "GET", "method").
+		HasField(0, "path", "/orders/42", "path").
+		HasField(0, "route", "GET /orders/{id}", "route").
+		HasField(0, "response_written", false, "response written")
+
+	require.SliceLen(t, reports, 1, "reported panics")
+	assert.Equal(t, reports[0].Value, any("order exploded"), "reported panic value")
+	assert.Equal(t, reports[0].RequestID, "req-42", "reported request id")
+	assert.Equal(t, reports[0].Route, "GET /orders/{id}", "reported route")
+	assert.StringContains(t, string(reports[0].Stack), "recover_test.go", "reported stack trace")
+}
+
+func TestRecoverMiddlewareRoute(t *testing.T) {
+	tests := map[string]struct {
+		want  string
+		inner []func(http.Handler) http.Handler
+	}{
+		"pass-through middleware": {
+			inner: []func(http.Handler) http.Handler{Logging(slog.New(slog.DiscardHandler)), NoStore()},
+			want:  "GET /orders/{id}",
+		},
+		"request replaced": {
+			inner: []func(http.Handler) http.Handler{ErrorFormat(api.ErrorFormatEnvelope)},
+			want:  "/orders/42",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, _ := testlogger.New()
+			var reports []PanicReport
+			reporter := func(ctx context.Context, report PanicReport) {
+				reports = append(reports, report)
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+				panic("order exploded")
+			})
+			chain := NewChain()
+			chain.Add(Recover(log, WithPanicReporter(reporter)))
+			chain.Add(tt.inner...)
+
+			chain.Apply(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+
+			require.SliceLen(t, reports, 1, "reported panics")
+			assert.Equal(t, reports[0].Route, tt.want, "reported route")
+		})
+	}
+}
+
+func TestRecoverMiddlewareCommittedResponse(t *testing.T) {
+	log, logCapture := testlogger.New()
+
+	handler := Recover(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		if _, err := w.Write([]byte(`{"partial":`)); err != nil {
+			t.Errorf("failed to write partial body: %v", err)
+		}
+		panic("stream broke")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	rec := httptest.NewRecorder()
+
+	assert.Panics(t, func() { handler.ServeHTTP(rec, req) }, "should abort the committed response")
+
+	assert.Equal(t, rec.Code, http.StatusAccepted, "status stays as written")
+	assert.Equal(t, rec.Body.String(), `{"partial":`, "no error body appended")
+	testlogger.Assert(t, logCapture.GetOutput()).
+		Count(1, "should log the recovered panic once").
+		HasField(0, "response_written", true, "response written")
+}
+
+func TestRecoverMiddlewareAbortHandler(t *testing.T) {
+	log, logCapture := testlogger.New()
+
+	handler := Recover(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abort", nil)
+	rec := httptest.NewRecorder()
+
+	assert.Panics(t, func() { handler.ServeHTTP(rec, req) }, "should re-panic with http.ErrAbortHandler")
+	testlogger.Assert(t, logCapture.GetOutput()).
+		Empty("http.ErrAbortHandler is not logged")
+}
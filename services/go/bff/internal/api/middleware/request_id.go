@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go-services/bff/internal/api"
+)
+
+// maxRequestIDLength bounds the size of a client supplied request id.
+const maxRequestIDLength = 128
+
+// RequestID returns an HTTP middleware that assigns every request an id.
+//
+// Behavior:
+//   - If the request carries a valid "X-Request-Id" header, that id is reused
+//     so ids can be propagated from upstream proxies.
+//   - Otherwise a random 128-bit hex id is generated.
+//   - The id is stored in the request context (see api.RequestIDFromContext)
+//     and echoed in the "X-Request-Id" response header.
+//
+// A client supplied id is considered valid if it is non-empty, at most 128
+// characters long, and consists only of printable ASCII characters without
+// spaces.
+//
+// Error responses written in the Problem Details format include the id as the
+// "requestId" extension member.
+//
+// Example:
+//
+//	chain := middleware.NewChain().Add(
+//		middleware.RequestID(),
+//		middleware.Recover(logger),
+//	)
+//	http.ListenAndServe(":8080", chain.Apply(mux))
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(api.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+
+			w.Header().Set(api.RequestIDHeader, requestID)
+			next.ServeHTTP(w, r.WithContext(api.WithRequestID(r.Context(), requestID)))
+		})
+	}
+}
+
+// validRequestID reports whether a client supplied request id can be reused.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit id encoded as hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // crypto/rand.Read never returns an error.
+	return hex.EncodeToString(b)
+}
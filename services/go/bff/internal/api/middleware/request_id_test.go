@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/bff/internal/api/middleware"
+	"go-services/library/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := map[string]struct {
+		header    string
+		wantReuse bool
+	}{
+		"no header generates id":         {header: "", wantReuse: false},
+		"valid header is reused":         {header: "abc-123", wantReuse: true},
+		"header with spaces is replaced": {header: "abc 123", wantReuse: false},
+		"oversized header is replaced":   {header: strings.Repeat("a", 129), wantReuse: false},
+		"non ascii header is replaced":   {header: "abcé", wantReuse: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var ctxID string
+			handler := middleware.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = api.RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.header != "" {
+				req.Header.Set(api.RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			gotID := rec.Header().Get(api.RequestIDHeader)
+			assert.Equal(t, ctxID, gotID, "context id matches response header")
+			if tt.wantReuse {
+				assert.Equal(t, gotID, tt.header, "reused request id")
+			} else {
+				assert.Equal(t, len(gotID), 32, "generated request id length")
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -8,16 +9,44 @@ import (
 	"go-services/library/apperror"
 )
 
+// DefaultMaxBodyBytes is the request body limit applied by RequireJSON when
+// WithMaxBodyBytes is not provided.
+const DefaultMaxBodyBytes int64 = 1 << 20 // 1 MiB
+
+// requireJSONConfig holds the settings for RequireJSON.
+type requireJSONConfig struct {
+	maxBodyBytes int64
+}
+
+// RequireJSONOption customizes the RequireJSON middleware.
+type RequireJSONOption func(*requireJSONConfig)
+
+// WithMaxBodyBytes sets the maximum accepted request body size in bytes.
+// Values less than or equal to zero disable the limit.
+func WithMaxBodyBytes(n int64) RequireJSONOption {
+	return func(c *requireJSONConfig) {
+		c.maxBodyBytes = n
+	}
+}
+
 // RequireJSON returns an HTTP middleware that validates requests
 // to ensure they conform to JSON expectations.
 //
 // Behavior:
 //  1. OPTIONS, GET, and HEAD requests are passed through without checks.
-//  2. Other methods (POST, PUT, PATCH, etc.) require a "Content-Type" header of "application/json".
+//  2. Other methods (POST, PUT, PATCH, etc.) require a JSON "Content-Type"
+//     whenever they carry a body or declare a Content-Type at all. Media type
+//     parameters are allowed and JSON suffix types are accepted, so
+//     "application/json; charset=utf-8" and "application/merge-patch+json"
+//     both pass.
 //     - If the Content-Type is invalid, responds with 415 Unsupported Media Type using api.SendErrorLog.
-//  3. If an "Accept" header is provided and does not allow "application/json" or "*/*",
-//     responds with 406 Not Acceptable.
-//  4. Otherwise, the request proceeds to the next handler.
+//  3. If an "Accept" header is provided and none of its media ranges (taking
+//     q-values into account) allow JSON, responds with 406 Not Acceptable.
+//  4. If the declared Content-Length exceeds the body limit, responds with
+//     413 Content Too Large. Otherwise the body is wrapped in
+//     http.MaxBytesReader, so reading past the limit fails with an
+//     *http.MaxBytesError that the Error middleware maps to 413.
+//  5. Otherwise, the request proceeds to the next handler.
 //
 // This middleware helps enforce proper content negotiation and ensures
 // clients are sending and expecting JSON payloads where appropriate.
@@ -31,7 +60,7 @@ import (
 //		w.Write([]byte(`{"message":"ok"}`))
 //	})
 //
-//	handler := middleware.RequireJSON(logger)(mux)
+//	handler := middleware.RequireJSON(logger, middleware.WithMaxBodyBytes(64<<10))(mux)
 //	http.ListenAndServe(":8080", handler)
 //
 // Example behaviors:
@@ -42,9 +71,20 @@ import (
 //  2. PATCH with "Accept: text/html"
 //     -> HTTP 406 Not Acceptable
 //
-//  3. GET request
+//  3. POST with "Accept: application/json, text/plain"
+//     -> Passes through
+//
+//  4. PUT with a 2 MiB body and the default limit
+//     -> HTTP 413 Content Too Large
+//
+//  5. GET request
 //     -> Passes through without validation
-func RequireJSON(log *slog.Logger) func(http.Handler) http.Handler {
+func RequireJSON(log *slog.Logger, opts ...RequireJSONOption) func(http.Handler) http.Handler {
+	cfg := &requireJSONConfig{maxBodyBytes: DefaultMaxBodyBytes}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == http.MethodOptions || r.Method == http.MethodGet || r.Method == http.MethodHead {
@@ -52,10 +92,10 @@ func RequireJSON(log *slog.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
-			ctx := r.Context()
-			if r.Header.Get("Content-Type") != api.ContentTypeJSON {
+			if contentType := r.Header.Get("Content-Type"); (contentType != "" || hasBody(r)) &&
+				!api.IsJSONMediaType(contentType) {
 				api.SendErrorLog(
-					ctx,
+					r,
 					log,
 					w,
 					http.StatusUnsupportedMediaType,
@@ -65,11 +105,9 @@ func RequireJSON(log *slog.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
-			// TODO: move media type to constants
-			if accept := r.Header.Get("Accept"); accept != "" &&
-				accept != api.ContentTypeJSON && accept != "*/*" {
+			if accept := r.Header.Values("Accept"); !api.AcceptsJSON(accept) {
 				api.SendErrorLog(
-					ctx,
+					r,
 					log,
 					w,
 					http.StatusNotAcceptable,
@@ -79,7 +117,28 @@ func RequireJSON(log *slog.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
+			if cfg.maxBodyBytes > 0 && r.Body != nil {
+				if r.ContentLength > cfg.maxBodyBytes {
+					api.SendErrorLog(
+						r,
+						log,
+						w,
+						http.StatusRequestEntityTooLarge,
+						apperror.CodeTooLarge,
+						fmt.Sprintf("request body must not exceed %d bytes", cfg.maxBodyBytes),
+					)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, cfg.maxBodyBytes)
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// hasBody reports whether the request declares a body, either through a
+// non-zero Content-Length or a chunked Transfer-Encoding.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength != 0 || len(r.TransferEncoding) > 0
+}
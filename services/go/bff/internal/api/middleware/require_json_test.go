@@ -1,6 +1,8 @@
 package middleware_test
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -8,6 +10,8 @@ import (
 
 	"go-services/bff/internal/api"
 	"go-services/bff/internal/api/middleware"
+	"go-services/library/apperror"
+	"go-services/library/assert"
 	"go-services/library/testlogger"
 )
 
@@ -95,6 +99,62 @@ func TestRequireJSONMiddleware(t *testing.T) {
 			wantStatus:       http.StatusOK,
 			wantBodyContains: "",
 		},
+		"POST with JSON Content-Type and charset": {
+			method:           http.MethodPost,
+			contentType:      "application/json; charset=utf-8",
+			accept:           "",
+			wantStatus:       http.StatusOK,
+			wantBodyContains: "",
+		},
+		"PATCH with merge patch Content-Type": {
+			method:           http.MethodPatch,
+			contentType:      "application/merge-patch+json",
+			accept:           api.ContentTypeJSON,
+			wantStatus:       http.StatusOK,
+			wantBodyContains: "",
+		},
+		"POST with malformed Content-Type": {
+			method:           http.MethodPost,
+			contentType:      "application/json; charset",
+			accept:           "",
+			wantStatus:       http.StatusUnsupportedMediaType,
+			wantBodyContains: "Content-Type must be application/json string",
+		},
+		"POST with Accept list including JSON": {
+			method:           http.MethodPost,
+			contentType:      api.ContentTypeJSON,
+			accept:           "application/json, text/plain",
+			wantStatus:       http.StatusOK,
+			wantBodyContains: "",
+		},
+		"POST with Accept problem JSON only": {
+			method:           http.MethodPost,
+			contentType:      api.ContentTypeJSON,
+			accept:           "application/problem+json",
+			wantStatus:       http.StatusNotAcceptable,
+			wantBodyContains: "Accept header must include application/json",
+		},
+		"POST with Accept application wildcard and q-value": {
+			method:           http.MethodPost,
+			contentType:      api.ContentTypeJSON,
+			accept:           "text/html, application/*;q=0.2",
+			wantStatus:       http.StatusOK,
+			wantBodyContains: "",
+		},
+		"POST with JSON refused by q-value": {
+			method:           http.MethodPost,
+			contentType:      api.ContentTypeJSON,
+			accept:           "application/json;q=0, */*",
+			wantStatus:       http.StatusNotAcceptable,
+			wantBodyContains: "Accept header must include application/json",
+		},
+		"DELETE without body or Content-Type": {
+			method:           http.MethodDelete,
+			contentType:      "",
+			accept:           "",
+			wantStatus:       http.StatusOK,
+			wantBodyContains: "",
+		},
 	}
 
 	for name, tt := range tests {
@@ -122,3 +182,68 @@ func TestRequireJSONMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireJSONMiddlewareBodyLimit(t *testing.T) {
+	log, _ := testlogger.New()
+	const limit = 16
+
+	readHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if !errors.As(err, &maxBytesErr) {
+				t.Errorf("want *http.MaxBytesError, got %v", err)
+			}
+			api.SendErrorLog(
+				r,
+				log,
+				w,
+				http.StatusRequestEntityTooLarge,
+				apperror.CodeTooLarge,
+				"body read failed",
+			)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.RequireJSON(log, middleware.WithMaxBodyBytes(limit))(readHandler)
+
+	tests := map[string]struct {
+		body             string
+		wantBodyContains string
+		contentLength    int64
+		wantStatus       int
+	}{
+		"body within limit": {
+			body:             `{"a":1}`,
+			contentLength:    7,
+			wantStatus:       http.StatusOK,
+			wantBodyContains: "",
+		},
+		"declared length over limit": {
+			body:             `{"name":"far too long"}`,
+			contentLength:    23,
+			wantStatus:       http.StatusRequestEntityTooLarge,
+			wantBodyContains: `"code":"TOO_LARGE"`,
+		},
+		"chunked body over limit": {
+			body:             `{"name":"far too long"}`,
+			contentLength:    -1,
+			wantStatus:       http.StatusRequestEntityTooLarge,
+			wantBodyContains: "body read failed",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			req.Header.Set("Content-Type", api.ContentTypeJSON)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, rec.Code, tt.wantStatus, "response status")
+			assert.StringContains(t, rec.Body.String(), tt.wantBodyContains, "response body")
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps an http.ResponseWriter to record the status code,
+// whether the response has been committed, and optionally the body.
+//
+// It is shared by the middleware in this package. The optional interfaces of
+// the underlying writer stay reachable: Flush and Hijack are forwarded, and
+// Unwrap lets http.ResponseController find any other capability.
+type responseWriter struct {
+	http.ResponseWriter
+	// body receives a copy of everything written, or nil to skip capturing.
+	body       *bytes.Buffer
+	statusCode int
+	written    bool
+}
+
+// newResponseWriter wraps w. If captureBody is true, the response body is
+// copied into an internal buffer.
+func newResponseWriter(w http.ResponseWriter, captureBody bool) *responseWriter {
+	rw := &responseWriter{
+		ResponseWriter: w,
+		body:           nil,
+		statusCode:     http.StatusOK,
+		written:        false,
+	}
+	if captureBody {
+		rw.body = &bytes.Buffer{}
+	}
+	return rw
+}
+
+// WriteHeader overrides the default WriteHeader method to record the
+// HTTP status code before writing it to the underlying ResponseWriter.
+func (rw *responseWriter) WriteHeader(code int) {
+	// 1xx responses are informational and do not commit the response.
+	if !rw.written && code >= http.StatusOK {
+		rw.statusCode = code
+		rw.written = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write overrides the default Write method to capture the response body
+// while still passing it through to the underlying ResponseWriter.
+//
+// It stores the written bytes in the internal buffer for later logging.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.written = true
+	if rw.body != nil {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client. Flushing commits the
+// response headers.
+func (rw *responseWriter) Flush() {
+	rw.written = true
+	//nolint:errcheck // http.Flusher cannot report errors; unsupported writers are a no-op.
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection, for example to upgrade
+// to WebSocket. The hijacked connection counts as a committed response.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.written = true
+	}
+	return conn, buf, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Written reports whether the response headers have been sent, after which
+// the status code can no longer change.
+func (rw *responseWriter) Written() bool {
+	return rw.written
+}
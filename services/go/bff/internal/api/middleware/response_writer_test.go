@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-services/library/assert"
+)
+
+func TestResponseWriter(t *testing.T) {
+	t.Run("records status and body", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := newResponseWriter(rec, true)
+
+		assert.False(t, rw.Written(), "fresh writer is not committed")
+		rw.WriteHeader(http.StatusCreated)
+		_, err := rw.Write([]byte("created"))
+		assert.NoError(t, err, "write body")
+
+		assert.True(t, rw.Written(), "response is committed")
+		assert.Equal(t, rw.statusCode, http.StatusCreated, "recorded status")
+		assert.Equal(t, rw.body.String(), "created", "captured body")
+	})
+
+	t.Run("write without header commits 200", func(t *testing.T) {
+		rw := newResponseWriter(httptest.NewRecorder(), false)
+
+		_, err := rw.Write([]byte("ok"))
+		assert.NoError(t, err, "write body")
+
+		assert.True(t, rw.Written(), "response is committed")
+		assert.Equal(t, rw.statusCode, http.StatusOK, "recorded status")
+		assert.Nil(t, rw.body, "body is not captured")
+	})
+
+	t.Run("flush reaches the underlying writer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := newResponseWriter(rec, false)
+
+		err := http.NewResponseController(rw).Flush()
+		assert.NoError(t, err, "flush through response controller")
+
+		assert.True(t, rec.Flushed, "underlying writer flushed")
+		assert.True(t, rw.Written(), "flushing commits the response")
+	})
+
+	t.Run("unsupported hijack is reported", func(t *testing.T) {
+		rw := newResponseWriter(httptest.NewRecorder(), false)
+
+		_, _, err := rw.Hijack()
+		assert.ErrorIs(t, err, http.ErrNotSupported, "hijack on a recorder")
+		assert.False(t, rw.Written(), "failed hijack does not commit the response")
+	})
+}
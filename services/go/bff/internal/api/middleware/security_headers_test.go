@@ -0,0 +1,126 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-services/bff/internal/api/middleware"
+	"go-services/library/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := map[string]struct {
+		want   map[string]string
+		policy middleware.SecurityHeadersPolicy
+	}{
+		"default policy over http": {
+			policy: middleware.DefaultSecurityHeadersPolicy(false),
+			want: map[string]string{
+				"Strict-Transport-Security":  "",
+				"X-Content-Type-Options":     "nosniff",
+				"Referrer-Policy":            "strict-origin-when-cross-origin",
+				"X-Frame-Options":            "DENY",
+				"Content-Security-Policy":    "frame-ancestors 'none'",
+				"Cross-Origin-Opener-Policy": "same-origin",
+				"Cache-Control":              "",
+			},
+		},
+		"default policy over https": {
+			policy: middleware.DefaultSecurityHeadersPolicy(true),
+			want: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+			},
+		},
+		"hsts without subdomains": {
+			policy: middleware.SecurityHeadersPolicy{
+				ReferrerPolicy:          "",
+				FrameOptions:            "",
+				FrameAncestors:          "",
+				CrossOriginOpenerPolicy: "",
+				HSTSMaxAge:              time.Hour,
+				HSTSIncludeSubdomains:   false,
+				NoSniff:                 false,
+				NoStore:                 false,
+			},
+			want: map[string]string{
+				"Strict-Transport-Security": "max-age=3600",
+				"X-Content-Type-Options":    "",
+				"Referrer-Policy":           "",
+				"X-Frame-Options":           "",
+				"Content-Security-Policy":   "",
+			},
+		},
+		"empty policy sets nothing": {
+			policy: middleware.SecurityHeadersPolicy{},
+			want: map[string]string{
+				"Strict-Transport-Security":  "",
+				"X-Content-Type-Options":     "",
+				"Referrer-Policy":            "",
+				"X-Frame-Options":            "",
+				"Content-Security-Policy":    "",
+				"Cross-Origin-Opener-Policy": "",
+				"Cache-Control":              "",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := middleware.SecurityHeaders(tt.policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			for header, want := range tt.want {
+				assert.Equal(t, rec.Header().Get(header), want, "header %s", header)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersOverride(t *testing.T) {
+	inner := middleware.DefaultSecurityHeadersPolicy(false)
+	inner.FrameOptions = "SAMEORIGIN"
+	inner.FrameAncestors = ""
+
+	var handlerSawNoStore bool
+	chain := middleware.NewChain()
+	chain.Add(
+		middleware.SecurityHeaders(middleware.DefaultSecurityHeadersPolicy(false)),
+		middleware.SecurityHeaders(inner),
+		middleware.NoStore(),
+	)
+	handler := chain.Apply(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSawNoStore = w.Header().Get("Cache-Control") == "no-store"
+		w.Header().Set("Referrer-Policy", "no-referrer")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.True(t, handlerSawNoStore, "headers are set before the handler runs")
+	assert.Equal(t, rec.Header().Get("X-Frame-Options"), "SAMEORIGIN", "inner policy overrides outer")
+	assert.Equal(t, rec.Header().Get("Content-Security-Policy"), "frame-ancestors 'none'", "empty field keeps outer header")
+	assert.Equal(t, rec.Header().Get("Cache-Control"), "no-store", "no store")
+	assert.Equal(t, rec.Header().Get("Referrer-Policy"), "no-referrer", "handler overrides middleware")
+	assert.Equal(t, rec.Header().Get("X-Content-Type-Options"), "nosniff", "outer header kept")
+}
+
+func TestSecurityHeadersNotSharedAcrossResponses(t *testing.T) {
+	var seen []string
+	handler := middleware.NoStore()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, w.Header().Get("Cache-Control"))
+		w.Header()["Cache-Control"][0] = "private"
+	}))
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, rec.Header().Get("Cache-Control"), "private", "handler edit applies to its response")
+	}
+
+	assert.Equal(t, seen, []string{"no-store", "no-store"}, "headers seen by the handler")
+}
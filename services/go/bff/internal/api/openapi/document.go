@@ -0,0 +1,100 @@
+// Package openapi describes the BFF's HTTP routes as an OpenAPI 3.1 document.
+//
+// Routes are registered through a Registry, which installs the handler on an
+// http.ServeMux and records the route together with its request and response
+// types. The Go types are reflected into JSON Schema (2020-12, as used by
+// OpenAPI 3.1) following encoding/json conventions and the `validate`,
+// `path` and `query` struct tags understood by api.Handle, so the document
+// stays in sync with what the handlers actually accept and return.
+//
+// Every operation documents the api.SuccessResponse envelope around its
+// response type and a default error response with both the api.ErrorResponse
+// envelope and the RFC 9457 api.ProblemDetails format. apperror.ErrorCode is
+// documented as an enum of apperror.Codes.
+package openapi
+
+// Version is the OpenAPI specification version of generated documents.
+const Version = "3.1.0"
+
+// Document is the root object of an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+	Info       Info                 `json:"info"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the reusable schemas referenced from operations.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem maps the HTTP methods of a single path to their operations.
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+}
+
+// Parameter describes a single path or query parameter.
+type Parameter struct {
+	Schema   *Schema `json:"schema"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+}
+
+// RequestBody describes the body accepted by an operation.
+type RequestBody struct {
+	Content  map[string]*MediaType `json:"content"`
+	Required bool                  `json:"required,omitempty"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Content     map[string]*MediaType `json:"content,omitempty"`
+	Description string                `json:"description"`
+}
+
+// MediaType describes the schema of a request or response body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema 2020-12 produced by the generator.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+}
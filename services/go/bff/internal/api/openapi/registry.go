@@ -0,0 +1,286 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
+)
+
+// Component names of the shared error schemas.
+const (
+	errorResponseSchema  = "ErrorResponse"
+	problemDetailsSchema = "ProblemDetails"
+)
+
+// Route describes an HTTP route registered with a Registry.
+type Route struct {
+	// Request is a value of the request type, or nil if the route takes no
+	// input. Fields tagged `path` or `query` become parameters; the
+	// remaining JSON fields make up the request body.
+	Request any
+	// Response is a value of the type returned in the SuccessResponse data
+	// member, or nil if the route has no JSON response body (for example
+	// redirects).
+	Response any
+	// Method is the HTTP method, e.g. http.MethodGet.
+	Method string
+	// Path is the http.ServeMux path pattern, e.g. "/orders/{id}".
+	Path string
+	// OperationID uniquely identifies the operation. It is derived from the
+	// method and path when empty, e.g. "getOrdersId".
+	OperationID string
+	// Summary is a short description of the operation.
+	Summary string
+	// Tags group operations in generated documentation and clients.
+	Tags []string
+	// Status is the status code of successful responses. It defaults to
+	// http.StatusOK.
+	Status int
+}
+
+// Registry registers routes on an http.ServeMux and describes them in an
+// OpenAPI document.
+type Registry struct {
+	mux    *http.ServeMux
+	info   Info
+	routes []Route
+	mu     sync.Mutex
+}
+
+// NewRegistry returns a Registry that installs handlers on mux.
+func NewRegistry(mux *http.ServeMux, info Info) *Registry {
+	return &Registry{mux: mux, info: info, routes: nil, mu: sync.Mutex{}}
+}
+
+// Handle registers handler for the route's method and path and records the
+// route in the document.
+func (reg *Registry) Handle(route Route, handler http.Handler) {
+	reg.mux.Handle(route.Method+" "+route.Path, handler)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.routes = append(reg.routes, route)
+}
+
+// HandleFunc is like Handle but takes a handler function.
+func (reg *Registry) HandleFunc(route Route, handler http.HandlerFunc) {
+	reg.Handle(route, handler)
+}
+
+// Document builds the OpenAPI document of the registered routes.
+func (reg *Registry) Document() *Document {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	gen := newGenerator()
+	codes := apperror.Codes()
+	values := make([]any, len(codes))
+	for i, code := range codes {
+		values[i] = code
+	}
+	gen.enum(reflect.TypeFor[apperror.ErrorCode](), values)
+	gen.schemaFor(reflect.TypeFor[api.ErrorResponse]())
+	gen.schemaFor(reflect.TypeFor[api.ProblemDetails]())
+
+	doc := &Document{
+		Paths:      make(map[string]*PathItem),
+		Components: Components{Schemas: gen.schemas},
+		OpenAPI:    Version,
+		Info:       reg.info,
+	}
+	for _, route := range reg.routes {
+		path := openAPIPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		setOperation(item, route.Method, operation(gen, route))
+	}
+
+	return doc
+}
+
+// SpecHandler returns a handler that serves the document as JSON.
+func (reg *Registry) SpecHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", api.ContentTypeJSON)
+		if err := json.NewEncoder(w).Encode(reg.Document()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// operation builds the operation object of route.
+func operation(gen *generator, route Route) *Operation {
+	op := &Operation{
+		RequestBody: nil,
+		Responses:   make(map[string]*Response),
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Tags:        route.Tags,
+		Parameters:  nil,
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(route.Method, route.Path)
+	}
+
+	if route.Request != nil {
+		requestType := reflect.TypeOf(route.Request)
+		for requestType.Kind() == reflect.Pointer {
+			requestType = requestType.Elem()
+		}
+		op.Parameters = parameters(gen, requestType)
+		if hasBodyFields(requestType) {
+			op.RequestBody = &RequestBody{
+				Content: map[string]*MediaType{
+					api.ContentTypeJSON: {Schema: gen.schemaFor(requestType)},
+				},
+				Required: true,
+			}
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Content: nil, Description: http.StatusText(status)}
+	if route.Response != nil && status != http.StatusNoContent {
+		success.Content = map[string]*MediaType{
+			api.ContentTypeJSON: {Schema: successEnvelope(gen.schemaFor(reflect.TypeOf(route.Response)))},
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = &Response{
+		Content: map[string]*MediaType{
+			api.ContentTypeJSON:        {Schema: ref(errorResponseSchema)},
+			api.ContentTypeProblemJSON: {Schema: ref(problemDetailsSchema)},
+		},
+		Description: "Error",
+	}
+
+	return op
+}
+
+// successEnvelope returns the schema of api.SuccessResponse carrying data.
+func successEnvelope(data *Schema) *Schema {
+	success := typed("boolean", "")
+	success.Const = true
+
+	schema := typed("object", "")
+	schema.Properties = map[string]*Schema{"success": success, "data": data}
+	schema.Required = []string{"success", "data"}
+	return schema
+}
+
+// parameters returns the path and query parameters declared by the fields of
+// struct type t.
+func parameters(gen *generator, t reflect.Type) []*Parameter {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []*Parameter
+	for i := range t.NumField() {
+		field := t.Field(i)
+		param := &Parameter{Schema: nil, Name: field.Tag.Get("path"), In: "path", Required: true}
+		if param.Name == "" {
+			param.Name, param.In = field.Tag.Get("query"), "query"
+		}
+		if param.Name == "" {
+			continue
+		}
+
+		rules := parseRules(field.Tag.Get("validate"))
+		param.Schema = gen.schemaFor(field.Type)
+		if rules.constrains() {
+			param.Schema = applyRules(param.Schema, rules)
+		}
+		if param.In == "query" {
+			param.Required = rules.required
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// hasBodyFields reports whether struct type t has fields decoded from the
+// JSON body.
+func hasBodyFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Tag.Get("path") != "" || field.Tag.Get("query") != "" {
+			continue
+		}
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name == "-" {
+			continue
+		}
+		if field.IsExported() || field.Anonymous {
+			return true
+		}
+	}
+	return false
+}
+
+// setOperation stores op under method in item.
+func setOperation(item *PathItem, method string, op *Operation) {
+	switch method {
+	case http.MethodGet:
+		item.Get = op
+	case http.MethodPut:
+		item.Put = op
+	case http.MethodPost:
+		item.Post = op
+	case http.MethodDelete:
+		item.Delete = op
+	case http.MethodPatch:
+		item.Patch = op
+	default:
+		panic(fmt.Sprintf("openapi: unsupported method %q", method))
+	}
+}
+
+// openAPIPath converts an http.ServeMux pattern into an OpenAPI path
+// template: "{path...}" wildcards become "{path}" and the "{$}" end anchor is
+// dropped.
+func openAPIPath(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "{$}")
+	return strings.ReplaceAll(pattern, "...}", "}")
+}
+
+// operationID derives an operation id from the method and path, e.g.
+// "GET /orders/{id}" becomes "getOrdersId".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, r := range openAPIPath(path) {
+		switch {
+		case r == '{' || r == '}':
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('/')
+		}
+	}
+
+	words := strings.Split(b.String(), "/")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "")
+}
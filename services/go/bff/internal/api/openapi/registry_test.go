@@ -0,0 +1,130 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/bff/internal/api/openapi"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type createUserRequest struct {
+	Address  *address `json:"address,omitempty"`
+	TenantID string   `json:"-"                  path:"tenant"`
+	Name     string   `json:"name"               validate:"required,min=2,max=64"`
+	Role     string   `json:"role,omitempty"     validate:"oneof=admin member"`
+	DryRun   bool     `json:"-"                  query:"dryRun"`
+	Age      int      `json:"age,omitempty"      validate:"min=18"`
+}
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestRegistryDocument(t *testing.T) {
+	log, _ := testlogger.New()
+	mux := http.NewServeMux()
+	registry := openapi.NewRegistry(mux, openapi.Info{Title: "Test API", Version: "1.0.0", Description: ""})
+	registry.HandleFunc(openapi.Route{
+		Request:     createUserRequest{},
+		Response:    user{},
+		Method:      http.MethodPost,
+		Path:        "/tenants/{tenant}/users",
+		OperationID: "",
+		Summary:     "Create a user",
+		Tags:        []string{"users"},
+		Status:      http.StatusCreated,
+	}, api.Handle(log, func(context.Context, createUserRequest) (user, error) {
+		return user{ID: "1", Name: "ada"}, nil
+	}, api.WithStatus(http.StatusCreated)))
+
+	doc := registry.Document()
+
+	assert.Equal(t, doc.OpenAPI, openapi.Version, "openapi version")
+	item, ok := doc.Paths["/tenants/{tenant}/users"]
+	require.True(t, ok, "path should be documented")
+	op := item.Post
+	require.NotNil(t, op, "POST operation")
+	assert.Equal(t, op.OperationID, "postTenantsTenantUsers", "derived operation id")
+
+	require.SliceLen(t, op.Parameters, 2, "parameters")
+	assert.Equal(t, *op.Parameters[0], openapi.Parameter{
+		Schema: op.Parameters[0].Schema, Name: "tenant", In: "path", Required: true,
+	}, "path parameter")
+	assert.Equal(t, op.Parameters[1].Name, "dryRun", "query parameter name")
+	assert.Equal(t, op.Parameters[1].Schema.Type, "boolean", "query parameter type")
+
+	require.NotNil(t, op.RequestBody, "request body")
+	bodyRef := op.RequestBody.Content[api.ContentTypeJSON].Schema.Ref
+	assert.Equal(t, bodyRef, "#/components/schemas/createUserRequest", "request body schema")
+
+	body := doc.Components.Schemas["createUserRequest"]
+	require.NotNil(t, body, "request schema component")
+	assert.Equal(t, body.Required, []string{"name"}, "required body fields")
+	_, hasTenant := body.Properties["TenantID"]
+	assert.False(t, hasTenant, "path fields are not part of the body")
+	assert.Equal(t, *body.Properties["name"].MinLength, 2, "name min length")
+	assert.Equal(t, *body.Properties["name"].MaxLength, 64, "name max length")
+	assert.Equal(t, body.Properties["role"].Enum, []any{"admin", "member"}, "role enum")
+	assert.Equal(t, *body.Properties["age"].Minimum, 18.0, "age minimum")
+	assert.Equal(t, body.Properties["address"].Ref, "#/components/schemas/address", "nested struct reference")
+
+	created := op.Responses["201"]
+	require.NotNil(t, created, "201 response")
+	envelope := created.Content[api.ContentTypeJSON].Schema
+	assert.Equal(t, envelope.Properties["success"].Const, any(true), "success flag const")
+	assert.Equal(t, envelope.Properties["data"].Ref, "#/components/schemas/user", "response data schema")
+
+	errResponse := op.Responses["default"]
+	require.NotNil(t, errResponse, "default error response")
+	assert.Equal(
+		t,
+		errResponse.Content[api.ContentTypeProblemJSON].Schema.Ref,
+		"#/components/schemas/ProblemDetails",
+		"problem details schema",
+	)
+
+	errorCode := doc.Components.Schemas["ErrorCode"]
+	require.NotNil(t, errorCode, "error code schema")
+	assert.SliceContains(t, errorCode.Enum, any(apperror.CodeNotFound), "error code enum")
+}
+
+func TestRegistrySpecHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	registry := openapi.NewRegistry(mux, openapi.Info{Title: "Test API", Version: "1.0.0", Description: ""})
+	registry.HandleFunc(openapi.Route{
+		Request:     nil,
+		Response:    nil,
+		Method:      http.MethodGet,
+		Path:        "/files/{path...}",
+		OperationID: "getFile",
+		Summary:     "",
+		Tags:        nil,
+		Status:      0,
+	}, func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("GET /openapi.json", registry.SpecHandler())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, rec.Code, http.StatusOK, "status code")
+	assert.Equal(t, rec.Header().Get("Content-Type"), api.ContentTypeJSON, "content type")
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc), "decode document")
+	item, ok := doc.Paths["/files/{path}"]
+	require.True(t, ok, "wildcard path should be converted")
+	assert.NotNil(t, item.Get.Responses["200"], "default success status")
+}
@@ -0,0 +1,313 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// refPrefix is the JSON pointer prefix of component schemas.
+const refPrefix = "#/components/schemas/"
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	durationType   = reflect.TypeFor[time.Duration]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// generator reflects Go types into JSON Schemas. Named struct types and
+// registered enums become component schemas referenced with $ref; every other
+// type is inlined.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	enums   map[reflect.Type][]any
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+		enums:   make(map[reflect.Type][]any),
+	}
+}
+
+// enum documents t as a string enum with the given values.
+func (g *generator) enum(t reflect.Type, values []any) {
+	g.enums[t] = values
+}
+
+// schemaFor returns the schema of t, registering component schemas for the
+// named types it refers to.
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := g.enums[t]; ok {
+		return g.component(t, func() *Schema {
+			schema := typed(jsonType(t), "")
+			schema.Enum = values
+			return schema
+		})
+	}
+
+	switch t {
+	case timeType:
+		return typed("string", "date-time")
+	case durationType:
+		return typed("integer", "int64")
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.component(t, func() *Schema { return g.structSchema(t) })
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return typed("string", "byte")
+		}
+		schema := typed("array", "")
+		schema.Items = g.schemaFor(t.Elem())
+		return schema
+	case reflect.Map:
+		schema := typed("object", "")
+		schema.AdditionalProperties = g.schemaFor(t.Elem())
+		return schema
+	case reflect.Interface:
+		return &Schema{}
+	default:
+		return scalarSchema(t)
+	}
+}
+
+// component registers the schema built by build under the name of t and
+// returns a reference to it. The reference is registered before build runs,
+// so recursive types terminate.
+func (g *generator) component(t reflect.Type, build func() *Schema) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *build()
+	}
+
+	return ref(name)
+}
+
+// typed returns a schema of the given JSON type and format.
+func typed(typ, format string) *Schema {
+	schema := &Schema{}
+	schema.Type = typ
+	schema.Format = format
+	return schema
+}
+
+// ref returns a reference to the component schema called name.
+func ref(name string) *Schema {
+	schema := &Schema{}
+	schema.Ref = refPrefix + name
+	return schema
+}
+
+// componentName picks a unique component name for t. Types sharing a name
+// with an already registered type are prefixed with their package name.
+func (g *generator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	runes := []rune(pkg)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes) + name
+}
+
+// structSchema builds the object schema of struct type t following the
+// encoding/json field naming rules. Fields bound from path or query
+// parameters are not part of the body and are left out.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := typed("object", "")
+	schema.Properties = make(map[string]*Schema)
+	g.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the JSON fields of t to schema, flattening embedded structs
+// the way encoding/json does.
+func (g *generator) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Tag.Get("path") != "" || field.Tag.Get("query") != "" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.addFields(schema, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		rules := parseRules(field.Tag.Get("validate"))
+		property := g.schemaFor(field.Type)
+		if rules.constrains() {
+			property = applyRules(property, rules)
+		}
+		schema.Properties[name] = property
+
+		omitEmpty := strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")
+		if rules.required || (!omitEmpty && field.Type.Kind() != reflect.Pointer) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// scalarSchema returns the schema of a boolean, numeric or string type.
+func scalarSchema(t reflect.Type) *Schema {
+	schema := typed(jsonType(t), "")
+	switch t.Kind() {
+	case reflect.Int32:
+		schema.Format = "int32"
+	case reflect.Int, reflect.Int64:
+		schema.Format = "int64"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		schema.Minimum = &zero
+	case reflect.Float32:
+		schema.Format = "float"
+	case reflect.Float64:
+		schema.Format = "double"
+	default:
+	}
+	return schema
+}
+
+// jsonType returns the JSON Schema type of a scalar Go type.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// rules are the `validate` tag rules that are reflected in schemas.
+type rules struct {
+	min      *float64
+	max      *float64
+	oneOf    []string
+	required bool
+}
+
+// constrains reports whether the rules add constraints to a field schema.
+func (r rules) constrains() bool {
+	return r.min != nil || r.max != nil || len(r.oneOf) > 0
+}
+
+// parseRules parses a `validate` struct tag. The tag has already been checked
+// by api.Validate, so malformed rules are simply ignored here.
+func parseRules(tag string) rules {
+	var parsed rules
+	for rule := range strings.SplitSeq(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch key {
+		case "required":
+			parsed.required = true
+		case "min", "max":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			if key == "min" {
+				parsed.min = &bound
+			} else {
+				parsed.max = &bound
+			}
+		case "oneof":
+			parsed.oneOf = strings.Fields(value)
+		default:
+		}
+	}
+	return parsed
+}
+
+// applyRules returns a copy of schema constrained by rules. min and max bound
+// the length of strings and arrays and the value of numbers, like they do in
+// api.Validate.
+func applyRules(schema *Schema, r rules) *Schema {
+	if schema.Ref != "" {
+		// Siblings of $ref are allowed in JSON Schema 2020-12.
+		schema = ref(strings.TrimPrefix(schema.Ref, refPrefix))
+	} else {
+		copied := *schema
+		schema = &copied
+	}
+
+	toInt := func(bound *float64) *int {
+		if bound == nil {
+			return nil
+		}
+		n := int(*bound)
+		return &n
+	}
+
+	switch schema.Type {
+	case "string":
+		schema.MinLength, schema.MaxLength = toInt(r.min), toInt(r.max)
+	case "array":
+		schema.MinItems, schema.MaxItems = toInt(r.min), toInt(r.max)
+	case "integer", "number":
+		if r.min != nil {
+			schema.Minimum = r.min
+		}
+		schema.Maximum = r.max
+	default:
+	}
+
+	for _, value := range r.oneOf {
+		if schema.Type == "integer" {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				schema.Enum = append(schema.Enum, n)
+				continue
+			}
+		}
+		schema.Enum = append(schema.Enum, value)
+	}
+
+	return schema
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-services/library/apperror"
+)
+
+// ErrorFormat selects how error responses are serialized.
+type ErrorFormat string
+
+const (
+	// ErrorFormatEnvelope writes the {"success":false,"error":{...}} envelope
+	// described by ErrorResponse.
+	ErrorFormatEnvelope ErrorFormat = "envelope"
+	// ErrorFormatProblem writes RFC 9457 Problem Details documents with the
+	// application/problem+json media type.
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+// ParseErrorFormat converts a configuration value into an ErrorFormat.
+func ParseErrorFormat(value string) (ErrorFormat, error) {
+	switch format := ErrorFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case ErrorFormatEnvelope, ErrorFormatProblem:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported error format %q", value)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseErrorFormat,
+// so an ErrorFormat can be loaded from configuration.
+func (f *ErrorFormat) UnmarshalText(text []byte) error {
+	format, err := ParseErrorFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// errorFormatKey is the context key under which the default format is stored.
+type errorFormatKey struct{}
+
+// WithErrorFormat returns a copy of ctx carrying the server's default error
+// format.
+func WithErrorFormat(ctx context.Context, format ErrorFormat) context.Context {
+	return context.WithValue(ctx, errorFormatKey{}, format)
+}
+
+// ErrorFormatFromContext returns the default error format stored in ctx, or
+// ErrorFormatEnvelope if none was configured.
+func ErrorFormatFromContext(ctx context.Context) ErrorFormat {
+	if format, ok := ctx.Value(errorFormatKey{}).(ErrorFormat); ok {
+		return format
+	}
+	return ErrorFormatEnvelope
+}
+
+// NegotiateErrorFormat picks the error format for a request.
+//
+// A client that lists application/problem+json in its Accept header with a
+// q-value at least as high as application/json receives Problem Details, and
+// one that prefers application/json receives the envelope. Every other
+// request falls back to the format configured for the server.
+func NegotiateErrorFormat(r *http.Request) ErrorFormat {
+	problemQ, jsonQ := -1.0, -1.0
+	for _, mr := range parseAccept(r.Header.Values("Accept")) {
+		switch mr.mediaType {
+		case ContentTypeProblemJSON:
+			problemQ = max(problemQ, mr.quality)
+		case ContentTypeJSON:
+			jsonQ = max(jsonQ, mr.quality)
+		}
+	}
+	if problemQ > 0 && problemQ >= jsonQ {
+		return ErrorFormatProblem
+	}
+	if jsonQ > 0 && jsonQ > problemQ {
+		return ErrorFormatEnvelope
+	}
+
+	return ErrorFormatFromContext(r.Context())
+}
+
+// ProblemDetails is an RFC 9457 Problem Details document.
+//
+// Besides the standard members it carries extension members: the
+// apperror.ErrorCode of the failure and the id of the request, so clients can
+// branch on stable codes and operators can correlate reports with logs, and
+// the field violations and metadata of the underlying error, if any.
+type ProblemDetails struct {
+	// Metadata holds additional details about the error.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// Type is a URI reference identifying the problem type. "about:blank"
+	// means the problem has no semantics beyond the HTTP status code.
+	Type string `json:"type"`
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title"`
+	// Detail is a human-readable explanation specific to this occurrence.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence.
+	Instance string `json:"instance,omitempty"`
+	// Code is the application error code.
+	Code apperror.ErrorCode `json:"code"`
+	// RequestID is the id assigned by the RequestID middleware.
+	RequestID string `json:"requestId,omitempty"`
+	// Violations lists the invalid fields of a validation error.
+	Violations []apperror.FieldViolation `json:"violations,omitempty"`
+	// Status is the HTTP status code generated by the origin server.
+	Status int `json:"status"`
+}
+
+// NewProblemDetails builds the Problem Details document for an error response
+// to r.
+func NewProblemDetails(r *http.Request, status int, body ErrorResponseBody) ProblemDetails {
+	return ProblemDetails{
+		Metadata:   body.Metadata,
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Detail:     body.Message,
+		Instance:   r.URL.Path,
+		Code:       body.Code,
+		RequestID:  RequestIDFromContext(r.Context()),
+		Violations: body.Violations,
+		Status:     status,
+	}
+}
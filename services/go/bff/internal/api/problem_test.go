@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+func TestParseErrorFormat(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    api.ErrorFormat
+		wantErr bool
+	}{
+		"envelope":             {value: "envelope", want: api.ErrorFormatEnvelope, wantErr: false},
+		"problem":              {value: "problem", want: api.ErrorFormatProblem, wantErr: false},
+		"mixed case and space": {value: " Problem ", want: api.ErrorFormatProblem, wantErr: false},
+		"unknown":              {value: "xml", want: "", wantErr: true},
+		"empty":                {value: "", want: "", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := api.ParseErrorFormat(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err, "ParseErrorFormat(%q)", tt.value)
+				return
+			}
+			assert.NoError(t, err, "ParseErrorFormat(%q)", tt.value)
+			assert.Equal(t, got, tt.want, "ParseErrorFormat(%q)", tt.value)
+		})
+	}
+}
+
+func TestNegotiateErrorFormat(t *testing.T) {
+	tests := map[string]struct {
+		accept        string
+		defaultFormat api.ErrorFormat
+		want          api.ErrorFormat
+	}{
+		"no accept uses envelope by default": {
+			accept:        "",
+			defaultFormat: "",
+			want:          api.ErrorFormatEnvelope,
+		},
+		"no accept uses configured default": {
+			accept:        "",
+			defaultFormat: api.ErrorFormatProblem,
+			want:          api.ErrorFormatProblem,
+		},
+		"problem json requested": {
+			accept:        "application/problem+json",
+			defaultFormat: api.ErrorFormatEnvelope,
+			want:          api.ErrorFormatProblem,
+		},
+		"problem json preferred over json": {
+			accept:        "application/json;q=0.5, application/problem+json",
+			defaultFormat: api.ErrorFormatEnvelope,
+			want:          api.ErrorFormatProblem,
+		},
+		"json preferred over problem json": {
+			accept:        "application/json, application/problem+json;q=0.5",
+			defaultFormat: api.ErrorFormatEnvelope,
+			want:          api.ErrorFormatEnvelope,
+		},
+		"problem json refused": {
+			accept:        "application/problem+json;q=0",
+			defaultFormat: api.ErrorFormatEnvelope,
+			want:          api.ErrorFormatEnvelope,
+		},
+		"plain json overrides configured default": {
+			accept:        "application/json",
+			defaultFormat: api.ErrorFormatProblem,
+			want:          api.ErrorFormatEnvelope,
+		},
+		"json preferred over configured default": {
+			accept:        "application/json, application/problem+json;q=0.5",
+			defaultFormat: api.ErrorFormatProblem,
+			want:          api.ErrorFormatEnvelope,
+		},
+		"json refused keeps configured default": {
+			accept:        "application/json;q=0",
+			defaultFormat: api.ErrorFormatProblem,
+			want:          api.ErrorFormatProblem,
+		},
+		"wildcard keeps configured default": {
+			accept:        "*/*",
+			defaultFormat: api.ErrorFormatProblem,
+			want:          api.ErrorFormatProblem,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.defaultFormat != "" {
+				req = req.WithContext(api.WithErrorFormat(req.Context(), tt.defaultFormat))
+			}
+
+			assert.Equal(t, api.NegotiateErrorFormat(req), tt.want, "negotiated format")
+		})
+	}
+}
+
+func TestSendErrorLogProblem(t *testing.T) {
+	log, _ := testlogger.New()
+	ctx := api.WithRequestID(context.Background(), "req-123")
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/auth/login?next=/", nil)
+	req.Header.Set("Accept", api.ContentTypeProblemJSON)
+	rec := httptest.NewRecorder()
+
+	api.SendErrorLog(req, log, rec, http.StatusNotFound, apperror.CodeNotFound, "session not found")
+
+	assert.Equal(t, rec.Code, http.StatusNotFound, "status code")
+	assert.Equal(t, rec.Header().Get("Content-Type"), api.ContentTypeProblemJSON, "content type")
+
+	var got api.ProblemDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), "decode problem details")
+	assert.Equal(t, got, api.ProblemDetails{
+		Type:      "about:blank",
+		Title:     "Not Found",
+		Detail:    "session not found",
+		Instance:  "/auth/login",
+		Code:      apperror.CodeNotFound,
+		RequestID: "req-123",
+		Status:    http.StatusNotFound,
+	}, "problem details")
+}
+
+func TestSendErrorLogEnvelope(t *testing.T) {
+	log, _ := testlogger.New()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	rec := httptest.NewRecorder()
+
+	api.SendErrorLog(req, log, rec, http.StatusNotFound, apperror.CodeNotFound, "session not found")
+
+	assert.Equal(t, rec.Header().Get("Content-Type"), api.ContentTypeJSON, "content type")
+
+	var got api.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got), "decode error envelope")
+	assert.Equal(t, got.Error.Code, apperror.CodeNotFound, "error code")
+	assert.Equal(t, got.Error.Message, "session not found", "error message")
+}
@@ -0,0 +1,18 @@
+package api
+
+import "context"
+
+// requestIDKey is the context key under which the request id is stored.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the given request id.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request id stored by the RequestID
+// middleware, or an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
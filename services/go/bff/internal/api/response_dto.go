@@ -13,6 +13,19 @@ type ErrorResponse struct {
 }
 
 type ErrorResponseBody struct {
-	Code    apperror.ErrorCode `json:"code"`
-	Message string             `json:"message"`
+	Metadata   map[string]any            `json:"metadata,omitempty"`
+	Code       apperror.ErrorCode        `json:"code"`
+	Message    string                    `json:"message"`
+	Violations []apperror.FieldViolation `json:"violations,omitempty"`
+}
+
+// NewErrorResponseBody builds the error body for an application error,
+// including its field violations and metadata.
+func NewErrorResponseBody(appErr *apperror.AppError) ErrorResponseBody {
+	return ErrorResponseBody{
+		Metadata:   appErr.Metadata,
+		Code:       appErr.Code,
+		Message:    appErr.Msg,
+		Violations: appErr.Violations,
+	}
 }
@@ -0,0 +1,232 @@
+// Package router organizes the BFF's routes into groups with their own
+// middleware stacks on top of http.ServeMux.
+//
+// A Router holds routes, middleware and child routers. Children are created
+// with Group or attached with Mount; they inherit the path prefix and the
+// middleware of their parents. Nothing is installed until Register, which
+// registers every route on an openapi.Registry (and through it on the
+// underlying http.ServeMux) with its effective middleware stack.
+//
+// Ordering guarantees:
+//
+//   - Middleware of a parent always runs before (outside) the middleware of
+//     its children.
+//   - Within a router, middleware runs in the order it was added with Use,
+//     regardless of whether Use was called before or after the routes were
+//     declared.
+//   - Handlers registered with HandleError are wrapped in middleware.Error
+//     innermost, so every other middleware sees the final response.
+//
+// Example:
+//
+//	rt := router.New(log)
+//	rt.Group("/auth", func(auth *router.Router) {
+//		auth.HandleError(openapi.Route{Method: http.MethodGet, Path: "/login", ...}, h.Login)
+//	})
+//	rt.Group("/api", func(api *router.Router) {
+//		api.Use(router.Named("requireJSON", middleware.RequireJSON(log)))
+//		api.Handle(openapi.Route{Method: http.MethodGet, Path: "/orders/{id}", ...}, getOrder)
+//	})
+//	rt.Register(registry)
+package router
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-services/bff/internal/api/middleware"
+	"go-services/bff/internal/api/openapi"
+)
+
+// errorMiddlewareName is the name listed for the middleware.Error wrapper
+// applied by HandleError.
+const errorMiddlewareName = "error"
+
+// Middleware is an HTTP middleware with a name used in route listings.
+type Middleware struct {
+	Wrap func(http.Handler) http.Handler
+	Name string
+}
+
+// Named returns wrap as a Middleware called name.
+func Named(name string, wrap func(http.Handler) http.Handler) Middleware {
+	return Middleware{Wrap: wrap, Name: name}
+}
+
+// RouteInfo describes a registered route and its effective middleware.
+type RouteInfo struct {
+	// Method is the HTTP method of the route.
+	Method string
+	// Path is the full path pattern, including the prefixes of all groups.
+	Path string
+	// Middlewares lists the names of the middleware wrapping the handler,
+	// from the outermost to the innermost.
+	Middlewares []string
+}
+
+// route is a route declared on a Router.
+type route struct {
+	handler http.Handler
+	spec    openapi.Route
+	// errorHandled is set for routes declared with HandleError, whose
+	// handler is already wrapped in middleware.Error.
+	errorHandled bool
+}
+
+// Router is a group of routes sharing a path prefix and middleware stack.
+type Router struct {
+	log         *slog.Logger
+	parent      *Router
+	prefix      string
+	middlewares []Middleware
+	routes      []route
+	children    []*Router
+}
+
+// New returns an empty root Router. log is used by the middleware.Error
+// wrapper of HandleError routes and to log registered routes.
+func New(log *slog.Logger) *Router {
+	return &Router{
+		log:         log,
+		parent:      nil,
+		prefix:      "",
+		middlewares: nil,
+		routes:      nil,
+		children:    nil,
+	}
+}
+
+// Use appends middleware to the stack of the router. It applies to every
+// route of the router and of its children.
+func (rt *Router) Use(mws ...Middleware) {
+	rt.middlewares = append(rt.middlewares, mws...)
+}
+
+// Group creates a child router for routes under prefix and passes it to fn.
+// The child inherits the middleware of rt and can add its own.
+func (rt *Router) Group(prefix string, fn func(group *Router)) *Router {
+	group := New(rt.log)
+	group.prefix = prefix
+	rt.attach(group)
+	fn(group)
+	return group
+}
+
+// Mount attaches a separately built router under prefix. The routes of sub
+// are registered below prefix and run inside the middleware of rt. A router
+// can only be mounted once.
+func (rt *Router) Mount(prefix string, sub *Router) {
+	if sub.parent != nil {
+		panic("router: router is already mounted")
+	}
+	sub.prefix = joinPath(prefix, sub.prefix)
+	rt.attach(sub)
+}
+
+// attach adds child as a child router of rt.
+func (rt *Router) attach(child *Router) {
+	child.parent = rt
+	rt.children = append(rt.children, child)
+}
+
+// Handle declares a route served by handler. The route's Path is relative to
+// the prefix of the router.
+func (rt *Router) Handle(spec openapi.Route, handler http.Handler) {
+	rt.routes = append(rt.routes, route{handler: handler, spec: spec, errorHandled: false})
+}
+
+// HandleFunc is like Handle but takes a handler function.
+func (rt *Router) HandleFunc(spec openapi.Route, handler http.HandlerFunc) {
+	rt.Handle(spec, handler)
+}
+
+// HandleError declares a route served by a handler that returns an error.
+// Errors are turned into responses by middleware.Error.
+func (rt *Router) HandleError(spec openapi.Route, handler func(w http.ResponseWriter, r *http.Request) error) {
+	rt.routes = append(rt.routes, route{
+		handler:      middleware.Error(rt.log)(handler),
+		spec:         spec,
+		errorHandled: true,
+	})
+}
+
+// Routes lists the routes of rt and its children with their full path and
+// effective middleware, in declaration order.
+func (rt *Router) Routes() []RouteInfo {
+	var infos []RouteInfo
+	rt.walk(func(r route, path string, stack []Middleware) {
+		infos = append(infos, RouteInfo{Method: r.spec.Method, Path: path, Middlewares: middlewareNames(r, stack)})
+	})
+	return infos
+}
+
+// middlewareNames lists the names of the middleware wrapping r, including
+// the middleware.Error wrapper of HandleError routes.
+func middlewareNames(r route, stack []Middleware) []string {
+	names := make([]string, 0, len(stack)+1)
+	for _, mw := range stack {
+		names = append(names, mw.Name)
+	}
+	if r.errorHandled {
+		names = append(names, errorMiddlewareName)
+	}
+	return names
+}
+
+// Register installs every route of rt and its children on registry, wrapped
+// in its effective middleware stack. It must be called on the root router
+// after all routes are declared.
+func (rt *Router) Register(registry *openapi.Registry) {
+	if rt.parent != nil {
+		panic("router: Register must be called on the root router")
+	}
+
+	rt.walk(func(r route, path string, stack []Middleware) {
+		chain := middleware.NewChain()
+		for _, mw := range stack {
+			chain.Add(mw.Wrap)
+		}
+
+		spec := r.spec
+		spec.Path = path
+		registry.Handle(spec, chain.Apply(r.handler))
+		rt.log.Debug(
+			"route registered",
+			"method", spec.Method,
+			"path", path,
+			"middlewares", middlewareNames(r, stack),
+		)
+	})
+}
+
+// walk calls fn for every route of rt and its children with the route's full
+// path and effective middleware stack.
+func (rt *Router) walk(fn func(r route, path string, stack []Middleware)) {
+	rt.walkPrefixed("", nil, fn)
+}
+
+func (rt *Router) walkPrefixed(prefix string, stack []Middleware, fn func(route, string, []Middleware)) {
+	prefix = joinPath(prefix, rt.prefix)
+	stack = append(stack[:len(stack):len(stack)], rt.middlewares...)
+
+	for _, r := range rt.routes {
+		fn(r, joinPath(prefix, r.spec.Path), stack)
+	}
+	for _, child := range rt.children {
+		child.walkPrefixed(prefix, stack, fn)
+	}
+}
+
+// joinPath joins two path pattern segments with exactly one slash.
+func joinPath(prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	default:
+		return fmt.Sprintf("%s/%s", strings.TrimSuffix(prefix, "/"), strings.TrimPrefix(path, "/"))
+	}
+}
@@ -0,0 +1,122 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-services/bff/internal/api/openapi"
+	"go-services/bff/internal/api/router"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/testlogger"
+)
+
+// tracing returns a middleware that appends its name to the X-Trace
+// response header before calling the next handler.
+func tracing(name string) router.Middleware {
+	return router.Named(name, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+func get(path string) openapi.Route {
+	return openapi.Route{
+		Request:     nil,
+		Response:    nil,
+		Method:      http.MethodGet,
+		Path:        path,
+		OperationID: "",
+		Summary:     "",
+		Tags:        nil,
+		Status:      0,
+	}
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func newTestRouter(t *testing.T) (*router.Router, *http.ServeMux) {
+	t.Helper()
+	log, _ := testlogger.New()
+
+	rt := router.New(log)
+	rt.Group("/api", func(api *router.Router) {
+		api.HandleFunc(get("/orders"), ok)
+		// Use after the route is declared still applies to it.
+		api.Use(tracing("api"))
+		api.Group("/admin", func(admin *router.Router) {
+			admin.Use(tracing("admin"))
+			admin.HandleError(get("/fail"), func(w http.ResponseWriter, r *http.Request) error {
+				return apperror.New(apperror.CodeForbidden, "admins only")
+			})
+		})
+	})
+	rt.Use(tracing("root"))
+
+	users := router.New(log)
+	users.Use(tracing("users"))
+	users.HandleFunc(get("/{id}"), ok)
+	rt.Mount("/users", users)
+
+	mux := http.NewServeMux()
+	rt.Register(openapi.NewRegistry(mux, openapi.Info{Title: "test", Version: "1", Description: ""}))
+	return rt, mux
+}
+
+func TestRouterRoutes(t *testing.T) {
+	rt, _ := newTestRouter(t)
+
+	assert.Equal(t, rt.Routes(), []router.RouteInfo{
+		{Method: http.MethodGet, Path: "/api/orders", Middlewares: []string{"root", "api"}},
+		{Method: http.MethodGet, Path: "/api/admin/fail", Middlewares: []string{"root", "api", "admin", "error"}},
+		{Method: http.MethodGet, Path: "/users/{id}", Middlewares: []string{"root", "users"}},
+	}, "route listing")
+}
+
+func TestRouterServe(t *testing.T) {
+	_, mux := newTestRouter(t)
+
+	tests := map[string]struct {
+		path       string
+		wantTrace  string
+		wantStatus int
+	}{
+		"group route":           {path: "/api/orders", wantTrace: "root,api", wantStatus: http.StatusOK},
+		"nested group error":    {path: "/api/admin/fail", wantTrace: "root,api,admin", wantStatus: http.StatusForbidden},
+		"mounted router":        {path: "/users/42", wantTrace: "root,users", wantStatus: http.StatusOK},
+		"unknown route":         {path: "/missing", wantTrace: "", wantStatus: http.StatusNotFound},
+		"prefix is not a route": {path: "/api", wantTrace: "", wantStatus: http.StatusNotFound},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, rec.Code, tt.wantStatus, "status code")
+			assert.Equal(t, strings.Join(rec.Header().Values("X-Trace"), ","), tt.wantTrace, "middleware order")
+		})
+	}
+}
+
+func TestRouterMisuse(t *testing.T) {
+	log, _ := testlogger.New()
+	registry := openapi.NewRegistry(http.NewServeMux(), openapi.Info{Title: "test", Version: "1", Description: ""})
+
+	t.Run("mount twice", func(t *testing.T) {
+		sub := router.New(log)
+		router.New(log).Mount("/a", sub)
+		assert.Panics(t, func() { router.New(log).Mount("/b", sub) }, "mounting a router twice")
+	})
+
+	t.Run("register child", func(t *testing.T) {
+		group := router.New(log).Group("/a", func(*router.Router) {})
+		assert.Panics(t, func() { group.Register(registry) }, "registering a child router")
+	})
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"go-services/library/apperror"
+)
+
+// Validate checks v against the rules declared in its `validate` struct tags
+// and returns an apperror.AppError listing every violation, or nil if v is
+// valid.
+//
+// Rules are comma separated:
+//
+//   - required: the field must not be the zero value (nil, "", 0, empty
+//     slice or map).
+//   - min=N / max=N: bounds the length of strings (in characters), slices and
+//     maps, or the value of numbers.
+//   - oneof=a b c: the string or integer value must be one of the listed
+//     space separated values.
+//
+// Nested structs, pointers to structs and slices of structs are validated
+// recursively. Violations are reported with the JSON path of the field, e.g.
+// "items[1].quantity", so clients can map them back onto their payload. Empty
+// optional fields are only checked against required.
+//
+// Example:
+//
+//	type CreateOrderRequest struct {
+//		Customer string      `json:"customer" validate:"required,max=64"`
+//		Priority string      `json:"priority" validate:"oneof=low normal high"`
+//		Items    []OrderItem `json:"items"    validate:"required,min=1"`
+//	}
+//
+// A malformed tag is a programming error and is returned as a plain error,
+// which the Error middleware turns into a 500 response.
+func Validate(v any) error {
+	var violations []apperror.FieldViolation
+	if err := validateValue(reflect.ValueOf(v), "", &violations); err != nil {
+		return err
+	}
+
+	return apperror.NewValidation(violations...)
+}
+
+// validateInto is like Validate but appends the violations of v to
+// violations, so callers can merge them with violations found elsewhere.
+func validateInto(v any, violations *[]apperror.FieldViolation) error {
+	return validateValue(reflect.ValueOf(v), "", violations)
+}
+
+// fieldRules are the parsed rules of a single struct field.
+type fieldRules struct {
+	min      *float64
+	max      *float64
+	name     string
+	oneOf    []string
+	index    int
+	required bool
+}
+
+// ruleCache maps a struct type to its parsed []fieldRules.
+var ruleCache sync.Map
+
+// rulesFor returns the parsed rules of the exported fields of struct type t.
+func rulesFor(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := ruleCache.Load(t); ok {
+		rules, _ := cached.([]fieldRules)
+		return rules, nil
+	}
+
+	rules := make([]fieldRules, 0, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		rule, err := parseRules(field.Tag.Get("validate"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid validate tag on %s.%s: %w", t.Name(), field.Name, err)
+		}
+		rule.name = name
+		rule.index = i
+		rules = append(rules, rule)
+	}
+
+	ruleCache.Store(t, rules)
+	return rules, nil
+}
+
+// fieldName returns the name a field is reported under: its path or query
+// parameter name for fields bound by Handle, otherwise the name it is encoded
+// under by encoding/json.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{pathTag, queryTag} {
+		if name := field.Tag.Get(tag); name != "" {
+			return name
+		}
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// parseRules parses the value of a `validate` struct tag.
+func parseRules(tag string) (fieldRules, error) {
+	var rules fieldRules
+	if tag == "" {
+		return rules, nil
+	}
+
+	for rule := range strings.SplitSeq(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch key {
+		case "required":
+			rules.required = true
+		case "min", "max":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return rules, fmt.Errorf("rule %q: %w", rule, err)
+			}
+			if key == "min" {
+				rules.min = &bound
+			} else {
+				rules.max = &bound
+			}
+		case "oneof":
+			rules.oneOf = strings.Fields(value)
+			if len(rules.oneOf) == 0 {
+				return rules, fmt.Errorf("rule %q: no values", rule)
+			}
+		default:
+			return rules, fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// validateValue validates the fields of v if it is a struct, or the elements
+// of v if it is a slice, appending violations under the given path.
+func validateValue(v reflect.Value, path string, violations *[]apperror.FieldViolation) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		rules, err := rulesFor(v.Type())
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			fieldPath := rule.name
+			if path != "" {
+				fieldPath = path + "." + rule.name
+			}
+			field := v.Field(rule.index)
+			if violation, ok := checkField(field, fieldPath, rule); ok {
+				*violations = append(*violations, violation)
+				continue
+			}
+			if err := validateValue(field, fieldPath, violations); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), violations); err != nil {
+				return err
+			}
+		}
+	default:
+	}
+
+	return nil
+}
+
+// checkField applies rule to a single field and returns the first violation.
+func checkField(field reflect.Value, path string, rule fieldRules) (apperror.FieldViolation, bool) {
+	violation := func(code apperror.ErrorCode, format string, args ...any) (apperror.FieldViolation, bool) {
+		return apperror.FieldViolation{Field: path, Message: fmt.Sprintf(format, args...), Code: code}, true
+	}
+
+	if field.IsZero() {
+		if rule.required {
+			return violation(apperror.CodeMissingField, "is required")
+		}
+		return apperror.FieldViolation{}, false
+	}
+	for field.Kind() == reflect.Pointer {
+		field = field.Elem()
+	}
+
+	if size, unit, ok := measure(field); ok {
+		if rule.min != nil && size < *rule.min {
+			return violation(apperror.CodeOutOfRange, "must be at least %s%s", formatBound(*rule.min), unit)
+		}
+		if rule.max != nil && size > *rule.max {
+			return violation(apperror.CodeOutOfRange, "must be at most %s%s", formatBound(*rule.max), unit)
+		}
+	}
+
+	if len(rule.oneOf) > 0 {
+		value, ok := scalarString(field)
+		if ok && !slices.Contains(rule.oneOf, value) {
+			return violation(apperror.CodeInvalidInput, "must be one of: %s", strings.Join(rule.oneOf, ", "))
+		}
+	}
+
+	return apperror.FieldViolation{}, false
+}
+
+// measure returns the quantity min and max are compared against, and the
+// unit used in violation messages.
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	default:
+		return 0, "", false
+	}
+}
+
+// scalarString formats string and integer values for oneof comparisons.
+func scalarString(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	default:
+		return "", false
+	}
+}
+
+// formatBound prints a rule bound without a trailing ".0".
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+type orderItem struct {
+	SKU      string `json:"sku"      validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1,max=100"`
+}
+
+type createOrderRequest struct {
+	Note     *string     `json:"note"     validate:"max=5"`
+	Customer string      `json:"customer" validate:"required,min=2,max=8"`
+	Priority string      `json:"priority" validate:"oneof=low normal high"`
+	Internal string      `json:"-"        validate:"required"`
+	Items    []orderItem `json:"items"    validate:"required,min=1"`
+}
+
+func TestValidate(t *testing.T) {
+	longNote := "too long"
+
+	tests := map[string]struct {
+		request createOrderRequest
+		want    []apperror.FieldViolation
+	}{
+		"valid request": {
+			request: createOrderRequest{
+				Note:     nil,
+				Customer: "ada",
+				Priority: "high",
+				Items:    []orderItem{{SKU: "sku-1", Quantity: 2}},
+				Internal: "",
+			},
+			want: nil,
+		},
+		"every field invalid": {
+			request: createOrderRequest{
+				Note:     &longNote,
+				Customer: "",
+				Priority: "urgent",
+				Items:    nil,
+				Internal: "",
+			},
+			want: []apperror.FieldViolation{
+				{Field: "note", Message: "must be at most 5 characters", Code: apperror.CodeOutOfRange},
+				{Field: "customer", Message: "is required", Code: apperror.CodeMissingField},
+				{Field: "priority", Message: "must be one of: low, normal, high", Code: apperror.CodeInvalidInput},
+				{Field: "items", Message: "is required", Code: apperror.CodeMissingField},
+			},
+		},
+		"string length is counted in characters": {
+			request: createOrderRequest{
+				Note:     nil,
+				Customer: "ééééééééé",
+				Priority: "",
+				Items:    []orderItem{{SKU: "sku-1", Quantity: 1}},
+				Internal: "",
+			},
+			want: []apperror.FieldViolation{
+				{Field: "customer", Message: "must be at most 8 characters", Code: apperror.CodeOutOfRange},
+			},
+		},
+		"nested items report their path": {
+			request: createOrderRequest{
+				Note:     nil,
+				Customer: "ada",
+				Priority: "low",
+				Items: []orderItem{
+					{SKU: "sku-1", Quantity: 1},
+					{SKU: "", Quantity: 101},
+				},
+				Internal: "",
+			},
+			want: []apperror.FieldViolation{
+				{Field: "items[1].sku", Message: "is required", Code: apperror.CodeMissingField},
+				{Field: "items[1].quantity", Message: "must be at most 100", Code: apperror.CodeOutOfRange},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := api.Validate(&tt.request)
+			if tt.want == nil {
+				assert.NoError(t, err, "Validate")
+				return
+			}
+
+			appErr, ok := apperror.As(err)
+			require.True(t, ok, "Validate should return an *AppError, got %v", err)
+			assert.Equal(t, appErr.Code, apperror.CodeInvalidInput, "error code")
+			assert.Equal(t, appErr.Violations, tt.want, "violations")
+		})
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	type request struct {
+		Name string `json:"name" validate:"required,pattern=^a"`
+	}
+
+	err := api.Validate(request{Name: "a"})
+
+	assert.ErrorContains(t, err, `unknown rule "pattern=^a"`, "invalid tag error")
+	_, ok := apperror.As(err)
+	assert.False(t, ok, "invalid tags are programming errors, not client errors")
+}
@@ -7,22 +7,35 @@ import (
 	"os"
 
 	"go-services/bff/internal/config"
+	libconfig "go-services/library/config"
 )
 
 type App struct {
 	Log     *slog.Logger
 	Config  *config.Config
 	Handler *Handler
+	service *service
 }
 
-func New(ctx context.Context) (*App, error) {
+func New(ctx context.Context, args []string) (*App, error) {
 	log := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	cfg, err := config.New()
+	cfg, err := config.New(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize config: %w", err)
 	}
+	log.InfoContext(ctx, "configuration loaded", "config", libconfig.LogValue(cfg))
 
-	service, err := newService(ctx, log, cfg)
+	// Sessions are only stored for CSRF protection, so NATS is not needed
+	// without it.
+	var repository *repository
+	if cfg.CSRFProtection {
+		repository, err = newRepository(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize repositories: %w", err)
+		}
+	}
+
+	service, err := newService(ctx, log, cfg, repository)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize services: %w", err)
 	}
@@ -33,5 +46,6 @@ func New(ctx context.Context) (*App, error) {
 		Log:     log,
 		Config:  cfg,
 		Handler: handler,
+		service: service,
 	}, nil
 }
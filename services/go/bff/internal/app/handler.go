@@ -5,10 +5,13 @@ import (
 
 	"go-services/bff/internal/auth"
 	"go-services/bff/internal/config"
+	"go-services/bff/internal/session"
 )
 
 type Handler struct {
-	AuthCommandHandler *auth.AuthCommandHandler
+	AuthCommandHandler    *auth.AuthCommandHandler
+	SessionQueryHandler   *session.SessionQueryHandler
+	SessionCommandHandler *session.SessionCommandHandler
 }
 
 func newHandler(log *slog.Logger, cfg *config.Config, service *service) *Handler {
@@ -20,7 +23,25 @@ func newHandler(log *slog.Logger, cfg *config.Config, service *service) *Handler
 		service.AuthCommandService,
 	)
 
+	var sessionQueryHandler *session.SessionQueryHandler
+	if service.SessionQueryService != nil {
+		sessionQueryHandler = session.NewSessionQueryHandler(
+			service.SessionQueryService,
+			cfg.UseHTTPS,
+		)
+	}
+
+	var sessionCommandHandler *session.SessionCommandHandler
+	if service.SessionCommandService != nil {
+		sessionCommandHandler = session.NewSessionCommandHandler(
+			service.SessionCommandService,
+			cfg.UseHTTPS,
+		)
+	}
+
 	return &Handler{
-		AuthCommandHandler: authCommandHandler,
+		AuthCommandHandler:    authCommandHandler,
+		SessionQueryHandler:   sessionQueryHandler,
+		SessionCommandHandler: sessionCommandHandler,
 	}
 }
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"go-services/bff/internal/config"
+	"go-services/bff/internal/session"
+)
+
+type repository struct {
+	NatsKVSessionQueryRepository   *session.NatsKVSessionQueryRepository
+	NatsKVSessionCommandRepository *session.NatsKVSessionCommandRepository
+}
+
+func newRepository(ctx context.Context, cfg *config.Config) (*repository, error) {
+	nc, err := nats.Connect(cfg.NatsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	natsKVSessionQueryRepository, err := session.NewNatsKVSessionQueryRepository(
+		ctx,
+		js,
+		cfg.NatsKVSessionBucketName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session query repository: %w", err)
+	}
+	natsKVSessionCommandRepository, err := session.NewNatsKVSessionCommandRepository(
+		ctx,
+		js,
+		cfg.NatsKVSessionBucketName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session command repository: %w", err)
+	}
+
+	return &repository{
+		NatsKVSessionQueryRepository:   natsKVSessionQueryRepository,
+		NatsKVSessionCommandRepository: natsKVSessionCommandRepository,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-services/bff/internal/api/middleware"
+	"go-services/bff/internal/api/openapi"
+	"go-services/bff/internal/api/router"
+	"go-services/bff/internal/session"
+	"go-services/library/apperror"
+)
+
+// apiInfo is the metadata of the BFF's OpenAPI document.
+var apiInfo = openapi.Info{
+	Title:       "BFF API",
+	Version:     "1.0.0",
+	Description: "Backend for frontend of the web application.",
+}
+
+// loginParams documents the query parameters of GET /auth/login.
+type loginParams struct {
+	ReturnTo string `json:"-" query:"return_to"`
+}
+
+// callbackParams documents the query parameters of GET /auth/callback.
+type callbackParams struct {
+	Code  string `json:"-" query:"code"  validate:"required"`
+	State string `json:"-" query:"state" validate:"required"`
+}
+
+// Routes returns the BFF's HTTP routes. The OpenAPI document describing them
+// is served at GET /openapi.json.
+func (a *App) Routes() http.Handler {
+	// CSRF protection is opt-in, see CSRF_PROTECTION.
+	var csrfStore middleware.CSRFTokenStore
+	if a.Config.CSRFProtection {
+		csrfStore = a.service.SessionQueryService
+	}
+
+	routes, _ := newRoutes(a.Log, a.Handler, csrfStore)
+	return routes
+}
+
+// newRoutes registers every documented route and the OpenAPI document on a
+// new mux.
+func newRoutes(
+	log *slog.Logger,
+	handler *Handler,
+	csrfStore middleware.CSRFTokenStore,
+) (http.Handler, *openapi.Registry) {
+	mux := http.NewServeMux()
+	registry := openapi.NewRegistry(mux, apiInfo)
+	newRouter(log, handler, csrfStore).Register(registry)
+	mux.Handle("GET /openapi.json", registry.SpecHandler())
+
+	return mux, registry
+}
+
+// newRouter declares the BFF's route groups and their middleware. Unsafe
+// requests to /auth are checked by the CSRF middleware when csrfStore is not
+// nil. Without CSRF protection sessions are not stored, so the session
+// endpoints are not found.
+func newRouter(log *slog.Logger, handler *Handler, csrfStore middleware.CSRFTokenStore) *router.Router {
+	rt := router.New(log)
+
+	noSessions := func(http.ResponseWriter, *http.Request) error {
+		return apperror.New(apperror.CodeNotFound, "sessions are only stored with CSRF protection enabled")
+	}
+	getSession, deleteSession := noSessions, noSessions
+	if handler.SessionQueryHandler != nil {
+		getSession = handler.SessionQueryHandler.GetSessionStatus
+	}
+	if handler.SessionCommandHandler != nil {
+		deleteSession = handler.SessionCommandHandler.DeleteSession
+	}
+
+	// Auth routes are browser redirects, not JSON endpoints. They set and
+	// clear session cookies, so responses must never be cached.
+	rt.Group("/auth", func(auth *router.Router) {
+		auth.Use(router.Named("noStore", middleware.NoStore()))
+		if csrfStore != nil {
+			auth.Use(router.Named("csrf", middleware.CSRF(log, csrfStore)))
+		}
+		auth.HandleError(openapi.Route{
+			Request:     loginParams{ReturnTo: ""},
+			Response:    nil,
+			Method:      http.MethodGet,
+			Path:        "/login",
+			OperationID: "login",
+			Summary:     "Redirect to the identity provider to sign in",
+			Tags:        []string{"auth"},
+			Status:      http.StatusTemporaryRedirect,
+		}, handler.AuthCommandHandler.LoginHandler)
+		auth.HandleError(openapi.Route{
+			Request:     callbackParams{Code: "", State: ""},
+			Response:    nil,
+			Method:      http.MethodGet,
+			Path:        "/callback",
+			OperationID: "loginCallback",
+			Summary:     "Complete sign in and redirect back to the frontend",
+			Tags:        []string{"auth"},
+			Status:      http.StatusFound,
+		}, handler.AuthCommandHandler.CallbackHandler)
+		auth.HandleFunc(openapi.Route{
+			Request:     nil,
+			Response:    nil,
+			Method:      http.MethodGet,
+			Path:        "/logout",
+			OperationID: "logout",
+			Summary:     "Redirect to the identity provider to sign out",
+			Tags:        []string{"auth"},
+			Status:      http.StatusFound,
+		}, handler.AuthCommandHandler.LogutoutHandler)
+		auth.HandleError(openapi.Route{
+			Request:     nil,
+			Response:    session.SessionResponse{ExpiresAt: time.Time{}, CSRFToken: ""},
+			Method:      http.MethodGet,
+			Path:        "/session",
+			OperationID: "getSession",
+			Summary:     "Get the current session and its CSRF token",
+			Tags:        []string{"auth"},
+			Status:      http.StatusOK,
+		}, getSession)
+		auth.HandleError(openapi.Route{
+			Request:     nil,
+			Response:    nil,
+			Method:      http.MethodPost,
+			Path:        "/logout",
+			OperationID: "deleteSession",
+			Summary:     "Delete the current session and clear its cookies",
+			Tags:        []string{"auth"},
+			Status:      http.StatusNoContent,
+		}, deleteSession)
+	})
+
+	return rt
+}
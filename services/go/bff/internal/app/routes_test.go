@@ -0,0 +1,236 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-services/bff/internal/api"
+	"go-services/bff/internal/auth"
+	"go-services/bff/internal/config"
+	"go-services/bff/internal/session"
+	"go-services/library/assert"
+	libconfig "go-services/library/config"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+var update = flag.Bool("update", false, "rewrite the committed OpenAPI spec")
+
+// specPath is the committed OpenAPI document consumed by the frontend.
+var specPath = filepath.Join("..", "..", "api", "openapi.json")
+
+func TestOpenAPISpec(t *testing.T) {
+	log, _ := testlogger.New()
+	_, registry := newRoutes(log, &Handler{AuthCommandHandler: nil, SessionQueryHandler: nil, SessionCommandHandler: nil}, nil)
+
+	got, err := json.MarshalIndent(registry.Document(), "", "  ")
+	require.NoError(t, err, "marshal OpenAPI document")
+	got = append(got, '\n')
+
+	if *update {
+		require.NoError(t, os.WriteFile(specPath, got, 0o644), "write %s", specPath)
+	}
+
+	want, err := os.ReadFile(specPath)
+	require.NoError(t, err, "read %s", specPath)
+	assert.Equal(
+		t,
+		string(got),
+		string(want),
+		"%s is out of date; run `go test ./bff/internal/app -run TestOpenAPISpec -update`",
+		specPath,
+	)
+}
+
+// fakeAuthService stands in for the OIDC backed auth.CommandService.
+type fakeAuthService struct{}
+
+func (fakeAuthService) GenerateAuthCodeURL() (state, authURL string, err error) {
+	return "state", "https://idp.example.com/auth", nil
+}
+
+func (fakeAuthService) AuthenticateUser(context.Context, string) (sessionToken, accessToken string, err error) {
+	return "session", "access", nil
+}
+
+// fakeSessionQueryService stands in for the NATS KV backed
+// session.SessionQueryService.
+type fakeSessionQueryService struct{}
+
+func (fakeSessionQueryService) GetBySessionID(context.Context, string) (session.SessionModel, error) {
+	return session.SessionModel{
+		ExpiresAt:    time.Now().Add(time.Hour),
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		IDToken:      "id",
+		CSRFToken:    "csrf",
+	}, nil
+}
+
+func (fakeSessionQueryService) CSRFToken(context.Context, string) (string, error) {
+	return "csrf", nil
+}
+
+// fakeSessionCommandService stands in for the NATS KV backed
+// session.SessionCommandService.
+type fakeSessionCommandService struct{}
+
+func (fakeSessionCommandService) Delete(context.Context, string) error {
+	return nil
+}
+
+func TestSecurityHeadersPerRouteGroup(t *testing.T) {
+	baseline := map[string]string{
+		"X-Content-Type-Options":     "nosniff",
+		"Referrer-Policy":            "strict-origin-when-cross-origin",
+		"X-Frame-Options":            "DENY",
+		"Content-Security-Policy":    "frame-ancestors 'none'",
+		"Cross-Origin-Opener-Policy": "same-origin",
+	}
+
+	tests := map[string]struct {
+		cookie *http.Cookie
+		path   string
+		// preflightOrigin makes the request a CORS preflight from the origin.
+		preflightOrigin string
+		wantStatus      int
+		noStore         bool
+	}{
+		"auth login":          {cookie: nil, path: "/auth/login", preflightOrigin: "", wantStatus: http.StatusTemporaryRedirect, noStore: true},
+		"auth callback":       {cookie: &http.Cookie{Name: "oauth_state", Value: "s"}, path: "/auth/callback?code=c&state=s", preflightOrigin: "", wantStatus: http.StatusFound, noStore: true},
+		"auth callback error": {cookie: nil, path: "/auth/callback?code=c&state=s", preflightOrigin: "", wantStatus: http.StatusUnauthorized, noStore: true},
+		"auth session":        {cookie: &http.Cookie{Name: "session_token", Value: "s"}, path: "/auth/session", preflightOrigin: "", wantStatus: http.StatusOK, noStore: true},
+		"auth logout":         {cookie: nil, path: "/auth/logout", preflightOrigin: "", wantStatus: http.StatusFound, noStore: true},
+		"unknown api path":    {cookie: nil, path: "/api/unknown", preflightOrigin: "", wantStatus: http.StatusNotFound, noStore: false},
+		"openapi document":    {cookie: nil, path: "/openapi.json", preflightOrigin: "", wantStatus: http.StatusOK, noStore: false},
+		"unknown path":        {cookie: nil, path: "/unknown", preflightOrigin: "", wantStatus: http.StatusNotFound, noStore: false},
+		"allowed preflight":   {cookie: nil, path: "/auth/login", preflightOrigin: "http://localhost:3000", wantStatus: http.StatusNoContent, noStore: false},
+		"rejected preflight":  {cookie: nil, path: "/auth/login", preflightOrigin: "https://evil.example.com", wantStatus: http.StatusForbidden, noStore: false},
+	}
+
+	for _, useHTTPS := range []bool{false, true} {
+		log, _ := testlogger.New()
+		handler := &Handler{
+			AuthCommandHandler: auth.NewAuthCommandHandler(
+				log,
+				&config.OIDCProviderConfig{
+					BaseURL:      "https://idp.example.com",
+					Realm:        "test",
+					URL:          "https://idp.example.com",
+					ClientID:     "bff",
+					ClientSecret: libconfig.ReloadableSecret{},
+					CallbackURL:  "http://localhost/auth/callback",
+					LogoutURL:    "https://idp.example.com/logout",
+				},
+				useHTTPS,
+				"http://localhost:3000",
+				fakeAuthService{},
+			),
+			SessionQueryHandler:   session.NewSessionQueryHandler(fakeSessionQueryService{}, useHTTPS),
+			SessionCommandHandler: nil,
+		}
+		routes, _ := newRoutes(log, handler, nil)
+		server, err := newServer(log, useHTTPS, api.ErrorFormatEnvelope, []string{"http://localhost:3000"}, routes)
+		require.NoError(t, err, "new server")
+
+		for name, tt := range tests {
+			t.Run(fmt.Sprintf("%s https=%t", name, useHTTPS), func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				if tt.preflightOrigin != "" {
+					req.Method = http.MethodOptions
+					req.Header.Set("Origin", tt.preflightOrigin)
+					req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				}
+				if tt.cookie != nil {
+					req.AddCookie(tt.cookie)
+				}
+				rec := httptest.NewRecorder()
+
+				server.ServeHTTP(rec, req)
+
+				assert.Equal(t, rec.Code, tt.wantStatus, "status code")
+				for header, want := range baseline {
+					assert.Equal(t, rec.Header().Get(header), want, "header %s", header)
+				}
+
+				wantCacheControl := ""
+				if tt.noStore {
+					wantCacheControl = "no-store"
+				}
+				assert.Equal(t, rec.Header().Get("Cache-Control"), wantCacheControl, "Cache-Control")
+
+				wantHSTS := ""
+				if useHTTPS {
+					wantHSTS = "max-age=31536000; includeSubDomains"
+				}
+				assert.Equal(t, rec.Header().Get("Strict-Transport-Security"), wantHSTS, "Strict-Transport-Security")
+			})
+		}
+	}
+}
+
+func TestSessionRouteWithoutCSRFProtection(t *testing.T) {
+	log, _ := testlogger.New()
+	routes, _ := newRoutes(log, &Handler{AuthCommandHandler: nil, SessionQueryHandler: nil, SessionCommandHandler: nil}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/session", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: "s"})
+	rec := httptest.NewRecorder()
+
+	routes.ServeHTTP(rec, req)
+
+	assert.Equal(t, rec.Code, http.StatusNotFound, "status code")
+}
+
+func TestAuthRoutesRequireCSRFToken(t *testing.T) {
+	sessionCookies := []*http.Cookie{
+		{Name: "session_token", Value: "s"},
+		{Name: "csrf_token", Value: "csrf"},
+	}
+
+	tests := map[string]struct {
+		csrfToken  string
+		cookies    []*http.Cookie
+		wantStatus int
+	}{
+		"missing token":          {csrfToken: "", cookies: sessionCookies, wantStatus: http.StatusForbidden},
+		"stale token":            {csrfToken: "stale", cookies: sessionCookies, wantStatus: http.StatusForbidden},
+		"matching token":         {csrfToken: "csrf", cookies: sessionCookies, wantStatus: http.StatusNoContent},
+		"without session cookie": {csrfToken: "", cookies: nil, wantStatus: http.StatusUnauthorized},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, _ := testlogger.New()
+			handler := &Handler{
+				AuthCommandHandler:    nil,
+				SessionQueryHandler:   session.NewSessionQueryHandler(fakeSessionQueryService{}, false),
+				SessionCommandHandler: session.NewSessionCommandHandler(fakeSessionCommandService{}, false),
+			}
+			routes, _ := newRoutes(log, handler, fakeSessionQueryService{})
+			server, err := newServer(log, false, api.ErrorFormatEnvelope, []string{"http://localhost:3000"}, routes)
+			require.NoError(t, err, "new server")
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+			for _, cookie := range tt.cookies {
+				req.AddCookie(cookie)
+			}
+			if tt.csrfToken != "" {
+				req.Header.Set(api.CSRFTokenHeader, tt.csrfToken)
+			}
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			assert.Equal(t, rec.Code, tt.wantStatus, "status code")
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+)
+
+// secretOAuth2Config is an oauth2.Config whose client secret is looked up
+// for every token request, so a rotated Keycloak client secret is used
+// without a restart.
+type secretOAuth2Config struct {
+	secret func(ctx context.Context) (string, error)
+	config oauth2.Config
+}
+
+func (c *secretOAuth2Config) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return c.config.AuthCodeURL(state, opts...)
+}
+
+func (c *secretOAuth2Config) Exchange(
+	ctx context.Context,
+	code string,
+	opts ...oauth2.AuthCodeOption,
+) (*oauth2.Token, error) {
+	config, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.Exchange(ctx, code, opts...)
+}
+
+func (c *secretOAuth2Config) TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource {
+	config, err := c.current(ctx)
+	if err != nil {
+		return errTokenSource{err: err}
+	}
+	return config.TokenSource(ctx, t)
+}
+
+// current returns a copy of the config with the current client secret.
+func (c *secretOAuth2Config) current(ctx context.Context) (*oauth2.Config, error) {
+	secret, err := c.secret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := c.config
+	config.ClientSecret = secret
+	return &config, nil
+}
+
+// errTokenSource fails every token request with err.
+type errTokenSource struct {
+	err error
+}
+
+func (s errTokenSource) Token() (*oauth2.Token, error) {
+	return nil, s.err
+}
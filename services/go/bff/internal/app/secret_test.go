@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"go-services/library/assert"
+	libconfig "go-services/library/config"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+func TestSecretOAuth2ConfigUsesRotatedSecret(t *testing.T) {
+	var gotSecrets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, secret, _ := r.BasicAuth()
+		gotSecrets = append(gotSecrets, secret)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":300}`))
+		require.NoError(t, err, "write response")
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "client-secret")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600), "write secret")
+	secret, err := libconfig.NewReloadableSecret("file://" + path)
+	require.NoError(t, err, "load secret")
+
+	log, _ := testlogger.New()
+	cfg := &secretOAuth2Config{
+		secret: secret.Func(log, "keycloak client secret"),
+		config: oauth2.Config{
+			ClientID:     "bff",
+			ClientSecret: "",
+			Endpoint:     oauth2.Endpoint{AuthURL: ts.URL + "/auth", TokenURL: ts.URL + "/token"},
+			RedirectURL:  "http://localhost/auth/callback",
+			Scopes:       nil,
+		},
+	}
+
+	_, err = cfg.Exchange(context.Background(), "code")
+	require.NoError(t, err, "first exchange")
+
+	require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o600), "rotate secret")
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime), "touch secret")
+
+	_, err = cfg.Exchange(context.Background(), "code")
+	require.NoError(t, err, "second exchange")
+
+	assert.Equal(t, gotSecrets, []string{"first", "rotated"}, "client secrets used")
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-services/bff/internal/api"
+	"go-services/bff/internal/api/middleware"
+)
+
+// Server returns the BFF's HTTP handler: Routes wrapped in the middleware
+// that applies to every request.
+func (a *App) Server() (http.Handler, error) {
+	return newServer(a.Log, a.Config.UseHTTPS, a.Config.ErrorFormat, a.Config.CORSAllowedOrigins, a.Routes())
+}
+
+// newServer wraps routes in the middleware that applies to every request.
+// The security headers are set first, so responses written by the other
+// middleware, such as rejected CORS preflights, carry them as well. HSTS is
+// only sent when useHTTPS is set.
+func newServer(
+	log *slog.Logger,
+	useHTTPS bool,
+	errorFormat api.ErrorFormat,
+	allowedOrigins []string,
+	routes http.Handler,
+) (http.Handler, error) {
+	cors, err := middleware.CORS(log, middleware.CORSPolicy{
+		Routes: map[string]middleware.CORSRouteRule{
+			"/auth/": {
+				AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+				AllowedHeaders: nil,
+			},
+		},
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   nil,
+		AllowedHeaders:   []string{"Content-Type", "Authorization", api.CSRFTokenHeader},
+		ExposedHeaders:   nil,
+		MaxAge:           10 * time.Minute,
+		AllowCredentials: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cors middleware: %w", err)
+	}
+
+	chain := middleware.NewChain()
+	chain.Add(
+		middleware.SecurityHeaders(middleware.DefaultSecurityHeadersPolicy(useHTTPS)),
+		middleware.RequestID(),
+		middleware.ErrorFormat(errorFormat),
+		middleware.Recover(log),
+		middleware.Logging(log),
+		cors,
+	)
+	return chain.Apply(routes), nil
+}
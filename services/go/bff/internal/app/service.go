@@ -12,36 +12,56 @@ import (
 	"go-services/bff/internal/auth"
 	"go-services/bff/internal/common/tokenutil"
 	"go-services/bff/internal/config"
+	"go-services/bff/internal/session"
 )
 
+// service holds the BFF's services. The session services are nil unless
+// CSRF protection is enabled.
 type service struct {
-	AuthCommandService *auth.AuthCommandService
-	// SessinQueryService    *session.SessionQueryService
-	// SessionCommandService *session.SessionCommandService
+	AuthCommandService    *auth.AuthCommandService
+	SessionQueryService   *session.SessionQueryService
+	SessionCommandService *session.SessionCommandService
 }
 
+// newService creates the services. repository is nil unless CSRF protection
+// is enabled, in which case sessions are not stored.
 func newService(
 	ctx context.Context,
 	log *slog.Logger,
 	cfg *config.Config,
-	// repository *repository,
+	repository *repository,
 ) (*service, error) {
-	// sessionQueryService := session.NewSessionQueryService(
-	// 	repository.NatsKVSessionQueryRepository)
-	// sessionCommandService := session.NewSessionCommandService(
-	// 	repository.NatsKVSessionCommandRepository)
+	var sessionQueryService *session.SessionQueryService
+	var sessionCommandService *session.SessionCommandService
+	// sessionStore stays a nil interface without a repository, rather than
+	// one holding a nil *session.SessionCommandService.
+	var sessionStore interface {
+		Put(ctx context.Context, createSessionCommand session.CreateSessionCommand) error
+	}
+	if repository != nil {
+		sessionQueryService = session.NewSessionQueryService(
+			repository.NatsKVSessionQueryRepository,
+		)
+		sessionCommandService = session.NewSessionCommandService(
+			repository.NatsKVSessionCommandRepository,
+		)
+		sessionStore = sessionCommandService
+	}
 
 	provider, err := oidc.NewProvider(ctx, cfg.Keycloak.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
 	}
 	verifier := provider.VerifierContext(ctx, &oidc.Config{ClientID: cfg.Keycloak.ClientID})
-	oauth2Config := &oauth2.Config{
-		ClientID:     cfg.Keycloak.ClientID,
-		ClientSecret: cfg.Keycloak.ClientSecret,
-		RedirectURL:  cfg.Keycloak.CallbackURL,
-		Endpoint:     provider.Endpoint(),
-		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+	oauth2Config := &secretOAuth2Config{
+		secret: cfg.Keycloak.ClientSecret.Func(log, "keycloak client secret"),
+		config: oauth2.Config{
+			ClientID:     cfg.Keycloak.ClientID,
+			ClientSecret: "",
+			RedirectURL:  cfg.Keycloak.CallbackURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
 	}
 	tokenGenerator := tokenutil.NewTokenUtil(rand.Reader)
 
@@ -51,11 +71,12 @@ func newService(
 		oauth2Config,
 		verifier,
 		tokenGenerator,
+		sessionStore,
 	)
 
 	return &service{
-		AuthCommandService: authCommandService,
-		// SessinQueryService:    sessionQueryService,
-		// SessionCommandService: sessionCommandService,
+		AuthCommandService:    authCommandService,
+		SessionQueryService:   sessionQueryService,
+		SessionCommandService: sessionCommandService,
 	}, nil
 }
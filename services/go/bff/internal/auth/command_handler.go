@@ -98,8 +98,8 @@ func (h *AuthCommandHandler) CallbackHandler(w http.ResponseWriter, r *http.Requ
 
 	h.clearCookie(w, returnToCookieName)
 	h.clearCookie(w, stateCookieName)
-	h.setCookie(w, api.AccessTokenCookieName, accessToken, 24*time.Hour)
-	h.setCookie(w, api.SessionTokenCookieName, sessionToken, 24*time.Hour)
+	h.setCookie(w, api.AccessTokenCookieName, accessToken, api.SessionLifetime)
+	h.setCookie(w, api.SessionTokenCookieName, sessionToken, api.SessionLifetime)
 
 	frontendURL := fmt.Sprintf("%s%s", h.frontendBaseURL, returnTo)
 	http.Redirect(w, r, frontendURL, http.StatusFound)
@@ -3,12 +3,15 @@ package auth
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
 
+	"go-services/bff/internal/api"
 	"go-services/bff/internal/common/tokenutil"
 	"go-services/bff/internal/config"
+	"go-services/bff/internal/session"
 	"go-services/library/apperror"
 )
 
@@ -22,27 +25,37 @@ type oauth2Config interface {
 	TokenSource(ctx context.Context, t *oauth2.Token) oauth2.TokenSource
 }
 
+type sessionCommandService interface {
+	Put(ctx context.Context, createSessionCommand session.CreateSessionCommand) error
+}
+
 type AuthCommandService struct {
-	log            *slog.Logger
-	providerConfig *config.OIDCProviderConfig
-	oauth2Config   oauth2Config
-	verifier       oidcVerifier
-	tokenGenerator *tokenutil.TokenUtil
+	log                   *slog.Logger
+	providerConfig        *config.OIDCProviderConfig
+	oauth2Config          oauth2Config
+	verifier              oidcVerifier
+	tokenGenerator        *tokenutil.TokenUtil
+	sessionCommandService sessionCommandService
 }
 
+// NewAuthCommandService returns the service signing users in. Sessions are
+// stored with sessionCommandService, which is nil unless CSRF protection is
+// enabled, as nothing else reads them yet.
 func NewAuthCommandService(
 	log *slog.Logger,
 	providerConfig *config.OIDCProviderConfig,
 	oauth2Client oauth2Config,
 	verifier oidcVerifier,
 	tokenGenerator *tokenutil.TokenUtil,
+	sessionCommandService sessionCommandService,
 ) *AuthCommandService {
 	return &AuthCommandService{
-		log:            log,
-		providerConfig: providerConfig,
-		oauth2Config:   oauth2Client,
-		verifier:       verifier,
-		tokenGenerator: tokenGenerator,
+		log:                   log,
+		providerConfig:        providerConfig,
+		oauth2Config:          oauth2Client,
+		verifier:              verifier,
+		tokenGenerator:        tokenGenerator,
+		sessionCommandService: sessionCommandService,
 	}
 }
 
@@ -92,7 +105,29 @@ func (s *AuthCommandService) AuthenticateUser(
 		return "", "", apperror.Wrap(apperror.CodeInternalError, err, "faield to generate session token: w")
 	}
 
-	// TODO: store refresh token
+	if s.sessionCommandService == nil {
+		return sessionToken, oauth2Token.AccessToken, nil
+	}
+
+	// The CSRF token is bound to the session and exposed by the session
+	// endpoint. The session outlives the access token, which is refreshed.
+	csrfToken, err := s.tokenGenerator.GenerateStateToken()
+	if err != nil {
+		return "", "", apperror.Wrap(apperror.CodeInternalError, err, "failed to generate CSRF token")
+	}
+
+	err = s.sessionCommandService.Put(ctx, session.CreateSessionCommand{
+		ExpiresAt:    time.Now().Add(api.SessionLifetime),
+		SessionID:    sessionToken,
+		AccessToken:  oauth2Token.AccessToken,
+		RefreshToken: oauth2Token.RefreshToken,
+		IDToken:      rawIDToken,
+		CSRFToken:    csrfToken,
+	})
+	if err != nil {
+		return "", "", apperror.Wrap(apperror.CodeInternalError, err, "failed to store session")
+	}
+
 	return sessionToken, oauth2Token.AccessToken, nil
 }
 
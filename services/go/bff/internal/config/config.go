@@ -3,106 +3,55 @@ package config
 import (
 	"fmt"
 	"net/url"
-	"os"
-	"strconv"
-	"strings"
+
+	"go-services/bff/internal/api"
+	libconfig "go-services/library/config"
 )
 
 type OIDCProviderConfig struct {
-	URL          string
-	ClientID     string
-	ClientSecret string
-	CallbackURL  string
-	LogoutURL    string
+	BaseURL      string                     `env:"KEYCLOAK_BASE_URL"      file:"base_url"      required:"true"`
+	Realm        string                     `env:"KEYCLOAK_REALM"         file:"realm"         required:"true"`
+	ClientID     string                     `env:"KEYCLOAK_CLIENT_ID"     file:"client_id"     required:"true"`
+	ClientSecret libconfig.ReloadableSecret `env:"KEYCLOAK_CLIENT_SECRET" file:"client_secret" required:"true"`
+	CallbackURL  string                     `env:"KEYCLOAK_CALLBACK_URL"  file:"callback_url"  required:"true"`
+	// URL and LogoutURL are derived from BaseURL and Realm.
+	URL       string
+	LogoutURL string
 }
 
 type Config struct {
-	Keycloak                *OIDCProviderConfig
-	ServerPort              string
-	FrontendBaseURL         string
-	NatsURL                 string
-	NatsKVSessionBucketName string
-	SessionSecret           []byte
-	UseHTTPS                bool
+	Keycloak                *OIDCProviderConfig `file:"keycloak"`
+	FrontendBaseURL         string              `env:"FRONTEND_BASE_URL"           file:"frontend_base_url"           required:"true"`
+	NatsURL                 string              `env:"NATS_URL"                    file:"nats_url"                    required:"true"`
+	NatsKVSessionBucketName string              `env:"NATS_KV_SESSION_BUCKET_NAME" file:"nats_kv_session_bucket_name" required:"true"`
+	ErrorFormat             api.ErrorFormat     `env:"ERROR_FORMAT"                file:"error_format"                default:"envelope"`
+	SessionSecret           libconfig.Secret    `env:"SESSION_SECRET"              file:"session_secret"              required:"true"`
+	// CORSAllowedOrigins defaults to FrontendBaseURL.
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" file:"cors_allowed_origins"`
+	ServerPort         int      `env:"SERVER_PORT"          file:"server_port"          required:"true" flag:"port"`
+	UseHTTPS           bool     `env:"USE_HTTPS"            file:"use_https"            required:"true"`
+	CSRFProtection     bool     `env:"CSRF_PROTECTION"      file:"csrf_protection"      default:"false"`
 }
 
-func New() (*Config, error) {
-	const (
-		keycloakBaseURLKey         = "KEYCLOAK_BASE_URL"
-		keycloakRealmKey           = "KEYCLOAK_REALM"
-		keycloakClientIDKey        = "KEYCLOAK_CLIENT_ID"
-		keycloakClientSecretKey    = "KEYCLOAK_CLIENT_SECRET"
-		keycloakCallbackURLKey     = "KEYCLOAK_CALLBACK_URL"
-		serverPortKey              = "SERVER_PORT"
-		sessionSecretKey           = "SESSION_SECRET"
-		useHTTPSKey                = "USE_HTTPS"
-		frontendBaseURLKey         = "FRONTEND_BASE_URL"
-		natsURLKey                 = "NATS_URL"
-		natsKVSessionBucketNameKey = "NATS_KV_SESSION_BUCKET_NAME"
-	)
-	required := []string{
-		keycloakBaseURLKey,
-		keycloakRealmKey,
-		keycloakClientIDKey,
-		keycloakClientSecretKey,
-		keycloakCallbackURLKey,
-		serverPortKey,
-		sessionSecretKey,
-		useHTTPSKey,
-		frontendBaseURLKey,
-		natsURLKey,
-		natsKVSessionBucketNameKey,
-	}
-
-	missing := []string{}
-	// Check all required envs
-	for _, key := range required {
-		if os.Getenv(key) == "" {
-			missing = append(missing, key)
-		}
-	}
-
-	if len(missing) > 0 {
-		return nil, fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", "))
-	}
-
-	// Convert SERVER_PORT to int
-	_, err := strconv.Atoi(os.Getenv(serverPortKey))
-	if err != nil {
-		return nil, fmt.Errorf("invalid SERVER_PORT: %w", err)
+// New loads the configuration from environment variables, an optional
+// configuration file (--config) and the command line flags in args, usually
+// os.Args[1:]. All missing or invalid settings are reported together.
+func New(args []string) (*Config, error) {
+	cfg := &Config{}
+	if err := libconfig.Load(cfg, libconfig.WithArgs(args)); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	frontendBaseURL := os.Getenv(frontendBaseURLKey)
-
-	keycloakBaseURL := os.Getenv(keycloakBaseURLKey)
-	keycloakRealm := os.Getenv(keycloakRealmKey)
-	keycloakURL := fmt.Sprintf("%s/realms/%s", keycloakBaseURL, keycloakRealm)
-	keycloakLogoutURL := fmt.Sprintf(
+	cfg.Keycloak.URL = fmt.Sprintf("%s/realms/%s", cfg.Keycloak.BaseURL, cfg.Keycloak.Realm)
+	cfg.Keycloak.LogoutURL = fmt.Sprintf(
 		"%s/protocol/openid-connect/logout?post_logout_redirect_uri=%s",
-		keycloakBaseURL,
-		url.QueryEscape(frontendBaseURL),
+		cfg.Keycloak.BaseURL,
+		url.QueryEscape(cfg.FrontendBaseURL),
 	)
-	keycloak := &OIDCProviderConfig{
-		URL:          keycloakURL,
-		ClientID:     os.Getenv(keycloakClientIDKey),
-		ClientSecret: os.Getenv(keycloakClientSecretKey),
-		CallbackURL:  os.Getenv(keycloakCallbackURLKey),
-		LogoutURL:    keycloakLogoutURL,
-	}
 
-	useHTTPSKeyEnv := os.Getenv(useHTTPSKey)
-	useHTTPS, err := strconv.ParseBool(useHTTPSKeyEnv)
-	if err != nil {
-		return nil, fmt.Errorf("invalid boolean for %s: %v", useHTTPSKey, useHTTPSKeyEnv)
+	if len(cfg.CORSAllowedOrigins) == 0 {
+		cfg.CORSAllowedOrigins = []string{cfg.FrontendBaseURL}
 	}
 
-	return &Config{
-		Keycloak:                keycloak,
-		ServerPort:              os.Getenv(serverPortKey),
-		SessionSecret:           []byte(os.Getenv(sessionSecretKey)),
-		UseHTTPS:                useHTTPS,
-		FrontendBaseURL:         frontendBaseURL,
-		NatsURL:                 os.Getenv(natsURLKey),
-		NatsKVSessionBucketName: os.Getenv(natsKVSessionBucketNameKey),
-	}, nil
+	return cfg, nil
 }
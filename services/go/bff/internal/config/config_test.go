@@ -0,0 +1,71 @@
+package config_test
+
+import (
+	"testing"
+
+	"go-services/bff/internal/api"
+	"go-services/bff/internal/config"
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("KEYCLOAK_BASE_URL", "http://localhost:7777")
+	t.Setenv("KEYCLOAK_REALM", "playground")
+	t.Setenv("KEYCLOAK_CLIENT_ID", "bff")
+	t.Setenv("KEYCLOAK_CLIENT_SECRET", "secret")
+	t.Setenv("KEYCLOAK_CALLBACK_URL", "http://localhost:8080/auth/callback")
+	t.Setenv("SERVER_PORT", "8080")
+	t.Setenv("SESSION_SECRET", "session-secret")
+	t.Setenv("USE_HTTPS", "false")
+	t.Setenv("FRONTEND_BASE_URL", "http://localhost:3000")
+	t.Setenv("NATS_URL", "nats://localhost:4222")
+	t.Setenv("NATS_KV_SESSION_BUCKET_NAME", "sessions")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("ERROR_FORMAT", "")
+	t.Setenv("CSRF_PROTECTION", "")
+}
+
+func TestNew_Success(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := config.New(nil)
+	require.NoError(t, err, "load config")
+
+	assert.Equal(t, cfg.Keycloak.URL, "http://localhost:7777/realms/playground", "keycloak url")
+	assert.Equal(
+		t,
+		cfg.Keycloak.LogoutURL,
+		"http://localhost:7777/protocol/openid-connect/logout?post_logout_redirect_uri=http%3A%2F%2Flocalhost%3A3000",
+		"logout url",
+	)
+	assert.Equal(t, cfg.ServerPort, 8080, "server port")
+	assert.Equal(t, cfg.CORSAllowedOrigins, []string{"http://localhost:3000"}, "cors origins default to frontend")
+	assert.Equal(t, cfg.ErrorFormat, api.ErrorFormatEnvelope, "default error format")
+	assert.False(t, cfg.CSRFProtection, "csrf protection disabled by default")
+}
+
+func TestNew_FlagsOverrideEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com,https://b.example.com")
+
+	cfg, err := config.New([]string{"--port", "9090"})
+	require.NoError(t, err, "load config")
+
+	assert.Equal(t, cfg.ServerPort, 9090, "port flag")
+	assert.Equal(t, cfg.CORSAllowedOrigins, []string{"https://a.example.com", "https://b.example.com"}, "cors origins")
+}
+
+func TestNew_InvalidValues(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SERVER_PORT", "http")
+	t.Setenv("ERROR_FORMAT", "xml")
+	t.Setenv("USE_HTTPS", "")
+
+	_, err := config.New(nil)
+
+	assert.ErrorContains(t, err, "invalid configuration (3 problems)", "all problems reported")
+	assert.ErrorContains(t, err, "invalid env ERROR_FORMAT", "invalid error format")
+	assert.ErrorContains(t, err, "invalid env SERVER_PORT", "invalid port")
+	assert.ErrorContains(t, err, "missing value: set env USE_HTTPS", "missing use https")
+}
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"context"
+	"net/http"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
+)
+
+type sessionCommandService interface {
+	Delete(ctx context.Context, sessionID string) error
+}
+
+type SessionCommandHandler struct {
+	sessionCommandService sessionCommandService
+	useHTTPS              bool
+}
+
+func NewSessionCommandHandler(sessionCommandService sessionCommandService, useHTTPS bool) *SessionCommandHandler {
+	return &SessionCommandHandler{
+		sessionCommandService: sessionCommandService,
+		useHTTPS:              useHTTPS,
+	}
+}
+
+// DeleteSession deletes the session of the session cookie and clears the
+// session, access token and CSRF token cookies. Signing out of the identity
+// provider is left to GET /auth/logout.
+func (s *SessionCommandHandler) DeleteSession(w http.ResponseWriter, r *http.Request) error {
+	sessionCookie, err := r.Cookie(api.SessionTokenCookieName)
+	if err != nil {
+		return apperror.Wrap(
+			apperror.CodeUnauthorized,
+			err,
+			"missing required cookie: %v", api.SessionTokenCookieName,
+		)
+	}
+
+	if err := s.sessionCommandService.Delete(r.Context(), sessionCookie.Value); err != nil {
+		return err
+	}
+
+	s.clearCookie(w, api.SessionTokenCookieName, true)
+	s.clearCookie(w, api.AccessTokenCookieName, true)
+	s.clearCookie(w, api.CSRFTokenCookieName, false)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *SessionCommandHandler) clearCookie(w http.ResponseWriter, name string, httpOnly bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: httpOnly,
+		Secure:   s.useHTTPS,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
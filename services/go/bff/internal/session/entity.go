@@ -7,4 +7,5 @@ type Session struct {
 	AccessToken  string
 	RefreshToken string
 	IDToken      string
+	CSRFToken    string
 }
@@ -10,5 +10,6 @@ func ToSession(createSessionCommand CreateSessionCommand) Session {
 		RefreshToken: createSessionCommand.RefreshToken,
 		IDToken:      createSessionCommand.IDToken,
 		ExpiresAt:    createSessionCommand.ExpiresAt,
+		CSRFToken:    createSessionCommand.CSRFToken,
 	}
 }
@@ -8,6 +8,7 @@ type CreateSessionCommand struct {
 	AccessToken  string
 	RefreshToken string
 	IDToken      string
+	CSRFToken    string
 }
 
 type SessionModel struct {
@@ -15,4 +16,11 @@ type SessionModel struct {
 	AccessToken  string
 	RefreshToken string
 	IDToken      string
+	CSRFToken    string
+}
+
+// SessionResponse is the body of the session endpoint.
+type SessionResponse struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	CSRFToken string    `json:"csrfToken"`
 }
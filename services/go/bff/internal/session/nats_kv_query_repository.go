@@ -3,10 +3,13 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
+
+	"go-services/library/apperror"
 )
 
 type NatsKVSessionQueryRepository struct {
@@ -36,6 +39,9 @@ func (s *NatsKVSessionQueryRepository) Get(ctx context.Context, sessionID string
 	defer cancel()
 
 	entry, err := s.sessions.Get(ctxWithTimeout, sessionID)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return Session{}, apperror.Wrap(apperror.CodeUnauthorized, err, "session not found")
+	}
 	if err != nil {
 		return Session{}, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -3,6 +3,9 @@ package session
 import (
 	"context"
 	"net/http"
+
+	"go-services/bff/internal/api"
+	"go-services/library/apperror"
 )
 
 type sessionQueryService interface {
@@ -11,18 +14,45 @@ type sessionQueryService interface {
 
 type SessionQueryHandler struct {
 	sessionQueryService sessionQueryService
+	useHTTPS            bool
 }
 
-func NewSessionQueryHandler(sessionQueryService sessionQueryService) *SessionQueryHandler {
+func NewSessionQueryHandler(sessionQueryService sessionQueryService, useHTTPS bool) *SessionQueryHandler {
 	return &SessionQueryHandler{
 		sessionQueryService: sessionQueryService,
+		useHTTPS:            useHTTPS,
 	}
 }
 
-func (s *SessionQueryHandler) GetSessionStatus(w http.ResponseWriter, r *http.Request) {
-	// sessionID, err := r.Cookie(shared.SessionTokenCookieName)
-	// if err != nil {
-	// 	w.w
-	// }
-	// session, err := s.sessionQueryService.GetBySessionID(r.context())
+// GetSessionStatus returns the session of the session cookie together with
+// its CSRF token. The token is also set in a cookie readable by scripts, so
+// the frontend can send it back in the X-CSRF-Token header.
+func (s *SessionQueryHandler) GetSessionStatus(w http.ResponseWriter, r *http.Request) error {
+	sessionCookie, err := r.Cookie(api.SessionTokenCookieName)
+	if err != nil {
+		return apperror.Wrap(
+			apperror.CodeUnauthorized,
+			err,
+			"missing required cookie: %v", api.SessionTokenCookieName,
+		)
+	}
+
+	session, err := s.sessionQueryService.GetBySessionID(r.Context(), sessionCookie.Value)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     api.CSRFTokenCookieName,
+		Value:    session.CSRFToken,
+		Path:     "/",
+		HttpOnly: false,
+		Secure:   s.useHTTPS,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return api.SendJSON(w, http.StatusOK, SessionResponse{
+		ExpiresAt: session.ExpiresAt,
+		CSRFToken: session.CSRFToken,
+	})
 }
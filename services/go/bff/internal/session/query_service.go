@@ -29,3 +29,13 @@ func (s *SessionQueryService) GetBySessionID(
 
 	return ToSessionDTO(session), nil
 }
+
+// CSRFToken returns the CSRF token bound to the session.
+func (s *SessionQueryService) CSRFToken(ctx context.Context, sessionID string) (string, error) {
+	session, err := s.sessionQueryRepository.Get(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	return session.CSRFToken, nil
+}
@@ -6,9 +6,22 @@ import (
 )
 
 type AppError struct {
-	Err  error
-	Msg  string
-	Code ErrorCode
+	Err error
+	// Metadata holds additional, machine-readable details about the error
+	// that are safe to expose to clients.
+	Metadata map[string]any
+	Msg      string
+	Code     ErrorCode
+	// Violations lists the individual field failures of a validation error.
+	Violations []FieldViolation
+}
+
+// FieldViolation describes why a single field of a request is invalid.
+type FieldViolation struct {
+	// Field is the path of the offending field, e.g. "items[0].name".
+	Field   string    `json:"field"`
+	Message string    `json:"message"`
+	Code    ErrorCode `json:"code"`
 }
 
 func (e *AppError) Error() string {
@@ -24,9 +37,11 @@ func (e *AppError) Unwrap() error {
 
 func New(code ErrorCode, msg string, msgArgs ...any) error {
 	return &AppError{
-		Msg:  fmt.Sprintf(msg, msgArgs...),
-		Code: code,
-		Err:  nil,
+		Msg:        fmt.Sprintf(msg, msgArgs...),
+		Code:       code,
+		Err:        nil,
+		Metadata:   nil,
+		Violations: nil,
 	}
 }
 
@@ -36,10 +51,38 @@ func Wrap(code ErrorCode, err error, msg string, msgArgs ...any) error {
 	}
 
 	return &AppError{
-		Code: code,
-		Err:  err,
-		Msg:  fmt.Sprintf(msg, msgArgs...),
+		Code:       code,
+		Err:        err,
+		Msg:        fmt.Sprintf(msg, msgArgs...),
+		Metadata:   nil,
+		Violations: nil,
+	}
+}
+
+// NewValidation returns a CodeInvalidInput error carrying the given field
+// violations, or nil if there are none.
+func NewValidation(violations ...FieldViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &AppError{
+		Code:       CodeInvalidInput,
+		Err:        nil,
+		Msg:        "request validation failed",
+		Metadata:   nil,
+		Violations: violations,
+	}
+}
+
+// WithMetadata adds a metadata entry to the error and returns it, so calls can
+// be chained after New or Wrap has been unpacked with As.
+func (e *AppError) WithMetadata(key string, value any) *AppError {
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]any)
 	}
+	e.Metadata[key] = value
+	return e
 }
 
 func As(err error) (*AppError, bool) {
@@ -55,7 +98,7 @@ func As(err error) (*AppError, bool) {
 func (e *AppError) ToHTTPStatus() int {
 	// TODO: cover all error code
 	switch e.Code {
-	case CodeInvalidInput, CodeInvalidFormat:
+	case CodeInvalidInput, CodeInvalidFormat, CodeMissingField, CodeOutOfRange:
 		return 400 // Bad Request
 	case CodeUnauthorized:
 		return 401 // Unauthorized
@@ -63,8 +106,14 @@ func (e *AppError) ToHTTPStatus() int {
 		return 403 // Forbidden
 	case CodeNotFound:
 		return 404 // Not Found
+	case CodeNotAcceptable:
+		return 406 // Not Acceptable
 	case CodeConflict:
 		return 409 // Conflict
+	case CodeTooLarge:
+		return 413 // Content Too Large
+	case CodeUnsupportedMediaType:
+		return 415 // Unsupported Media Type
 	case CodeTooManyRequests:
 		return 429
 	case CodeInternalError:
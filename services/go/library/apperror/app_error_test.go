@@ -35,3 +35,32 @@ func TestWrap_Nil(t *testing.T) {
 	err := apperror.Wrap(apperror.CodeAccountLocked, nil, "msg")
 	assert.Nil(t, err, "expected nil when wrapping nil error, got %v", err)
 }
+
+func TestNewValidation(t *testing.T) {
+	violations := []apperror.FieldViolation{
+		{Field: "name", Message: "is required", Code: apperror.CodeMissingField},
+		{Field: "items[1].quantity", Message: "must be at least 1", Code: apperror.CodeOutOfRange},
+	}
+
+	err := apperror.NewValidation(violations...)
+
+	appErr, ok := apperror.As(err)
+	assert.True(t, ok, "validation error should be an *AppError")
+	assert.Equal(t, appErr.Code, apperror.CodeInvalidInput, "validation error code")
+	assert.Equal(t, appErr.Violations, violations, "validation violations")
+	assert.Equal(t, appErr.ToHTTPStatus(), 400, "validation http status")
+}
+
+func TestNewValidation_NoViolations(t *testing.T) {
+	err := apperror.NewValidation()
+	assert.Nil(t, err, "expected nil without violations, got %v", err)
+}
+
+func TestWithMetadata(t *testing.T) {
+	appErr, ok := apperror.As(apperror.New(apperror.CodeConflict, "version mismatch"))
+	assert.True(t, ok, "New should return an *AppError")
+
+	appErr.WithMetadata("currentVersion", 3).WithMetadata("resource", "order")
+
+	assert.Equal(t, appErr.Metadata, map[string]any{"currentVersion": 3, "resource": "order"}, "metadata")
+}
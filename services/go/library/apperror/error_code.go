@@ -45,3 +45,40 @@ const (
 	CodeDNSResolution    ErrorCode = "DNS_RESOLUTION_FAILED"
 	CodeRequestTimeout   ErrorCode = "REQUEST_TIMEOUT"
 )
+
+// Codes returns every ErrorCode defined by this package, in declaration
+// order. It is used to document the set of codes clients may receive.
+func Codes() []ErrorCode {
+	return []ErrorCode{
+		CodeInvalidInput,
+		CodeMissingField,
+		CodeInvalidFormat,
+		CodeTooLarge,
+		CodeOutOfRange,
+		CodeUnauthorized,
+		CodeForbidden,
+		CodeTokenExpired,
+		CodeAccountLocked,
+		CodeNotFound,
+		CodeDuplicateRecord,
+		CodeConflict,
+		CodeTooManyRequests,
+		CodeUnsupportedMediaType,
+		CodeNotAcceptable,
+		CodeInternalError,
+		CodeNotImplemented,
+		CodeServiceUnavailable,
+		CodeGatewayTimeout,
+		CodeDependencyFailed,
+		CodeSerializationError,
+		CodeDBConnection,
+		CodeDBTimeout,
+		CodeDBTransaction,
+		CodeRecordLocked,
+		CodeDataCorruption,
+		CodeConnectionFailed,
+		CodeExternalService,
+		CodeDNSResolution,
+		CodeRequestTimeout,
+	}
+}
@@ -0,0 +1,67 @@
+package apperror_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+// TestCodesListsEveryCode guards the hand-maintained Codes list, which feeds
+// the documented error code enum, against constants added without it.
+func TestCodesListsEveryCode(t *testing.T) {
+	paths, err := filepath.Glob("*.go")
+	require.NoError(t, err, "failed to list package files")
+
+	fset := token.NewFileSet()
+	var declared []apperror.ErrorCode
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		require.NoError(t, err, "failed to parse %s", path)
+		for _, decl := range file.Decls {
+			declared = append(declared, errorCodeConsts(t, decl)...)
+		}
+	}
+
+	assert.Equal(t, apperror.Codes(), declared, "Codes() must list every declared ErrorCode constant in declaration order")
+}
+
+// errorCodeConsts returns the values of the ErrorCode constants declared by
+// decl.
+func errorCodeConsts(t *testing.T, decl ast.Decl) []apperror.ErrorCode {
+	t.Helper()
+
+	gen, ok := decl.(*ast.GenDecl)
+	if !ok || gen.Tok != token.CONST {
+		return nil
+	}
+
+	var codes []apperror.ErrorCode
+	for _, spec := range gen.Specs {
+		value, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if typ, ok := value.Type.(*ast.Ident); !ok || typ.Name != "ErrorCode" {
+			continue
+		}
+		for i, name := range value.Names {
+			lit, ok := value.Values[i].(*ast.BasicLit)
+			require.True(t, ok && lit.Kind == token.STRING, "constant %s must be a string literal", name.Name)
+			code, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err, "failed to unquote %s", name.Name)
+			codes = append(codes, apperror.ErrorCode(code))
+		}
+	}
+	return codes
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// configFlag is the flag naming the configuration file when WithArgs is used.
+const configFlag = "config"
+
+// ErrMissing is reported for required fields that no source sets.
+var ErrMissing = errors.New("missing value")
+
+// loadConfig holds the settings for Load.
+type loadConfig struct {
+	lookupEnv   func(key string) (string, bool)
+	flagOutput  io.Writer
+	file        string
+	programName string
+	args        []string
+	parseArgs   bool
+}
+
+// Option customizes Load.
+type Option func(*loadConfig)
+
+// WithFile reads the YAML (.yaml, .yml) or JSON (.json) file at path. The
+// --config flag overrides it.
+func WithFile(path string) Option {
+	return func(c *loadConfig) {
+		c.file = path
+	}
+}
+
+// WithArgs parses command line flags from args, usually os.Args[1:]. Besides
+// the flags declared with `flag` tags, --config selects the configuration
+// file.
+func WithArgs(args []string) Option {
+	return func(c *loadConfig) {
+		c.args = args
+		c.parseArgs = true
+	}
+}
+
+// WithProgramName sets the program name shown in the flag usage message. It
+// defaults to os.Args[0].
+func WithProgramName(name string) Option {
+	return func(c *loadConfig) {
+		c.programName = name
+	}
+}
+
+// WithFlagOutput sets where flag usage and parse messages are written. It
+// defaults to os.Stderr.
+func WithFlagOutput(w io.Writer) Option {
+	return func(c *loadConfig) {
+		c.flagOutput = w
+	}
+}
+
+// WithLookupEnv replaces os.LookupEnv, e.g. in tests.
+func WithLookupEnv(lookup func(key string) (string, bool)) Option {
+	return func(c *loadConfig) {
+		c.lookupEnv = lookup
+	}
+}
+
+// Load populates dst, which must be a pointer to a struct, from its `default`
+// tags, the configuration file, environment variables and flags, in this
+// order of increasing precedence.
+//
+// Invalid values and missing required fields of all sources are collected
+// and returned together as a *ValidationError. Errors that prevent loading
+// altogether, such as an unreadable file, are returned as is. When the
+// arguments contain -h or --help, the usage is printed and flag.ErrHelp is
+// returned.
+func Load(dst any, opts ...Option) error {
+	cfg := &loadConfig{
+		lookupEnv:   os.LookupEnv,
+		flagOutput:  os.Stderr,
+		file:        "",
+		programName: os.Args[0],
+		args:        nil,
+		parseArgs:   false,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: destination must be a non-nil pointer to a struct, got %T", dst)
+	}
+
+	fields, err := collectFields(v.Elem(), "", nil, true)
+	if err != nil {
+		return err
+	}
+
+	var flags map[*field]*flagValue
+	if cfg.parseArgs {
+		flags, err = parseFlags(cfg, fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []*FieldError
+	for _, f := range fields {
+		if f.defaultValue != "" {
+			errs = f.apply(errs, "default", f.defaultValue)
+		}
+	}
+
+	if cfg.file != "" {
+		doc, err := readFile(cfg.file)
+		if err != nil {
+			return err
+		}
+		errs = applyFile(errs, doc, fields)
+	}
+
+	for _, f := range fields {
+		if f.env != "" {
+			errs = applyEnv(errs, cfg, f)
+		}
+	}
+
+	for f, fv := range flags {
+		if fv.set {
+			errs = f.apply(errs, "flag -"+f.flag, fv.value)
+		}
+	}
+
+	for _, f := range fields {
+		if f.required && !f.set && !f.invalid {
+			errs = append(errs, &FieldError{
+				Err:    fmt.Errorf("%w: set %s", ErrMissing, f.sources()),
+				Field:  f.path,
+				Source: "",
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		sortFieldErrors(errs, fields)
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// applyEnv sets f from its environment variable. Secret fields may instead
+// name a file holding the value in the NAME_FILE variant, but not both.
+func applyEnv(errs []*FieldError, cfg *loadConfig, f *field) []*FieldError {
+	value, ok := cfg.env(f.env)
+	if !f.secret {
+		if ok {
+			errs = f.apply(errs, "env "+f.env, value)
+		}
+		return errs
+	}
+
+	fileEnv := f.env + fileEnvSuffix
+	path, fileOK := cfg.env(fileEnv)
+	switch {
+	case ok && fileOK:
+		f.invalid = true
+		return append(errs, &FieldError{
+			Err:    fmt.Errorf("set only one of %s and %s", f.env, fileEnv),
+			Field:  f.path,
+			Source: "env " + fileEnv,
+		})
+	case fileOK:
+		return f.apply(errs, "env "+fileEnv, fileRefPrefix+strings.TrimSpace(path))
+	case ok:
+		return f.apply(errs, "env "+f.env, value)
+	default:
+		return errs
+	}
+}
+
+// env looks up the environment variable key, treating blank values as unset.
+func (c *loadConfig) env(key string) (string, bool) {
+	value, ok := c.lookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return value, true
+}
+
+// parseFlags declares a flag for every field with a `flag` tag plus the
+// --config flag, and parses cfg.args. Values are only recorded here and
+// applied later, so their errors are reported with those of other sources.
+func parseFlags(cfg *loadConfig, fields []*field) (map[*field]*flagValue, error) {
+	fs := flag.NewFlagSet(cfg.programName, flag.ContinueOnError)
+	fs.SetOutput(cfg.flagOutput)
+
+	file := fs.String(configFlag, cfg.file, "path of a YAML or JSON configuration file")
+	flags := make(map[*field]*flagValue)
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		if f.flag == configFlag {
+			return nil, fmt.Errorf("config: field %s: flag -%s is reserved", f.path, configFlag)
+		}
+		fv := &flagValue{value: "", isBool: f.isBool(), set: false}
+		fs.Var(fv, f.flag, f.usage())
+		flags[f] = fv
+	}
+
+	if err := fs.Parse(cfg.args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("config: unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	cfg.file = *file
+
+	return flags, nil
+}
+
+// flagValue records the raw value of a flag.
+type flagValue struct {
+	value  string
+	isBool bool
+	set    bool
+}
+
+func (fv *flagValue) String() string {
+	if fv == nil {
+		return ""
+	}
+	return fv.value
+}
+
+func (fv *flagValue) Set(value string) error {
+	fv.value = value
+	fv.set = true
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set with a bare -name.
+func (fv *flagValue) IsBoolFlag() bool {
+	return fv.isBool
+}
@@ -0,0 +1,55 @@
+// Package config loads service configuration into tagged structs from
+// defaults, an optional YAML or JSON file, environment variables and command
+// line flags.
+//
+// Fields are bound with struct tags:
+//
+//   - env:"NAME"      environment variable
+//   - file:"key"      key in the configuration file; on a struct field it names
+//     the nested object holding the struct's fields
+//   - flag:"name"     command line flag, parsed from the arguments passed with
+//     WithArgs
+//   - default:"v"     value used when no source sets the field
+//   - required:"true" the field must be set by at least one source
+//   - usage:"text"    help text of the flag
+//
+// Later sources take precedence over earlier ones:
+//
+//	default < file < env < flag
+//
+// Supported field types are strings (including Secret), booleans, integers,
+// floats, time.Duration, url.URL and *url.URL (which must be absolute), types
+// implementing encoding.TextUnmarshaler, and slices of these. In environment
+// variables, flags and defaults, slices are written as comma separated lists.
+// Nested structs and pointers to structs are traversed; fields without any of
+// the tags above are left alone so they can be derived after loading.
+//
+// Load validates every field and reports all problems at once in a
+// *ValidationError instead of stopping at the first one.
+//
+// Secret and ReloadableSecret fields may be kept out of the environment and
+// the configuration file: a value of the form file://path is replaced by the
+// contents of the file, and for a field bound to env:"NAME" the variable
+// NAME_FILE may name the file instead. A ReloadableSecret re-reads its file
+// when it changes, for credentials that are rotated without a restart.
+//
+// Print and LogValue describe the effective configuration. Values
+// implementing redact.Redactor, such as Secret, are redacted, as are
+// passwords in URLs.
+//
+// Example:
+//
+//	type Config struct {
+//		DatabaseURL Secret        `env:"DATABASE_URL" flag:"database-url" file:"database_url" required:"true"`
+//		Brokers     []string      `env:"KAFKA_BROKER_URLS" file:"brokers" default:"localhost:9092"`
+//		Timeout     time.Duration `env:"TIMEOUT" flag:"timeout" default:"5s"`
+//	}
+//
+//	var cfg Config
+//	if err := config.Load(&cfg, config.WithArgs(os.Args[1:])); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Running the program with `--config=config.yaml` additionally reads the
+// file, and flags such as `--timeout=10s` override everything else.
+package config
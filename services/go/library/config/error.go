@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FieldError reports an invalid or missing value of a single field.
+type FieldError struct {
+	// Err describes the problem.
+	Err error
+	// Field is the Go path of the field, e.g. "Kafka.BrokerURLs".
+	Field string
+	// Source names where the invalid value came from, e.g.
+	// "env KAFKA_BROKER_URLS", "flag -port", "file kafka.brokers" or
+	// "default". It is empty for missing required fields.
+	Source string
+}
+
+func (e *FieldError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("%s: invalid %s: %v", e.Field, e.Source, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every problem found by Load, in field declaration
+// order.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Fields))
+	for _, f := range e.Fields {
+		b.WriteString("\n  - ")
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the field errors, so errors.Is(err, ErrMissing) reports
+// whether a required field is missing.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
+
+// sortFieldErrors orders errs by the declaration order of their fields,
+// keeping the source order for errors of the same field. Errors about unknown
+// file keys come last.
+func sortFieldErrors(errs []*FieldError, fields []*field) {
+	order := make(map[string]int, len(fields))
+	for i, f := range fields {
+		order[f.path] = i
+	}
+	position := func(e *FieldError) int {
+		if i, ok := order[e.Field]; ok {
+			return i
+		}
+		return len(fields)
+	}
+	slices.SortStableFunc(errs, func(a, b *FieldError) int {
+		return position(a) - position(b)
+	})
+}
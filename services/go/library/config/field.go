@@ -0,0 +1,336 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	urlType             = reflect.TypeFor[url.URL]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+var (
+	errEmptyListItem = errors.New("list must contain only non-empty values")
+	errUnknownKey    = errors.New("unknown key")
+)
+
+// field is a leaf field of the configuration struct bound to at least one
+// source.
+type field struct {
+	value        reflect.Value
+	path         string
+	env          string
+	flag         string
+	defaultValue string
+	usageText    string
+	fileKey      []string
+	required     bool
+	// secret records whether the field can also be read from a file named
+	// by the NAME_FILE environment variable.
+	secret bool
+	// set records whether any source, including the default, set the field.
+	set bool
+	// invalid records whether any source had an invalid value, in which case
+	// a missing required value is not reported on top.
+	invalid bool
+}
+
+// collectFields returns the bound leaf fields of struct v. Nil pointers to
+// nested structs are allocated if allocate is set, so their fields can be set,
+// and skipped otherwise.
+func collectFields(v reflect.Value, path string, fileKey []string, allocate bool) ([]*field, error) {
+	var fields []*field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+		fieldPath := sf.Name
+		if path != "" {
+			fieldPath = path + "." + sf.Name
+		}
+		key := fileKey
+		if name := sf.Tag.Get("file"); name != "" {
+			key = append(fileKey[:len(fileKey):len(fileKey)], name)
+		}
+
+		if isNested(sf.Type) {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() && !allocate {
+					continue
+				}
+				if fv.IsNil() {
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			nested, err := collectFields(fv, fieldPath, key, allocate)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+
+		f := &field{
+			value:        fv,
+			path:         fieldPath,
+			env:          sf.Tag.Get("env"),
+			flag:         sf.Tag.Get("flag"),
+			defaultValue: sf.Tag.Get("default"),
+			usageText:    sf.Tag.Get("usage"),
+			fileKey:      nil,
+			required:     sf.Tag.Get("required") == "true",
+			secret:       isSecret(sf.Type),
+			set:          false,
+			invalid:      false,
+		}
+		if sf.Tag.Get("file") != "" {
+			f.fileKey = key
+		}
+		if f.env == "" && f.flag == "" && f.fileKey == nil && f.defaultValue == "" && !f.required {
+			continue
+		}
+		if !isSupported(sf.Type) {
+			return nil, fmt.Errorf("config: field %s: unsupported type %s", fieldPath, sf.Type)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// isNested reports whether t is a struct, or a pointer to one, whose fields
+// are bound individually rather than parsed as a single value.
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && isNestedStruct(t)
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	return t != urlType && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isSupported reports whether values of type t can be parsed from text.
+func isSupported(t reflect.Type) bool {
+	if t == durationType || t == urlType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return t.Elem() == urlType
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && isSupported(t.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isBool reports whether the field is a boolean, which can be set with a
+// bare flag.
+func (f *field) isBool() bool {
+	return f.value.Kind() == reflect.Bool
+}
+
+// apply parses raw into the field, appending a FieldError attributed to
+// source on failure.
+func (f *field) apply(errs []*FieldError, source, raw string) []*FieldError {
+	if err := f.setText(raw); err != nil {
+		f.invalid = true
+		return append(errs, &FieldError{Err: err, Field: f.path, Source: source})
+	}
+	f.set = true
+	return errs
+}
+
+// setText parses raw into the field. Slices are parsed from comma separated
+// lists.
+func (f *field) setText(raw string) error {
+	if f.value.Kind() != reflect.Slice || implementsTextUnmarshaler(f.value) {
+		return setScalar(f.value, strings.TrimSpace(raw))
+	}
+
+	parts := strings.Split(raw, ",")
+	items := make([]string, 0, len(parts))
+	for _, part := range parts {
+		items = append(items, strings.TrimSpace(part))
+	}
+	return f.setList(items)
+}
+
+// setList parses items into the slice field.
+func (f *field) setList(items []string) error {
+	list := reflect.MakeSlice(f.value.Type(), len(items), len(items))
+	for i, item := range items {
+		if item == "" {
+			return errEmptyListItem
+		}
+		if err := setScalar(list.Index(i), item); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	f.value.Set(list)
+	return nil
+}
+
+// sources describes where the field can be set, for missing value errors.
+func (f *field) sources() string {
+	var sources []string
+	if f.env != "" {
+		sources = append(sources, "env "+f.env)
+		if f.secret {
+			sources = append(sources, "env "+f.env+fileEnvSuffix)
+		}
+	}
+	if f.flag != "" {
+		sources = append(sources, "flag -"+f.flag)
+	}
+	if f.fileKey != nil {
+		sources = append(sources, "file key "+strings.Join(f.fileKey, "."))
+	}
+
+	switch len(sources) {
+	case 0:
+		return "a default"
+	case 1:
+		return sources[0]
+	default:
+		return strings.Join(sources[:len(sources)-1], ", ") + " or " + sources[len(sources)-1]
+	}
+}
+
+// usage returns the help text of the field's flag.
+func (f *field) usage() string {
+	usage := f.usageText
+	if usage == "" {
+		usage = f.path
+	}
+	if f.env != "" {
+		usage += " (env " + f.env + ")"
+	}
+	return usage
+}
+
+// name is the name of the field in printed configurations: the environment
+// variable, the file key, the flag or the Go path, whichever is available
+// first.
+func (f *field) name() string {
+	switch {
+	case f.env != "":
+		return f.env
+	case f.fileKey != nil:
+		return strings.Join(f.fileKey, ".")
+	case f.flag != "":
+		return "-" + f.flag
+	default:
+		return f.path
+	}
+}
+
+// implementsTextUnmarshaler reports whether the address of v implements
+// encoding.TextUnmarshaler.
+func implementsTextUnmarshaler(v reflect.Value) bool {
+	return reflect.PointerTo(v.Type()).Implements(textUnmarshalerType)
+}
+
+// setScalar parses raw into v.
+func setScalar(v reflect.Value, raw string) error {
+	if implementsTextUnmarshaler(v) {
+		unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if !ok {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := parseURL(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	case reflect.PointerTo(urlType):
+		u, err := parseURL(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(u))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseURL parses raw as an absolute URL.
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// url.Error repeats the input, which may contain credentials.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("URL %q must be absolute", u.Redacted())
+	}
+	return u, nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes the YAML or JSON configuration file at path into a map.
+// The format is selected by the file extension.
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: read file: %w", err)
+	}
+
+	doc := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = decodeJSON(data, &doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config: unsupported file extension %q, use .yaml, .yml or .json", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: decode %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// decodeJSON decodes data into doc like json.Unmarshal, but keeps numbers as
+// json.Number, so large integers are not turned into floats in exponent
+// notation.
+func decodeJSON(data []byte, doc *map[string]any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(doc); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after top-level value")
+	}
+	return nil
+}
+
+// applyFile sets the fields bound to keys of doc and reports keys that do not
+// belong to any field, which are most likely typos.
+func applyFile(errs []*FieldError, doc map[string]any, fields []*field) []*FieldError {
+	known := make(map[string]bool)
+	for _, f := range fields {
+		if f.fileKey == nil {
+			continue
+		}
+		for i := range f.fileKey {
+			known[strings.Join(f.fileKey[:i+1], ".")] = true
+		}
+
+		value, ok := lookupKey(doc, f.fileKey)
+		if !ok || value == nil {
+			continue
+		}
+		source := "file " + strings.Join(f.fileKey, ".")
+		if err := f.setFileValue(value); err != nil {
+			f.invalid = true
+			errs = append(errs, &FieldError{Err: err, Field: f.path, Source: source})
+			continue
+		}
+		f.set = true
+	}
+
+	for _, key := range unknownKeys(doc, "", known) {
+		errs = append(errs, &FieldError{Err: errUnknownKey, Field: key, Source: "file " + key})
+	}
+	return errs
+}
+
+// setFileValue sets the field from a decoded file value. Lists may be given
+// as sequences or as comma separated strings.
+func (f *field) setFileValue(value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		return fmt.Errorf("expected a value, got an object")
+	case []any:
+		if f.value.Kind() != reflect.Slice || implementsTextUnmarshaler(f.value) {
+			return fmt.Errorf("expected a value, got a list")
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			if _, ok := item.(map[string]any); ok || item == nil {
+				return fmt.Errorf("item %d: expected a value", i)
+			}
+			items[i] = strings.TrimSpace(fileText(item))
+		}
+		return f.setList(items)
+	default:
+		return f.setText(fileText(v))
+	}
+}
+
+// fileText returns the text of a decoded scalar file value. Floats are
+// formatted without exponent, so integer-valued ones parse as integers.
+func fileText(value any) string {
+	if v, ok := value.(float64); ok {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// lookupKey returns the value at the nested key path in doc.
+func lookupKey(doc map[string]any, key []string) (any, bool) {
+	var current any = doc
+	for _, part := range key {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// unknownKeys returns the dotted paths of keys in doc that are not known, in
+// sorted order. Objects below unknown keys are not descended into.
+func unknownKeys(doc map[string]any, prefix string, known map[string]bool) []string {
+	var unknown []string
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if !known[path] {
+			unknown = append(unknown, path)
+			continue
+		}
+		if nested, ok := doc[key].(map[string]any); ok {
+			unknown = slices.Concat(unknown, unknownKeys(nested, path, known))
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"go-services/library/redact"
+)
+
+// Print writes the effective configuration cfg, a struct or a pointer to one,
+// as one NAME=value line per bound field in declaration order. Fields are
+// named by their environment variable, file key, flag or Go path, whichever
+// is available first. Values implementing redact.Redactor are redacted, as
+// are passwords in URLs.
+func Print(w io.Writer, cfg any) error {
+	fields, err := printableFields(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", f.name(), formatValue(f.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogValue returns the effective configuration cfg as a slog group with one
+// attribute per bound field, redacted and named like in Print.
+//
+// Example:
+//
+//	log.InfoContext(ctx, "configuration loaded", "config", config.LogValue(cfg))
+func LogValue(cfg any) slog.Value {
+	fields, err := printableFields(cfg)
+	if err != nil {
+		return slog.StringValue(err.Error())
+	}
+
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.String(f.name(), formatValue(f.value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// printableFields returns the bound fields of cfg without modifying it.
+func printableFields(cfg any) ([]*field, error) {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: cannot print %T, expected a struct", cfg)
+	}
+	return collectFields(v, "", nil, false)
+}
+
+// formatValue formats a field value for printing.
+func formatValue(v reflect.Value) string {
+	if v.CanInterface() {
+		switch value := v.Interface().(type) {
+		case redact.Redactor:
+			return value.Redact()
+		case url.URL:
+			return value.Redacted()
+		case *url.URL:
+			if value == nil {
+				return ""
+			}
+			return value.Redacted()
+		}
+	}
+
+	if v.Kind() == reflect.Slice && !implementsTextUnmarshaler(v) {
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
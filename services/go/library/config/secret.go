@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go-services/library/redact"
+)
+
+const (
+	// redacted replaces secret values in printed configurations.
+	redacted = "<REDACTED>"
+	// fileRefPrefix marks a secret value as a reference to the file holding
+	// it, e.g. file:///run/secrets/kafka-password.
+	fileRefPrefix = "file://"
+	// fileEnvSuffix is appended to the environment variable of a secret field
+	// to name a variable holding the path of the secret file instead, e.g.
+	// KAFKA_PASSWORD_FILE.
+	fileEnvSuffix = "_FILE"
+)
+
+var (
+	secretType           = reflect.TypeFor[Secret]()
+	reloadableSecretType = reflect.TypeFor[ReloadableSecret]()
+)
+
+var errEmptySecretFile = errors.New("secret file is empty")
+
+// Secret is a string setting, such as a password or client secret, that is
+// redacted whenever it is printed or logged. Convert it with string(s) where
+// the value is needed.
+//
+// A value of the form file://path is replaced by the contents of the file
+// when loaded, as is the file named by the NAME_FILE variant of the field's
+// environment variable.
+type Secret string
+
+var (
+	_ redact.Redactor = Secret("")
+	_ slog.LogValuer  = Secret("")
+)
+
+// UnmarshalText implements encoding.TextUnmarshaler and resolves file://
+// references.
+func (s *Secret) UnmarshalText(text []byte) error {
+	path, ok := strings.CutPrefix(string(text), fileRefPrefix)
+	if !ok {
+		*s = Secret(text)
+		return nil
+	}
+
+	value, err := readSecretFile(path)
+	if err != nil {
+		return err
+	}
+	*s = value
+	return nil
+}
+
+// Redact implements redact.Redactor. Empty secrets stay empty, so a missing
+// value remains visible.
+func (s Secret) Redact() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// String returns the redacted value, so formatting a Secret with the fmt
+// package does not leak it.
+func (s Secret) String() string {
+	return s.Redact()
+}
+
+// LogValue implements slog.LogValuer and returns the redacted value.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.Redact())
+}
+
+// ReloadableSecret is a Secret that follows changes of the file it was loaded
+// from, so a rotated mounted secret is picked up without a restart. Values
+// given inline never change.
+//
+// Copies of a ReloadableSecret share the file state and are safe for
+// concurrent use.
+type ReloadableSecret struct {
+	file  *secretFile
+	value Secret
+}
+
+var (
+	_ redact.Redactor = ReloadableSecret{file: nil, value: ""}
+	_ slog.LogValuer  = ReloadableSecret{file: nil, value: ""}
+)
+
+// NewReloadableSecret returns a ReloadableSecret for value, which is either
+// the secret itself or a file:// reference. It is mostly useful in tests;
+// Load creates ReloadableSecret fields from their sources.
+func NewReloadableSecret(value string) (ReloadableSecret, error) {
+	var s ReloadableSecret
+	if err := s.UnmarshalText([]byte(value)); err != nil {
+		return ReloadableSecret{file: nil, value: ""}, err
+	}
+	return s, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. A file:// reference is
+// read immediately, so a missing or empty file is reported by Load.
+func (s *ReloadableSecret) UnmarshalText(text []byte) error {
+	path, ok := strings.CutPrefix(string(text), fileRefPrefix)
+	if !ok {
+		*s = ReloadableSecret{file: nil, value: Secret(text)}
+		return nil
+	}
+
+	f := &secretFile{modTime: time.Time{}, path: path, value: "", mu: sync.Mutex{}, size: 0}
+	if _, err := f.current(); err != nil {
+		return err
+	}
+	*s = ReloadableSecret{file: f, value: ""}
+	return nil
+}
+
+// Value returns the current secret. File based secrets are re-read when the
+// modification time or size of the file changed since the last read. If
+// re-reading fails, Value returns the last value read along with the error,
+// leaving it to the caller whether to keep using it.
+func (s ReloadableSecret) Value() (Secret, error) {
+	if s.file == nil {
+		return s.value, nil
+	}
+	return s.file.current()
+}
+
+// Func returns a function reading the current value of s, e.g. for client
+// options that look up a credential per connection. When a rotated secret
+// file cannot be read, the last value read is used and the failure is logged
+// with name; the function fails only if no value was read at all.
+func (s ReloadableSecret) Func(log *slog.Logger, name string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		value, err := s.Value()
+		if err != nil {
+			if value == "" {
+				return "", fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			log.WarnContext(ctx, "failed to reload secret, using last value", "secret", name, "err", err)
+		}
+		return string(value), nil
+	}
+}
+
+// Equal reports whether s and other hold the same inline value or follow the
+// same loaded file.
+func (s ReloadableSecret) Equal(other ReloadableSecret) bool {
+	return s.file == other.file && s.value == other.value
+}
+
+// Redact implements redact.Redactor. File based secrets are shown as their
+// file:// reference, which is not secret; other secrets are redacted like
+// Secret.
+func (s ReloadableSecret) Redact() string {
+	if s.file != nil {
+		return fileRefPrefix + s.file.path
+	}
+	return s.value.Redact()
+}
+
+// String returns the redacted value.
+func (s ReloadableSecret) String() string {
+	return s.Redact()
+}
+
+// LogValue implements slog.LogValuer and returns the redacted value.
+func (s ReloadableSecret) LogValue() slog.Value {
+	return slog.StringValue(s.Redact())
+}
+
+// secretFile caches the contents of a secret file.
+type secretFile struct {
+	modTime time.Time
+	path    string
+	value   Secret
+	mu      sync.Mutex
+	size    int64
+}
+
+// current returns the contents of the file, reading it again if it changed.
+func (f *secretFile) current() (Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.value, fmt.Errorf("read secret file: %w", err)
+	}
+	if !f.modTime.IsZero() && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.value, nil
+	}
+
+	value, err := readSecretFile(f.path)
+	if err != nil {
+		return f.value, err
+	}
+	f.value, f.modTime, f.size = value, info.ModTime(), info.Size()
+	return value, nil
+}
+
+// readSecretFile returns the contents of the file at path without trailing
+// line breaks. Errors name the file but never include its contents.
+func readSecretFile(path string) (Secret, error) {
+	if path == "" {
+		return "", errors.New("secret file reference has no path")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	value := strings.TrimRight(string(b), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%w: %s", errEmptySecretFile, path)
+	}
+	return Secret(value), nil
+}
+
+// isSecret reports whether t can be read from a secret file.
+func isSecret(t reflect.Type) bool {
+	return t == secretType || t == reloadableSecretType
+}
@@ -0,0 +1,163 @@
+package config_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"go-services/library/assert"
+	"go-services/library/config"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+func TestLoadSecretFile(t *testing.T) {
+	secretPath := writeFile(t, "kafka-password", "from-file\n")
+
+	tests := map[string]struct {
+		env     map[string]string
+		file    string
+		wantErr string
+		want    config.Secret
+	}{
+		"file env variant": {
+			env:     map[string]string{"KAFKA_PASSWORD_FILE": secretPath},
+			file:    "",
+			wantErr: "",
+			want:    "from-file",
+		},
+		"file reference in env": {
+			env:     map[string]string{"KAFKA_PASSWORD": "file://" + secretPath},
+			file:    "",
+			wantErr: "",
+			want:    "from-file",
+		},
+		"file reference in config file": {
+			env:     map[string]string{},
+			file:    "kafka:\n  password: file://" + secretPath + "\n",
+			wantErr: "",
+			want:    "from-file",
+		},
+		"file env variant overrides config file": {
+			env:     map[string]string{"KAFKA_PASSWORD_FILE": secretPath},
+			file:    "kafka:\n  password: inline\n",
+			wantErr: "",
+			want:    "from-file",
+		},
+		"both env variants": {
+			env:     map[string]string{"KAFKA_PASSWORD": "inline", "KAFKA_PASSWORD_FILE": secretPath},
+			file:    "",
+			wantErr: "Kafka.Password: invalid env KAFKA_PASSWORD_FILE: set only one of KAFKA_PASSWORD and KAFKA_PASSWORD_FILE",
+			want:    "",
+		},
+		"missing file": {
+			env:     map[string]string{"KAFKA_PASSWORD_FILE": secretPath + ".missing"},
+			file:    "",
+			wantErr: "Kafka.Password: invalid env KAFKA_PASSWORD_FILE: read secret file: open " + secretPath + ".missing",
+			want:    "",
+		},
+		"empty file": {
+			env:     map[string]string{"KAFKA_PASSWORD": "file://" + writeFile(t, "empty", "\n")},
+			file:    "",
+			wantErr: "secret file is empty",
+			want:    "",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vars := map[string]string{"DATABASE_URL": "postgres://localhost/db", "KAFKA_BROKER_URLS": "b", "PORT": "1"}
+			for k, v := range tt.env {
+				vars[k] = v
+			}
+			opts := []config.Option{env(vars)}
+			if tt.file != "" {
+				opts = append(opts, config.WithFile(writeFile(t, "config.yaml", tt.file)))
+			}
+
+			var cfg settings
+			err := config.Load(&cfg, opts...)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr, "load error")
+				return
+			}
+			require.NoError(t, err, "load config")
+			assert.Equal(t, cfg.Kafka.Password, tt.want, "password")
+		})
+	}
+}
+
+func TestLoadMissingSecretListsFileVariant(t *testing.T) {
+	var cfg struct {
+		Password config.Secret `env:"PASSWORD" required:"true"`
+	}
+	err := config.Load(&cfg, env(nil))
+
+	assert.ErrorContains(t, err, "missing value: set env PASSWORD or env PASSWORD_FILE", "missing secret")
+}
+
+func TestReloadableSecret(t *testing.T) {
+	path := writeFile(t, "client-secret", "v1\n")
+
+	secret, err := config.NewReloadableSecret("file://" + path)
+	require.NoError(t, err, "new reloadable secret")
+
+	value, err := secret.Value()
+	require.NoError(t, err, "initial value")
+	assert.Equal(t, value, config.Secret("v1"), "initial value")
+
+	require.NoError(t, os.WriteFile(path, []byte("v2\n"), 0o600), "rotate secret")
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime), "touch secret")
+
+	value, err = secret.Value()
+	require.NoError(t, err, "rotated value")
+	assert.Equal(t, value, config.Secret("v2"), "rotated value")
+
+	require.NoError(t, os.Remove(path), "remove secret")
+	value, err = secret.Value()
+	assert.Error(t, err, "missing file")
+	assert.Equal(t, value, config.Secret("v2"), "last value kept")
+
+	assert.Equal(t, secret.Redact(), "file://"+path, "file reference shown")
+}
+
+func TestReloadableSecretFunc(t *testing.T) {
+	path := writeFile(t, "kafka-password", "v1\n")
+	secret, err := config.NewReloadableSecret("file://" + path)
+	require.NoError(t, err, "new reloadable secret")
+
+	log, capture := testlogger.New()
+	password := secret.Func(log, "kafka password")
+
+	value, err := password(t.Context())
+	require.NoError(t, err, "initial value")
+	assert.Equal(t, value, "v1", "initial value")
+
+	require.NoError(t, os.Remove(path), "remove secret")
+	value, err = password(t.Context())
+	require.NoError(t, err, "value after failed reload")
+	assert.Equal(t, value, "v1", "last value kept")
+
+	testlogger.Assert(t, capture.GetOutput()).
+		Count(1, "log entries").
+		AtIndex(0, slog.LevelWarn, "failed to reload secret, using last value", "reload warning").
+		HasField(0, "secret", "kafka password", "secret name")
+}
+
+func TestReloadableSecretInline(t *testing.T) {
+	var cfg struct {
+		Password config.ReloadableSecret `env:"PASSWORD" required:"true"`
+	}
+	require.NoError(t, config.Load(&cfg, env(map[string]string{"PASSWORD": "inline"})), "load config")
+
+	value, err := cfg.Password.Value()
+	require.NoError(t, err, "value")
+	assert.Equal(t, value, config.Secret("inline"), "value")
+
+	var b bytes.Buffer
+	require.NoError(t, config.Print(&b, cfg), "print config")
+	assert.Equal(t, b.String(), "PASSWORD=<REDACTED>\n", "printed config")
+}
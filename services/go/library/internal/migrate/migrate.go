@@ -0,0 +1,40 @@
+// Package migrate applies the goose migrations that library packages ship
+// for their own tables.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// Apply applies the SQL migrations of fsys to the database of pool. Applied
+// versions are tracked in tableName, which keeps them apart from the
+// migrations of the service and of other packages.
+func Apply(ctx context.Context, pool *pgxpool.Pool, fsys fs.FS, tableName string) (err error) {
+	db := stdlib.OpenDBFromPool(pool)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close db connection: %w", closeErr)
+		}
+	}()
+
+	provider, err := goose.NewProvider(
+		goose.DialectPostgres,
+		db,
+		fsys,
+		goose.WithTableName(tableName),
+		goose.WithDisableGlobalRegistry(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+	if _, err := provider.Up(ctx); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
@@ -5,8 +5,10 @@ type AckMode int
 
 const (
 	// AckModeAtLeastOnce ensures records are processed at least once.
-	// Records are committed after the handler finishes successfully.
-	// If the handler fails, the record might be processed again.
+	// A record is committed once it and every earlier record of its
+	// partition were handled successfully or forwarded to a retry or
+	// dead-letter topic. If the handler fails, the record and the ones
+	// after it are processed again after a restart or rebalance.
 	AckModeAtLeastOnce AckMode = iota
 
 	// AckModeAtMostOnce ensures records are processed at most once.
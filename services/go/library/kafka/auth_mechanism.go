@@ -1,5 +1,14 @@
 package kafka
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
 // AuthMechanism represents the SASL authentication mechanism.
 type AuthMechanism string
 
@@ -11,3 +20,40 @@ const (
 	// AuthMechanismScram512 uses SASL/SCRAM-SHA-512 authentication.
 	AuthMechanismScram512 AuthMechanism = "SCRAM-SHA-512"
 )
+
+// saslMechanism returns the franz-go SASL mechanism for a. Credentials are
+// resolved on every authentication, either from a.Provider or from the
+// static Username and Password.
+func (a *AuthConfig) saslMechanism() (sasl.Mechanism, error) {
+	provider := a.Provider
+	if provider == nil {
+		provider = func(context.Context) (string, string, error) {
+			return a.Username, a.Password, nil
+		}
+	}
+
+	scramAuth := func(ctx context.Context) (scram.Auth, error) {
+		user, pass, err := provider(ctx)
+		if err != nil {
+			return scram.Auth{}, fmt.Errorf("failed to get kafka credentials: %w", err)
+		}
+		return scram.Auth{User: user, Pass: pass}, nil
+	}
+
+	switch a.Mechanism {
+	case AuthMechanismPlain:
+		return plain.Plain(func(ctx context.Context) (plain.Auth, error) {
+			user, pass, err := provider(ctx)
+			if err != nil {
+				return plain.Auth{}, fmt.Errorf("failed to get kafka credentials: %w", err)
+			}
+			return plain.Auth{User: user, Pass: pass}, nil
+		}), nil
+	case AuthMechanismScram256:
+		return scram.Sha256(scramAuth), nil
+	case AuthMechanismScram512:
+		return scram.Sha512(scramAuth), nil
+	default:
+		return nil, fmt.Errorf("unsupported auth mechanism: %s", a.Mechanism)
+	}
+}
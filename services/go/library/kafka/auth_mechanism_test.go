@@ -0,0 +1,76 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+func TestSASLMechanism(t *testing.T) {
+	tests := map[string]struct {
+		mechanism AuthMechanism
+		wantName  string
+	}{
+		"plain":    {mechanism: AuthMechanismPlain, wantName: "PLAIN"},
+		"scram256": {mechanism: AuthMechanismScram256, wantName: "SCRAM-SHA-256"},
+		"scram512": {mechanism: AuthMechanismScram512, wantName: "SCRAM-SHA-512"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			auth := &AuthConfig{Username: "user", Password: "pass", Mechanism: tt.mechanism, Provider: nil}
+			m, err := auth.saslMechanism()
+			require.NoError(t, err, "sasl mechanism")
+			assert.Equal(t, m.Name(), tt.wantName, "mechanism name")
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		auth := &AuthConfig{Username: "user", Password: "pass", Mechanism: "GSSAPI", Provider: nil}
+		_, err := auth.saslMechanism()
+		assert.ErrorContains(t, err, "unsupported auth mechanism: GSSAPI", "unsupported mechanism")
+	})
+}
+
+func TestSASLMechanismProviderCalledPerAuthentication(t *testing.T) {
+	password := "first"
+	auth := &AuthConfig{
+		Username:  "",
+		Password:  "",
+		Mechanism: AuthMechanismPlain,
+		Provider: func(context.Context) (string, string, error) {
+			return "user", password, nil
+		},
+	}
+	m, err := auth.saslMechanism()
+	require.NoError(t, err, "sasl mechanism")
+
+	_, msg, err := m.Authenticate(t.Context(), "broker:9092")
+	require.NoError(t, err, "first authentication")
+	assert.Equal(t, string(msg), "\x00user\x00first", "first credentials")
+
+	password = "rotated"
+	_, msg, err = m.Authenticate(t.Context(), "broker:9092")
+	require.NoError(t, err, "second authentication")
+	assert.Equal(t, string(msg), "\x00user\x00rotated", "rotated credentials")
+}
+
+func TestSASLMechanismProviderError(t *testing.T) {
+	errUnavailable := errors.New("secret unavailable")
+	auth := &AuthConfig{
+		Username:  "",
+		Password:  "",
+		Mechanism: AuthMechanismScram512,
+		Provider: func(context.Context) (string, string, error) {
+			return "", "", errUnavailable
+		},
+	}
+	m, err := auth.saslMechanism()
+	require.NoError(t, err, "sasl mechanism")
+
+	_, _, err = m.Authenticate(t.Context(), "broker:9092")
+	assert.ErrorIs(t, err, errUnavailable, "provider error")
+}
@@ -0,0 +1,89 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	// defaultBatchSize is the default of WithBatchSize.
+	defaultBatchSize = 100
+	// defaultBatchLinger is the default of WithBatchLinger.
+	defaultBatchLinger = 100 * time.Millisecond
+)
+
+// BatchHandler is the function that processes a batch of Kafka records of a
+// single partition, in offset order, e.g. to write them to the database in
+// one transaction.
+//
+// The batch succeeds or fails as a whole: an error retries the whole batch
+// according to the retry policy, and once retries are exhausted every record
+// of the batch is forwarded to the retry or dead-letter topic, or none of
+// them is committed. Errors wrapped with Skip commit the whole batch. A
+// handler that must not fail the batch for a single record should
+// dead-letter or skip that record itself.
+//
+// The middleware of WithHandlerMiddleware does not apply to batch handlers,
+// but a panicking batch handler is recovered like with Recover: the panic is
+// logged and the batch fails with a permanent error matching ErrPanic.
+type BatchHandler func(ctx context.Context, records []*kgo.Record) error
+
+// AddBatchTopic registers a batch handler for a new topic and updates the
+// underlying client to start consuming the topic immediately, like AddTopic.
+func (c *Consumer) AddBatchTopic(topic string, handler BatchHandler) error {
+	return c.registerBatchTopic(topic, handler, true)
+}
+
+// registerBatchTopic stores a topic batch handler in the consumer router,
+// like registerTopic.
+func (c *Consumer) registerBatchTopic(topic string, handler BatchHandler, subscribe bool) error {
+	if handler == nil {
+		return fmt.Errorf("handler must not be nil")
+	}
+
+	return c.register(topic, subscribe, func(t string) { c.batchRouter[t] = handler })
+}
+
+func (c *Consumer) batchHandlerForTopic(topic string) (BatchHandler, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	handler, ok := c.batchRouter[topic]
+	return handler, ok
+}
+
+// handleBatch handles records, a batch of a single partition, and reports
+// whether they may be committed, like handleRecord.
+func (c *Consumer) handleBatch(ctx context.Context, records []*kgo.Record) bool {
+	handler, ok := c.batchHandlerForTopic(records[0].Topic)
+	if !ok {
+		c.log.ErrorContext(ctx, "failed to map topic to batch handler", "topic", records[0].Topic)
+		return false
+	}
+
+	attempts, err := c.attempt(ctx, records, func(ctx context.Context) error {
+		return c.callBatch(ctx, handler, records)
+	})
+	return c.settle(ctx, records, attempts, err)
+}
+
+// callBatch calls handler with records, turning a panic into a permanent
+// error matching ErrPanic, so the batch is settled like any failed batch.
+func (c *Consumer) callBatch(ctx context.Context, handler BatchHandler, records []*kgo.Record) (err error) {
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+
+		attrs := append(coordinates(records), "err", fmt.Sprint(rec), "stack", string(debug.Stack()))
+		c.log.ErrorContext(ctx, "Kafka batch handler panicked", attrs...)
+		err = Permanent(fmt.Errorf("%w: %v", ErrPanic, rec))
+	}()
+
+	return handler(ctx, records)
+}
@@ -0,0 +1,237 @@
+package kafka
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+// newBatchDispatcher returns a dispatcher passing the offsets of every batch
+// to batches and failing those containing offset fail.
+func newBatchDispatcher(ordering Ordering, batches chan<- []int64, fail int64) *dispatcher {
+	d := newDispatcher(newFakePauser(), func(context.Context, *kgo.Record) bool { return true }, 2, ordering, 100)
+	d.handleBatch = func(_ context.Context, records []*kgo.Record) bool {
+		offsets := offsetsOf(records)
+		batches <- offsets
+		for _, offset := range offsets {
+			if offset == fail {
+				return false
+			}
+		}
+		return true
+	}
+	return d
+}
+
+func TestDispatcherCollect(t *testing.T) {
+	tests := map[string]Ordering{
+		"none":      OrderingNone,
+		"partition": OrderingPartition,
+		"key":       OrderingKey,
+	}
+
+	for name, ordering := range tests {
+		t.Run(name, func(t *testing.T) {
+			batches := make(chan []int64, 4)
+			d := newBatchDispatcher(ordering, batches, -1)
+			now := time.Now()
+
+			for offset := range int64(5) {
+				record := &kgo.Record{Topic: "orders", Partition: 0, Offset: offset}
+				require.True(t, d.collect(t.Context(), record, 2, time.Second, now), "collect offset %d", offset)
+			}
+			// Without ordering, the full batches may be handled in parallel.
+			full := [][]int64{<-batches, <-batches}
+			slices.SortFunc(full, func(a, b []int64) int { return cmp.Compare(a[0], b[0]) })
+			assert.Equal(t, full, [][]int64{{0, 1}, {2, 3}}, "full batches")
+
+			due, ok := d.nextFlush()
+			require.True(t, ok, "batch being collected")
+			assert.Equal(t, due, now.Add(time.Second), "due after linger")
+
+			require.True(t, d.flush(t.Context(), now), "flush before due")
+			d.wait()
+			assert.Equal(t, offsetsOf(d.committable()), []int64{3}, "collected record is not committable")
+
+			require.True(t, d.flush(t.Context(), now.Add(time.Second)), "flush when due")
+			assert.Equal(t, <-batches, []int64{4}, "lingered batch")
+			d.wait()
+			assert.Equal(t, offsetsOf(d.committable()), []int64{4}, "committable after the lingered batch")
+			_, ok = d.nextFlush()
+			assert.False(t, ok, "no batch being collected")
+		})
+	}
+}
+
+func TestDispatcherFailedBatch(t *testing.T) {
+	batches := make(chan []int64, 4)
+	d := newBatchDispatcher(OrderingPartition, batches, 3)
+
+	for offset := range int64(6) {
+		record := &kgo.Record{Topic: "orders", Partition: 0, Offset: offset}
+		require.True(t, d.collect(t.Context(), record, 2, 0, time.Now()), "collect offset %d", offset)
+	}
+	d.wait()
+
+	assert.Equal(t, offsetsOf(d.committable()), []int64{1}, "prefix before the failed batch")
+}
+
+func TestDispatcherRevokeDropsBatches(t *testing.T) {
+	batches := make(chan []int64, 4)
+	d := newBatchDispatcher(OrderingPartition, batches, -1)
+	now := time.Now()
+
+	for partition := range int32(2) {
+		record := &kgo.Record{Topic: "orders", Partition: partition, Offset: 0}
+		require.True(t, d.collect(t.Context(), record, 10, 0, now), "collect partition %d", partition)
+	}
+	d.revoke(map[string][]int32{"orders": {0}})
+	require.NoError(t, d.drain(t.Context(), map[string][]int32{"orders": {0}}), "drain revoked partition")
+
+	require.True(t, d.flush(t.Context(), now), "flush")
+	d.wait()
+	close(batches)
+
+	var got [][]int64
+	for batch := range batches {
+		got = append(got, batch)
+	}
+	assert.Equal(t, got, [][]int64{{0}}, "only the batch of the assigned partition is handled")
+	committable := d.committable()
+	require.SliceLen(t, committable, 1, "committable records")
+	assert.Equal(t, committable[0].Partition, int32(1), "committable partition")
+}
+
+func TestConsumerHandleBatch(t *testing.T) {
+	errUnavailable := errors.New("database unavailable")
+
+	tests := map[string]struct {
+		errs      []error
+		wantCalls int
+		wantOK    bool
+	}{
+		"handled": {
+			errs:      nil,
+			wantCalls: 1,
+			wantOK:    true,
+		},
+		"retried": {
+			errs:      []error{errUnavailable},
+			wantCalls: 2,
+			wantOK:    true,
+		},
+		"retries exhausted": {
+			errs:      []error{errUnavailable, errUnavailable, errUnavailable},
+			wantCalls: 3,
+			wantOK:    true,
+		},
+		"permanent": {
+			errs:      []error{Permanent(errUnavailable)},
+			wantCalls: 1,
+			wantOK:    true,
+		},
+		"skipped": {
+			errs:      []error{Skip(errUnavailable)},
+			wantCalls: 1,
+			wantOK:    true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig([]string{"broker:9092"}, "group")
+			cfg.retry = RetryPolicy{
+				IsRetryable:    nil,
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+				Multiplier:     2,
+				Jitter:         0,
+			}
+			consumer, err := newConsumer(cfg, nil)
+			require.NoError(t, err, "failed to create consumer")
+
+			var mu sync.Mutex
+			var calls []int
+			err = consumer.AddBatchTopic("orders", func(ctx context.Context, records []*kgo.Record) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, len(records))
+				if len(calls) <= len(tt.errs) {
+					return tt.errs[len(calls)-1]
+				}
+				return nil
+			})
+			require.NoError(t, err, "failed to add batch topic")
+
+			records := []*kgo.Record{
+				{Topic: "orders", Partition: 0, Offset: 0},
+				{Topic: "orders", Partition: 0, Offset: 1},
+			}
+			ok := consumer.handleBatch(t.Context(), records)
+
+			assert.Equal(t, ok, tt.wantOK, "batch may be committed")
+			assert.SliceLen(t, calls, tt.wantCalls, "handler calls")
+			assert.SliceNotContains(t, calls, 1, "every call gets the whole batch")
+		})
+	}
+}
+
+func TestConsumerHandleBatchRecoversPanic(t *testing.T) {
+	log, capture := testlogger.New()
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	WithLogger(log)(cfg)
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+
+	calls := 0
+	err = consumer.AddBatchTopic("orders", func(context.Context, []*kgo.Record) error {
+		calls++
+		panic("nil map")
+	})
+	require.NoError(t, err, "failed to add batch topic")
+
+	records := []*kgo.Record{
+		{Topic: "orders", Partition: 0, Offset: 0},
+		{Topic: "orders", Partition: 0, Offset: 1},
+	}
+	ok := consumer.handleBatch(t.Context(), records)
+
+	assert.True(t, ok, "panicked batch is dropped without a dead-letter topic")
+	assert.Equal(t, calls, 1, "panics are not retried")
+	testlogger.Assert(t, capture.GetOutput()).
+		AtIndex(0, slog.LevelError, "Kafka batch handler panicked", "panic log").
+		HasField(0, "records", int64(2), "panic log batch size").
+		AtIndex(2, slog.LevelError, "Record dropped, no dead-letter topic is configured", "drop log")
+}
+
+func TestConsumerBatchTopicRegistration(t *testing.T) {
+	handler := func(context.Context, *kgo.Record) error { return nil }
+	batchHandler := func(context.Context, []*kgo.Record) error { return nil }
+
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	WithBatchTopic("batched", batchHandler)(cfg)
+	assert.Panics(t, func() { WithTopic("batched", handler)(cfg) }, "startup topic registered twice")
+	assert.Equal(t, cfg.consumeTopics(), []string{"batched"}, "consumed topics")
+
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+	_, ok := consumer.batchHandlerForTopic("batched")
+	assert.True(t, ok, "startup batch topic should be registered")
+
+	require.NoError(t, consumer.AddTopic("single", handler), "failed to add topic")
+	assert.ErrorContains(t, consumer.AddBatchTopic("single", batchHandler), `topic handler already registered for "single"`, "batch handler for a single topic")
+	assert.ErrorContains(t, consumer.AddTopic("batched", handler), `topic handler already registered for "batched"`, "handler for a batch topic")
+	assert.ErrorContains(t, consumer.AddBatchTopic("other", nil), "handler must not be nil", "nil batch handler")
+}
@@ -1,15 +1,12 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
-	"maps"
-	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/twmb/franz-go/pkg/kgo"
-	"github.com/twmb/franz-go/pkg/sasl"
-	"github.com/twmb/franz-go/pkg/sasl/plain"
-	"github.com/twmb/franz-go/pkg/sasl/scram"
 )
 
 // Client owns the shared franz-go client and exposes the consumer and producer
@@ -18,6 +15,9 @@ type Client struct {
 	Consumer  *Consumer
 	Producer  *Producer
 	kgoClient *kgo.Client
+	// session manages the transactions of the transactional mode. It wraps
+	// kgoClient and is nil otherwise.
+	session   *kgo.GroupTransactSession
 	closeOnce sync.Once
 	closed    bool
 	mu        sync.RWMutex
@@ -57,6 +57,10 @@ func (c *Client) isClosed() bool {
 //   - SASL Authentication (Plain, SCRAM-256, SCRAM-512)
 //   - Topic routing based on registered handlers
 //   - Offset management strategy (e.g., disabling auto-commit for AtLeastOnce mode)
+//   - Group rebalances with the cooperative-sticky balancer, draining and
+//     committing revoked partitions
+//   - Transactions, with WithTransactionalID, that commit the records a
+//     handler produces together with the offsets of the records it consumed
 //
 // Both Client.Consumer and Client.Producer share the same underlying TCP connections
 // to the Kafka brokers, which is more resource-efficient than creating separate clients.
@@ -70,8 +74,11 @@ func New(brokers []string, groupId string, opts ...Option) (*Client, error) {
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	if err := cfg.validateTransactional(); err != nil {
+		return nil, err
+	}
 
-	topics := slices.Collect(maps.Keys(cfg.topicRouter))
+	topics := cfg.consumeTopics()
 	kgoOpts := []kgo.Opt{
 		kgo.SeedBrokers(cfg.brokers...),
 		kgo.ConsumerGroup(cfg.groupId),
@@ -81,25 +88,9 @@ func New(brokers []string, groupId string, opts ...Option) (*Client, error) {
 	}
 
 	if cfg.auth != nil {
-		var m sasl.Mechanism
-		switch cfg.auth.Mechanism {
-		case AuthMechanismPlain:
-			m = plain.Auth{
-				User: cfg.auth.Username,
-				Pass: cfg.auth.Password,
-			}.AsMechanism()
-		case AuthMechanismScram256:
-			m = scram.Auth{
-				User: cfg.auth.Username,
-				Pass: cfg.auth.Password,
-			}.AsSha256Mechanism()
-		case AuthMechanismScram512:
-			m = scram.Auth{
-				User: cfg.auth.Username,
-				Pass: cfg.auth.Password,
-			}.AsSha512Mechanism()
-		default:
-			return nil, fmt.Errorf("unsupported auth mechanism: %s", cfg.auth.Mechanism)
+		m, err := cfg.auth.saslMechanism()
+		if err != nil {
+			return nil, err
 		}
 		kgoOpts = append(kgoOpts, kgo.SASL(m))
 	}
@@ -107,10 +98,40 @@ func New(brokers []string, groupId string, opts ...Option) (*Client, error) {
 	if cfg.ackMode == AckModeAtLeastOnce {
 		kgoOpts = append(kgoOpts, kgo.DisableAutoCommit())
 	}
+	if cfg.transactional() {
+		kgoOpts = append(kgoOpts, cfg.transactionalOpts()...)
+	}
+
+	// The client may join the group before the consumer is created below;
+	// until then, there is nothing to drain or commit.
+	var hookConsumer atomic.Pointer[Consumer]
+	hook := func(on func(*Consumer, context.Context, *kgo.Client, map[string][]int32)) func(context.Context, *kgo.Client, map[string][]int32) {
+		return func(ctx context.Context, cl *kgo.Client, partitions map[string][]int32) {
+			if c := hookConsumer.Load(); c != nil {
+				on(c, ctx, cl, partitions)
+			}
+		}
+	}
+	kgoOpts = append(kgoOpts,
+		kgo.Balancers(kgo.CooperativeStickyBalancer()),
+		kgo.OnPartitionsAssigned(hook((*Consumer).onPartitionsAssigned)),
+		kgo.OnPartitionsRevoked(hook((*Consumer).onPartitionsRevoked)),
+		kgo.OnPartitionsLost(hook((*Consumer).onPartitionsLost)),
+	)
 
 	kgoOpts = append(kgoOpts, cfg.kgoOpts...)
 
-	kgoClient, err := kgo.NewClient(kgoOpts...)
+	var session *kgo.GroupTransactSession
+	var kgoClient *kgo.Client
+	var err error
+	if cfg.transactional() {
+		session, err = kgo.NewGroupTransactSession(kgoOpts...)
+		if err == nil {
+			kgoClient = session.Client()
+		}
+	} else {
+		kgoClient, err = kgo.NewClient(kgoOpts...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kgo client: %w", err)
 	}
@@ -119,6 +140,7 @@ func New(brokers []string, groupId string, opts ...Option) (*Client, error) {
 		Consumer:  nil,
 		Producer:  nil,
 		kgoClient: kgoClient,
+		session:   session,
 		closeOnce: sync.Once{},
 		closed:    false,
 		mu:        sync.RWMutex{},
@@ -132,6 +154,7 @@ func New(brokers []string, groupId string, opts ...Option) (*Client, error) {
 	producer := newProducer(cfg, client)
 
 	client.Consumer = consumer
+	hookConsumer.Store(consumer)
 	client.Producer = producer
 
 	return client, nil
@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes values of type T into record values and decodes them back.
+type Codec[T any] interface {
+	// Encode returns the record value for v.
+	Encode(v T) ([]byte, error)
+	// Decode returns the value encoded in data.
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec encodes values as JSON with encoding/json.
+type JSONCodec[T any] struct{}
+
+var _ Codec[struct{}] = JSONCodec[struct{}]{}
+
+// Encode implements Codec.
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode json: %w", err)
+	}
+	return data, nil
+}
+
+// Decode implements Codec.
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("decode json: %w", err)
+	}
+	return v, nil
+}
+
+// ProtoCodec encodes generated Protobuf messages, such as *userpb.Event, in
+// the Protobuf wire format.
+type ProtoCodec[T proto.Message] struct{}
+
+// Encode implements Codec.
+func (ProtoCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := proto.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode protobuf: %w", err)
+	}
+	return data, nil
+}
+
+// Decode implements Codec.
+func (ProtoCodec[T]) Decode(data []byte) (T, error) {
+	var zero T
+	// Generated messages report their type even through a nil pointer.
+	v, ok := zero.ProtoReflect().New().Interface().(T)
+	if !ok {
+		return zero, fmt.Errorf("decode protobuf: unexpected message type %T", v)
+	}
+	if err := proto.Unmarshal(data, v); err != nil {
+		return zero, fmt.Errorf("decode protobuf: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+type testEvent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := JSONCodec[testEvent]{}
+
+	data, err := codec.Encode(testEvent{ID: "1", Name: "Ada"})
+	require.NoError(t, err, "failed to encode")
+	assert.Equal(t, string(data), `{"id":"1","name":"Ada"}`, "encoded value")
+
+	got, err := codec.Decode(data)
+	require.NoError(t, err, "failed to decode")
+	assert.Equal(t, got, testEvent{ID: "1", Name: "Ada"}, "decoded value")
+
+	_, err = codec.Decode([]byte(`{`))
+	assert.ErrorContains(t, err, "decode json", "invalid json")
+}
+
+func TestProtoCodec(t *testing.T) {
+	codec := ProtoCodec[*wrapperspb.StringValue]{}
+
+	data, err := codec.Encode(wrapperspb.String("hello"))
+	require.NoError(t, err, "failed to encode")
+
+	got, err := codec.Decode(data)
+	require.NoError(t, err, "failed to decode")
+	assert.True(t, proto.Equal(got, wrapperspb.String("hello")), "decoded value %v", got)
+
+	_, err = codec.Decode([]byte{0xff})
+	assert.ErrorContains(t, err, "decode protobuf", "invalid protobuf")
+}
@@ -2,13 +2,23 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
+const (
+	// commitInterval is how often handled records are committed.
+	commitInterval = time.Second
+	// finalCommitTimeout bounds the commit of the last handled records when
+	// the consumer stops.
+	finalCommitTimeout = 10 * time.Second
+)
+
 // Handler is the function that processes a single Kafka record.
 type Handler func(ctx context.Context, record *kgo.Record) error
 
@@ -16,10 +26,15 @@ type Handler func(ctx context.Context, record *kgo.Record) error
 // It manages the consumption loop and parallel processing of records.
 type Consumer struct {
 	topicRouter map[string]Handler
+	batchRouter map[string]BatchHandler
+	retryTiers  map[string]retryTier
 	client      *Client
 	cfg         *config
 	log         *slog.Logger
+	dispatcher  *dispatcher
+	assignment  map[topicPartition]bool
 	mu          sync.RWMutex
+	commitMu    sync.Mutex
 }
 
 // newConsumer creates a new Kafka consumer.
@@ -31,10 +46,15 @@ type Consumer struct {
 func newConsumer(cfg *config, client *Client) (*Consumer, error) {
 	consumer := &Consumer{
 		topicRouter: make(map[string]Handler, len(cfg.topicRouter)),
+		batchRouter: make(map[string]BatchHandler, len(cfg.batchRouter)),
+		retryTiers:  make(map[string]retryTier),
 		client:      client,
 		cfg:         cfg,
 		log:         cfg.logger,
+		dispatcher:  nil,
+		assignment:  make(map[topicPartition]bool),
 		mu:          sync.RWMutex{},
+		commitMu:    sync.Mutex{},
 	}
 
 	for topic, handler := range cfg.topicRouter {
@@ -42,6 +62,11 @@ func newConsumer(cfg *config, client *Client) (*Consumer, error) {
 			return nil, err
 		}
 	}
+	for topic, handler := range cfg.batchRouter {
+		if err := consumer.registerBatchTopic(topic, handler, false); err != nil {
+			return nil, err
+		}
+	}
 
 	return consumer, nil
 }
@@ -51,13 +76,19 @@ func newConsumer(cfg *config, client *Client) (*Consumer, error) {
 func (c *Consumer) Run(ctx context.Context) error {
 	c.log.InfoContext(ctx, "Starting Kafka consumer loop",
 		"groupId", c.cfg.groupId,
-		"workers", c.cfg.workers)
+		"workers", c.cfg.workers,
+		"ordering", c.cfg.ordering,
+		"maxInFlight", c.cfg.maxInFlight)
 
 	if c.client == nil || c.client.kgoClient == nil {
 		return fmt.Errorf("consumer client is not initialized")
 	}
 
-	if err := c.runClient(ctx, c.client.kgoClient); err != nil {
+	run := func() error { return c.runClient(ctx, c.client.kgoClient) }
+	if c.client.session != nil {
+		run = func() error { return c.runTransactional(ctx, c.client.session) }
+	}
+	if err := run(); err != nil {
 		if ctx.Err() != nil {
 			c.log.InfoContext(ctx, "Kafka consumer context cancelled, shutting down...")
 			return ctx.Err()
@@ -68,22 +99,67 @@ func (c *Consumer) Run(ctx context.Context) error {
 	return nil
 }
 
+// runClient polls records and dispatches them to the workers until ctx is
+// done or the client is closed. Handled records are committed every
+// commitInterval and when partitions are revoked; on return, the consumer
+// waits for the records being handled and commits them synchronously.
 func (c *Consumer) runClient(ctx context.Context, cl *kgo.Client) error {
-	sem := make(chan struct{}, c.cfg.workers)
+	d := newDispatcher(cl, c.handleRecord, c.cfg.workers, c.cfg.ordering, c.cfg.maxInFlight)
+	d.handleBatch = c.handleBatch
+	c.setDispatcher(d)
+
+	stop := make(chan struct{})
+	var committer sync.WaitGroup
+	committer.Go(func() {
+		ticker := time.NewTicker(commitInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				c.commit(ctx, cl, d.committable)
+			}
+		}
+	})
+
+	clientClosed := false
+	defer func() {
+		close(stop)
+		committer.Wait()
+		d.wait()
+		d.stop()
+		c.setDispatcher(nil)
+
+		if clientClosed {
+			return
+		}
+		commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalCommitTimeout)
+		defer cancel()
+		c.commit(commitCtx, cl, d.committable)
+	}()
+
 	for {
-		fetches := cl.PollRecords(ctx, -1)
+		if !d.flush(ctx, time.Now()) {
+			return ctx.Err()
+		}
+
+		fetches := c.poll(ctx, cl, d)
 		if fetches.IsClientClosed() {
+			clientClosed = true
 			return nil
 		}
 		if err := fetches.Err(); err != nil {
 			if ctx.Err() != nil {
 				return nil
 			}
-			c.log.Warn("Kafka poll error", "err", err)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				c.log.Warn("Kafka poll error", "err", err)
+			}
 			continue
 		}
 
-		records := fetches.Records()
+		records := c.holdUntilDue(ctx, d, fetches.Records(), time.Now())
 		if len(records) == 0 {
 			continue
 		}
@@ -95,39 +171,72 @@ func (c *Consumer) runClient(ctx context.Context, cl *kgo.Client) error {
 			}
 		}
 
-		var recordWg sync.WaitGroup
-		aborted := false
-
 		for _, record := range records {
-			select {
-			case <-ctx.Done():
-				aborted = true
-			case sem <- struct{}{}:
-				recordWg.Add(1)
-				go func(r *kgo.Record) {
-					defer recordWg.Done()
-					defer func() { <-sem }()
-					c.handleRecord(ctx, r)
-				}(record)
-			}
-
-			if aborted {
-				break
+			if !c.dispatch(ctx, d, record) {
+				return ctx.Err()
 			}
 		}
+	}
+}
 
-		recordWg.Wait()
+// poll polls records like kgo.Client.PollRecords. While batches are being
+// collected, it returns once the next one is due, with an error matching
+// context.DeadlineExceeded if no records arrived.
+func (c *Consumer) poll(ctx context.Context, cl *kgo.Client, d *dispatcher) kgo.Fetches {
+	due, ok := d.nextFlush()
+	if !ok {
+		return cl.PollRecords(ctx, c.cfg.maxInFlight)
+	}
 
-		if c.cfg.ackMode == AckModeAtLeastOnce {
-			if err := cl.CommitRecords(ctx, records...); err != nil {
-				c.log.ErrorContext(ctx, "failed to commit records (at least once)", "err", err)
-			}
-		}
+	pollCtx, cancel := context.WithDeadline(ctx, due)
+	defer cancel()
+	return cl.PollRecords(pollCtx, c.cfg.maxInFlight)
+}
 
-		if aborted {
-			return ctx.Err()
-		}
+// dispatch hands record to d, collecting the records of batch topics into
+// batches.
+func (c *Consumer) dispatch(ctx context.Context, d *dispatcher, record *kgo.Record) bool {
+	if _, ok := c.batchHandlerForTopic(record.Topic); !ok {
+		return d.dispatch(ctx, record)
+	}
+
+	linger := c.cfg.batchLinger
+	if c.cfg.transactional() {
+		// Batches are flushed before the transaction of the poll ends.
+		linger = 0
+	}
+	return d.collect(ctx, record, c.cfg.batchSize, linger, time.Now())
+}
+
+// commit commits the records returned by committable, which are taken under
+// commitMu so that concurrent commits never move an offset backwards. With
+// AckModeAtMostOnce, records are committed when polled instead, and in the
+// transactional mode by the transaction that handled them.
+func (c *Consumer) commit(ctx context.Context, cl *kgo.Client, committable func() []*kgo.Record) {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	records := committable()
+	if len(records) == 0 || c.cfg.ackMode != AckModeAtLeastOnce || c.cfg.transactional() {
+		return
 	}
+	if err := cl.CommitRecords(ctx, records...); err != nil {
+		c.log.ErrorContext(ctx, "failed to commit records (at least once)", "err", err)
+	}
+}
+
+func (c *Consumer) setDispatcher(d *dispatcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dispatcher = d
+}
+
+func (c *Consumer) currentDispatcher() *dispatcher {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.dispatcher
 }
 
 // AddTopic registers a new topic handler and updates the underlying client to
@@ -135,56 +244,168 @@ func (c *Consumer) runClient(ctx context.Context, cl *kgo.Client) error {
 //
 // Unlike startup topics registered through WithTopic, topics added here were not part
 // of the client's initial kgo.ConsumeTopics configuration, so AddTopic also calls the
-// franz-go runtime subscription API to begin consuming the new topic.
+// franz-go runtime subscription API to begin consuming the new topic. With
+// WithRetryTopics, the topic's retry topics are registered and subscribed as
+// well.
 func (c *Consumer) AddTopic(topic string, handler Handler) error {
 	return c.registerTopic(topic, handler, true)
 }
 
-// registerTopic stores a topic handler in the consumer router.
+// registerTopic stores a topic handler, wrapped in the configured middleware,
+// in the consumer router.
 //
 // When subscribe is true, the topic is also added to the underlying franz-go client at
 // runtime. When subscribe is false, only the handler router is updated because the
 // client is already subscribed from initial construction.
 func (c *Consumer) registerTopic(topic string, handler Handler, subscribe bool) error {
-	if topic == "" {
-		return fmt.Errorf("topic must not be empty")
-	}
 	if handler == nil {
 		return fmt.Errorf("handler must not be nil")
 	}
 
+	handler = chain(handler, c.cfg.middleware)
+	return c.register(topic, subscribe, func(t string) { c.topicRouter[t] = handler })
+}
+
+// register stores the handler of topic and its retry topics with store,
+// which is called with c.mu held, and subscribes to them if subscribe is
+// true.
+func (c *Consumer) register(topic string, subscribe bool, store func(topic string)) error {
+	if topic == "" {
+		return fmt.Errorf("topic must not be empty")
+	}
+
+	retryTopics := c.cfg.retryTopics(topic)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.client != nil && c.client.isClosed() {
 		return fmt.Errorf("consumer is closed")
 	}
-	if _, ok := c.topicRouter[topic]; ok {
-		return fmt.Errorf("topic handler already registered for %q", topic)
+	for _, t := range append([]string{topic}, retryTopics...) {
+		_, single := c.topicRouter[t]
+		_, batch := c.batchRouter[t]
+		if single || batch {
+			return fmt.Errorf("topic handler already registered for %q", t)
+		}
 	}
 
-	c.topicRouter[topic] = handler
+	store(topic)
+	for i, retryTopic := range retryTopics {
+		store(retryTopic)
+		c.retryTiers[retryTopic] = retryTier{source: topic, index: i}
+	}
 	if subscribe && c.client != nil && c.client.kgoClient != nil {
-		c.client.kgoClient.AddConsumeTopics(topic)
+		c.client.kgoClient.AddConsumeTopics(append([]string{topic}, retryTopics...)...)
 	}
 
 	return nil
 }
 
-func (c *Consumer) handleRecord(ctx context.Context, record *kgo.Record) {
+// handleRecord handles record and reports whether it may be committed: the
+// handler succeeded, or the record was forwarded to a retry or dead-letter
+// topic or dropped for lack of one.
+func (c *Consumer) handleRecord(ctx context.Context, record *kgo.Record) bool {
 	handler, ok := c.handlerForTopic(record.Topic)
 	if !ok {
 		c.log.ErrorContext(ctx, "failed to map topic to handler", "topic", record.Topic)
-		return
+		return false
+	}
+
+	attempts, err := c.process(ctx, handler, record)
+	return c.settle(ctx, []*kgo.Record{record}, attempts, err)
+}
+
+// settle reports whether records, of a single partition, may be committed
+// after their handler returned err: the handler succeeded, skipped them or
+// they were forwarded to a retry or dead-letter topic. Failed records with
+// neither topic to go to are dropped: they are logged and committed, so a
+// record failing for good never holds back its partition.
+func (c *Consumer) settle(ctx context.Context, records []*kgo.Record, attempts int, err error) bool {
+	if err == nil {
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, ErrSkip) {
+		c.log.WarnContext(ctx, "Record skipped", append(coordinates(records), "err", err)...)
+		return true
+	}
+
+	c.log.ErrorContext(ctx, "Handler error", append(coordinates(records), "attempts", attempts, "err", err)...)
+
+	retryable := c.cfg.retry.retryable(err)
+	for _, record := range records {
+		out, ok := c.failedRecord(record, attempts, err, retryable)
+		if !ok {
+			c.log.ErrorContext(ctx, "Record dropped, no dead-letter topic is configured",
+				append(coordinates(records), "attempts", attempts, "err", err)...)
+			return true
+		}
+		if !c.forward(ctx, record, out, attempts) {
+			return false
+		}
+	}
+	return true
+}
+
+// failedRecord returns the copy of record, which failed with err, to forward
+// to its next retry topic if err is retryable, or to the dead-letter topic.
+// It returns false if there is neither.
+func (c *Consumer) failedRecord(record *kgo.Record, attempts int, err error, retryable bool) (*kgo.Record, bool) {
+	origin := c.originOf(record)
+	if retryable {
+		if retryTopic, delay, ok := c.nextRetryTopic(record); ok {
+			return retryRecord(retryTopic, record, origin, err, attempts, time.Now().Add(delay)), true
+		}
+	}
+	if c.cfg.deadLetterTopic != "" {
+		return forwardedRecord(c.cfg.deadLetterTopic, record, origin, err, attempts), true
 	}
+	return nil, false
+}
+
+// process calls handler for record, retrying retryable errors in place
+// according to the retry policy. It returns the number of attempts, including
+// those on earlier retry topics, and the last error.
+func (c *Consumer) process(ctx context.Context, handler Handler, record *kgo.Record) (int, error) {
+	return c.attempt(ctx, []*kgo.Record{record}, func(ctx context.Context) error {
+		return handler(ctx, record)
+	})
+}
+
+// attempt calls call for records, retrying retryable errors in place
+// according to the retry policy. The attempts are counted from those of the
+// first record on earlier retry topics.
+func (c *Consumer) attempt(ctx context.Context, records []*kgo.Record, call func(ctx context.Context) error) (int, error) {
+	policy := c.cfg.retry
+	previous := c.previousAttempts(records[0])
+	for attempt := 1; ; attempt++ {
+		err := call(context.WithValue(ctx, attemptKey{}, previous+attempt))
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return previous + attempt, err
+		}
+
+		delay := policy.backoff(attempt)
+		c.log.WarnContext(ctx, "Handler error, retrying",
+			append(coordinates(records), "attempt", previous+attempt, "backoff", delay, "err", err)...)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return previous + attempt, err
+		}
+	}
+}
 
-	if err := handler(ctx, record); err != nil {
-		c.log.ErrorContext(ctx, "Handler error",
-			"topic", record.Topic,
-			"partition", record.Partition,
-			"offset", record.Offset,
-			"err", err)
+// coordinates returns the log attributes locating records, which are of a
+// single partition: the first record's offset and, for batches, their
+// number.
+func coordinates(records []*kgo.Record) []any {
+	first := records[0]
+	attrs := []any{"topic", first.Topic, "partition", first.Partition, "offset", first.Offset}
+	if len(records) > 1 {
+		attrs = append(attrs, "records", len(records))
 	}
+	return attrs
 }
 
 func (c *Consumer) handlerForTopic(topic string) (Handler, bool) {
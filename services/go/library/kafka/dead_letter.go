@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Headers added to records published to the dead-letter topic.
+const (
+	// HeaderOriginalTopic holds the topic the record was consumed from.
+	HeaderOriginalTopic = "x-original-topic"
+	// HeaderOriginalPartition holds the partition the record was consumed
+	// from.
+	HeaderOriginalPartition = "x-original-partition"
+	// HeaderOriginalOffset holds the offset of the consumed record.
+	HeaderOriginalOffset = "x-original-offset"
+	// HeaderError holds the error of the last handler call.
+	HeaderError = "x-error"
+	// HeaderAttempts holds the number of handler calls made for the record.
+	HeaderAttempts = "x-attempts"
+)
+
+// controlHeaders are the headers the consumer sets on forwarded records.
+// They are replaced whenever a record is forwarded again.
+var controlHeaders = []string{
+	HeaderOriginalTopic,
+	HeaderOriginalPartition,
+	HeaderOriginalOffset,
+	HeaderError,
+	HeaderAttempts,
+	HeaderNotBefore,
+}
+
+// recordOrigin locates the record on the topic it was first consumed from.
+type recordOrigin struct {
+	topic     string
+	partition string
+	offset    string
+}
+
+// originOf returns where record was first consumed from. Records on retry
+// topics carry it in their headers.
+func (c *Consumer) originOf(record *kgo.Record) recordOrigin {
+	origin := recordOrigin{
+		topic:     record.Topic,
+		partition: strconv.FormatInt(int64(record.Partition), 10),
+		offset:    strconv.FormatInt(record.Offset, 10),
+	}
+	if _, ok := c.retryTierOf(record.Topic); !ok {
+		return origin
+	}
+	if topic, ok := headerValue(record, HeaderOriginalTopic); ok {
+		origin.topic = topic
+		origin.partition, _ = headerValue(record, HeaderOriginalPartition)
+		origin.offset, _ = headerValue(record, HeaderOriginalOffset)
+	}
+	return origin
+}
+
+// forwardedRecord returns a copy of record for topic, with headers
+// describing where it came from and why it failed.
+func forwardedRecord(topic string, record *kgo.Record, origin recordOrigin, err error, attempts int) *kgo.Record {
+	headers := make([]kgo.RecordHeader, 0, len(record.Headers)+len(controlHeaders))
+	for _, h := range record.Headers {
+		if !slices.Contains(controlHeaders, h.Key) {
+			headers = append(headers, h)
+		}
+	}
+	headers = append(headers,
+		kgo.RecordHeader{Key: HeaderOriginalTopic, Value: []byte(origin.topic)},
+		kgo.RecordHeader{Key: HeaderOriginalPartition, Value: []byte(origin.partition)},
+		kgo.RecordHeader{Key: HeaderOriginalOffset, Value: []byte(origin.offset)},
+		kgo.RecordHeader{Key: HeaderError, Value: []byte(err.Error())},
+		kgo.RecordHeader{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+	)
+
+	return &kgo.Record{
+		Topic:   topic,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	}
+}
+
+// forward publishes out, a forwarded copy of record, and reports whether it
+// succeeded. Publishing is retried with the consumer's backoff until it
+// succeeds or ctx is done, so that a committed record is never lost while the
+// destination is unavailable. In the transactional mode, a failed produce
+// fails the transaction instead, which is aborted and its records consumed
+// again.
+func (c *Consumer) forward(ctx context.Context, record, out *kgo.Record, attempts int) bool {
+	for retry := 1; ; retry++ {
+		err := c.client.Producer.ProduceSync(ctx, out)
+		if err == nil {
+			c.log.WarnContext(ctx, "Record forwarded",
+				"topic", record.Topic,
+				"partition", record.Partition,
+				"offset", record.Offset,
+				"destination", out.Topic,
+				"attempts", attempts)
+			return true
+		}
+
+		c.log.ErrorContext(ctx, "failed to forward record",
+			"topic", record.Topic,
+			"partition", record.Partition,
+			"offset", record.Offset,
+			"destination", out.Topic,
+			"err", err)
+		if c.cfg.transactional() || sleep(ctx, c.forwardBackoff(retry)) != nil {
+			return false
+		}
+	}
+}
+
+// forwardBackoff returns the delay before retrying a failed forward, using
+// the retry policy or DefaultRetryPolicy if retries are off.
+func (c *Consumer) forwardBackoff(retry int) time.Duration {
+	policy := c.cfg.retry
+	if policy.InitialBackoff <= 0 {
+		policy = DefaultRetryPolicy()
+	}
+	return policy.backoff(retry)
+}
@@ -0,0 +1,151 @@
+// Package dedup makes Kafka handlers idempotent. A Deduplicator records the
+// ID of every handled record in a Postgres table, in the same transaction as
+// the handler's own writes, and skips records whose ID is already recorded.
+// A record redelivered after a crash or rebalance therefore has its effects
+// applied exactly once.
+//
+// Handlers must write through the transaction of their context, e.g. with
+// repositories using transactor.TXAccessor; writes outside of it are not
+// rolled back with the message ID.
+package dedup
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/apperror"
+	"go-services/library/kafka"
+	"go-services/library/transactor"
+)
+
+// Deduplicator skips records that were already handled successfully.
+type Deduplicator struct {
+	log      *slog.Logger
+	tr       transactor.Transactor
+	accessor transactor.TXAccessor[pgx.Tx]
+	consumer string
+	cfg      config
+}
+
+// New returns a Deduplicator recording message IDs under consumer, usually
+// the consumer group ID, so consumers of the same topic do not skip each
+// other's records.
+func New(
+	log *slog.Logger,
+	tr transactor.Transactor,
+	accessor transactor.TXAccessor[pgx.Tx],
+	consumer string,
+	opts ...Option,
+) *Deduplicator {
+	return &Deduplicator{
+		log:      log,
+		tr:       tr,
+		accessor: accessor,
+		consumer: consumer,
+		cfg:      newConfig(opts...),
+	}
+}
+
+// Middleware returns a handler that runs next in a transaction together
+// with recording the record's message ID, unless the ID is already recorded.
+// If next fails, the ID is rolled back with its writes and the record is
+// handled again on redelivery. Concurrent deliveries of the same record wait
+// for each other, so only one of them runs next.
+func (d *Deduplicator) Middleware(next kafka.Handler) kafka.Handler {
+	return func(ctx context.Context, record *kgo.Record) error {
+		id := d.messageID(record)
+		return d.tr.Atomic(ctx, func(ctx context.Context) error {
+			tx, ok := d.accessor.GetTx(ctx)
+			if !ok {
+				return apperror.New(apperror.CodeDBTransaction, "no transaction to record message %s in", id)
+			}
+
+			tag, err := tx.Exec(ctx,
+				"INSERT INTO kafka_processed_messages (consumer, message_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				d.consumer,
+				id,
+			)
+			if err != nil {
+				return apperror.Wrap(apperror.CodeInternalError, err, "failed to record message %s", id)
+			}
+			if tag.RowsAffected() == 0 {
+				d.log.InfoContext(ctx, "Skipping duplicate record",
+					"message_id", id,
+					"topic", record.Topic,
+					"partition", record.Partition,
+					"offset", record.Offset,
+				)
+				return nil
+			}
+
+			return next(ctx, record)
+		})
+	}
+}
+
+// messageID returns the ID of record: the value of the ID header if
+// configured and present, otherwise its topic, partition and offset.
+func (d *Deduplicator) messageID(record *kgo.Record) string {
+	if d.cfg.idHeader != "" {
+		for _, h := range record.Headers {
+			if h.Key == d.cfg.idHeader && len(h.Value) > 0 {
+				return string(h.Value)
+			}
+		}
+	}
+	return record.Topic + "/" + strconv.Itoa(int(record.Partition)) + "/" + strconv.FormatInt(record.Offset, 10)
+}
+
+// Prune deletes the message IDs recorded longer ago than the retention and
+// returns how many it deleted.
+func (d *Deduplicator) Prune(ctx context.Context) (int64, error) {
+	var deleted int64
+	err := d.tr.Atomic(ctx, func(ctx context.Context) error {
+		tx, ok := d.accessor.GetTx(ctx)
+		if !ok {
+			return apperror.New(apperror.CodeDBTransaction, "no transaction to prune message ids in")
+		}
+
+		tag, err := tx.Exec(ctx,
+			"DELETE FROM kafka_processed_messages WHERE consumer = $1 AND processed_at < now() - make_interval(secs => $2)",
+			d.consumer,
+			d.cfg.retention.Seconds(),
+		)
+		if err != nil {
+			return apperror.Wrap(apperror.CodeInternalError, err, "failed to prune processed message ids")
+		}
+		deleted = tag.RowsAffected()
+		return nil
+	})
+	return deleted, err
+}
+
+// Run prunes message IDs every prune interval until ctx is done. Failures
+// are logged and retried on the next interval.
+func (d *Deduplicator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.cfg.pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := d.Prune(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			d.log.ErrorContext(ctx, "Failed to prune processed message ids", "consumer", d.consumer, "err", err)
+		} else if deleted > 0 {
+			d.log.InfoContext(ctx, "Pruned processed message ids", "consumer", d.consumer, "deleted", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
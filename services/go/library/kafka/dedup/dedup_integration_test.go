@@ -0,0 +1,149 @@
+//go:build integration
+
+package dedup_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/kafka"
+	"go-services/library/kafka/dedup"
+	"go-services/library/require"
+	"go-services/library/transactor"
+)
+
+// setup creates a table the test handlers write to and returns a
+// deduplicator for consumer.
+func setup(ctx context.Context, t *testing.T, consumer string, opts ...dedup.Option) *dedup.Deduplicator {
+	t.Helper()
+	t.Cleanup(pg.CleanupData)
+
+	_, err := pg.Pool.Exec(ctx, "CREATE TABLE IF NOT EXISTS handled (value TEXT NOT NULL)")
+	require.NoError(t, err, "failed to create handled table")
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return dedup.New(log, transactor.NewPGTransactor(log, pg.Pool), transactor.NewTxAccessor[pgx.Tx](), consumer, opts...)
+}
+
+// insertHandled is a handler that writes the record value through the
+// transaction of its context, failing with err afterwards if not nil.
+func insertHandled(err error) kafka.Handler {
+	accessor := transactor.NewTxAccessor[pgx.Tx]()
+	return func(ctx context.Context, record *kgo.Record) error {
+		tx, ok := accessor.GetTx(ctx)
+		if !ok {
+			return errors.New("handler runs outside a transaction")
+		}
+		if _, execErr := tx.Exec(ctx, "INSERT INTO handled (value) VALUES ($1)", string(record.Value)); execErr != nil {
+			return execErr
+		}
+		return err
+	}
+}
+
+func handledValues(ctx context.Context, t *testing.T) []string {
+	t.Helper()
+
+	rows, err := pg.Pool.Query(ctx, "SELECT value FROM handled ORDER BY value")
+	require.NoError(t, err, "failed to query handled values")
+	values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.NoError(t, err, "failed to read handled values")
+	return values
+}
+
+func TestDeduplicatorSkipsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	d := setup(ctx, t, "group")
+	handler := d.Middleware(insertHandled(nil))
+
+	record := &kgo.Record{Topic: "users", Partition: 0, Offset: 1, Value: []byte("a")}
+	require.NoError(t, handler(ctx, record), "first delivery")
+	require.NoError(t, handler(ctx, record), "redelivery")
+	require.NoError(t, handler(ctx, &kgo.Record{Topic: "users", Partition: 0, Offset: 2, Value: []byte("b")}), "next record")
+
+	other := setup(ctx, t, "other-group")
+	require.NoError(t, other.Middleware(insertHandled(nil))(ctx, record), "delivery to another consumer")
+
+	assert.Equal(t, handledValues(ctx, t), []string{"a", "a", "b"}, "handled values")
+}
+
+func TestDeduplicatorRollsBackFailedRecord(t *testing.T) {
+	ctx := context.Background()
+	d := setup(ctx, t, "group")
+	record := &kgo.Record{Topic: "users", Partition: 0, Offset: 1, Value: []byte("a")}
+
+	wantErr := errors.New("handler failed")
+	err := d.Middleware(insertHandled(wantErr))(ctx, record)
+	require.ErrorIs(t, err, wantErr, "failed delivery")
+	assert.SliceLen(t, handledValues(ctx, t), 0, "writes of the failed delivery are rolled back")
+
+	require.NoError(t, d.Middleware(insertHandled(nil))(ctx, record), "redelivery")
+	assert.Equal(t, handledValues(ctx, t), []string{"a"}, "redelivered record is handled")
+}
+
+func TestDeduplicatorIDHeader(t *testing.T) {
+	ctx := context.Background()
+	d := setup(ctx, t, "group", dedup.WithIDHeader("message-id"))
+	handler := d.Middleware(insertHandled(nil))
+
+	headers := []kgo.RecordHeader{{Key: "message-id", Value: []byte("event-1")}}
+	require.NoError(t, handler(ctx, &kgo.Record{Topic: "users", Offset: 1, Value: []byte("a"), Headers: headers}), "first copy")
+	require.NoError(t, handler(ctx, &kgo.Record{Topic: "users", Offset: 2, Value: []byte("a"), Headers: headers}), "second copy")
+
+	assert.Equal(t, handledValues(ctx, t), []string{"a"}, "record produced twice is handled once")
+}
+
+func TestDeduplicatorConcurrentDeliveries(t *testing.T) {
+	ctx := context.Background()
+	d := setup(ctx, t, "group")
+	record := &kgo.Record{Topic: "users", Partition: 0, Offset: 1, Value: []byte("a")}
+
+	// The first delivery holds its transaction open until the second one
+	// waits on the recorded message ID.
+	started := make(chan struct{})
+	first := d.Middleware(func(ctx context.Context, record *kgo.Record) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return insertHandled(nil)(ctx, record)
+	})
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		assert.NoError(t, first(ctx, record), "first delivery")
+	})
+	<-started
+	assert.NoError(t, d.Middleware(insertHandled(nil))(ctx, record), "concurrent delivery")
+	wg.Wait()
+
+	assert.Equal(t, handledValues(ctx, t), []string{"a"}, "record handled once")
+}
+
+func TestDeduplicatorPrune(t *testing.T) {
+	ctx := context.Background()
+	d := setup(ctx, t, "group", dedup.WithRetention(time.Hour))
+
+	_, err := pg.Pool.Exec(ctx, `
+		INSERT INTO kafka_processed_messages (consumer, message_id, processed_at) VALUES
+			('group', 'old', now() - interval '2 hours'),
+			('group', 'recent', now() - interval '10 minutes'),
+			('other-group', 'old', now() - interval '2 hours')`)
+	require.NoError(t, err, "failed to insert processed messages")
+
+	deleted, err := d.Prune(ctx)
+	require.NoError(t, err, "prune")
+	assert.Equal(t, deleted, int64(1), "deleted message ids")
+
+	var remaining int
+	err = pg.Pool.QueryRow(ctx, "SELECT count(*) FROM kafka_processed_messages").Scan(&remaining)
+	require.NoError(t, err, "failed to count processed messages")
+	assert.Equal(t, remaining, 2, "remaining message ids")
+}
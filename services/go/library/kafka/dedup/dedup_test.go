@@ -0,0 +1,89 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+)
+
+func TestMessageID(t *testing.T) {
+	tests := map[string]struct {
+		record   *kgo.Record
+		idHeader string
+		want     string
+	}{
+		"coordinates": {
+			record:   &kgo.Record{Topic: "users", Partition: 2, Offset: 42},
+			idHeader: "",
+			want:     "users/2/42",
+		},
+		"header": {
+			record: &kgo.Record{Topic: "users", Partition: 2, Offset: 42, Headers: []kgo.RecordHeader{
+				{Key: "message-id", Value: []byte("abc")},
+			}},
+			idHeader: "message-id",
+			want:     "abc",
+		},
+		"missing header": {
+			record:   &kgo.Record{Topic: "users", Partition: 2, Offset: 42},
+			idHeader: "message-id",
+			want:     "users/2/42",
+		},
+		"empty header": {
+			record: &kgo.Record{Topic: "users", Partition: 2, Offset: 42, Headers: []kgo.RecordHeader{
+				{Key: "message-id", Value: nil},
+			}},
+			idHeader: "message-id",
+			want:     "users/2/42",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := New(nil, nil, nil, "group", WithIDHeader(tt.idHeader))
+
+			assert.Equal(t, d.messageID(tt.record), tt.want, "message id")
+		})
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	tests := map[string]struct {
+		opts              []Option
+		wantRetention     time.Duration
+		wantPruneInterval time.Duration
+	}{
+		"defaults": {
+			opts:              nil,
+			wantRetention:     defaultRetention,
+			wantPruneInterval: defaultPruneInterval,
+		},
+		"positive": {
+			opts:              []Option{WithRetention(time.Hour), WithPruneInterval(time.Minute)},
+			wantRetention:     time.Hour,
+			wantPruneInterval: time.Minute,
+		},
+		"zero": {
+			opts:              []Option{WithRetention(0), WithPruneInterval(0)},
+			wantRetention:     defaultRetention,
+			wantPruneInterval: defaultPruneInterval,
+		},
+		"negative": {
+			opts:              []Option{WithRetention(-time.Hour), WithPruneInterval(-time.Minute)},
+			wantRetention:     defaultRetention,
+			wantPruneInterval: defaultPruneInterval,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig(tt.opts...)
+
+			assert.Equal(t, cfg.retention, tt.wantRetention, "retention")
+			assert.Equal(t, cfg.pruneInterval, tt.wantPruneInterval, "prune interval")
+		})
+	}
+}
@@ -0,0 +1,40 @@
+//go:build integration
+
+package dedup_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"go-services/library/kafka/dedup"
+	"go-services/library/testenv"
+)
+
+var (
+	te *testenv.TestEnv
+	pg *testenv.Postgres
+)
+
+func TestMain(m *testing.M) {
+	te = testenv.New("library_kafka_dedup")
+
+	var err error
+	pg, err = testenv.SetupPostgres(te, testenv.WithMigrationTableName(dedup.MigrationTableName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up postgres: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dedup.Migrate(context.Background(), pg.Pool); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to apply dedup migrations: %v\n", err)
+		te.Cleanup()
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	te.Cleanup()
+
+	os.Exit(code)
+}
@@ -0,0 +1,34 @@
+package dedup
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-services/library/internal/migrate"
+)
+
+// MigrationTableName is the version table of the dedup migrations. It is
+// separate from the service's own migration table, so both can be applied to
+// the same schema independently.
+const MigrationTableName = "kafka_dedup_db_version"
+
+//go:embed migrations/*.sql
+var embedMigrations embed.FS
+
+// Migrate applies the dedup migrations to the database of pool. Services
+// that manage their schema with their own migrations can copy the migration
+// in the migrations directory instead.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := fs.Sub(embedMigrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to open dedup migrations: %w", err)
+	}
+	if err := migrate.Apply(ctx, pool, migrations, MigrationTableName); err != nil {
+		return fmt.Errorf("dedup: %w", err)
+	}
+	return nil
+}
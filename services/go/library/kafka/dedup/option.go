@@ -0,0 +1,61 @@
+package dedup
+
+import "time"
+
+// config holds the settings of a Deduplicator.
+type config struct {
+	idHeader      string
+	retention     time.Duration
+	pruneInterval time.Duration
+}
+
+// Default deduplicator settings.
+const (
+	defaultRetention     = 7 * 24 * time.Hour
+	defaultPruneInterval = time.Hour
+)
+
+func newConfig(opts ...Option) config {
+	cfg := config{
+		idHeader:      "",
+		retention:     defaultRetention,
+		pruneInterval: defaultPruneInterval,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Option configures a Deduplicator.
+type Option func(*config)
+
+// WithIDHeader identifies records by the value of the header key, e.g. a
+// message ID set by the producer, so a record produced twice is recognized
+// too. Records without the header are identified by their coordinates.
+func WithIDHeader(key string) Option {
+	return func(c *config) {
+		c.idHeader = key
+	}
+}
+
+// WithRetention sets how long processed message IDs are remembered. It
+// must exceed the time after which a record can be redelivered. It defaults
+// to seven days; non-positive values are ignored.
+func WithRetention(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.retention = d
+		}
+	}
+}
+
+// WithPruneInterval sets how often Run deletes message IDs older than the
+// retention. It defaults to one hour; non-positive values are ignored.
+func WithPruneInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.pruneInterval = d
+		}
+	}
+}
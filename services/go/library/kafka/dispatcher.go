@@ -0,0 +1,614 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// laneKey identifies a sequence of records handled one after another. With
+// OrderingKey, key is the record key; otherwise it is empty.
+type laneKey struct {
+	key string
+	tp  topicPartition
+}
+
+// unit is what a single handler call handles: a record, or a batch of
+// records of one partition for a batch handler.
+type unit struct {
+	records []*kgo.Record
+	batch   bool
+}
+
+// pendingBatch holds the records collected for a batch that is not
+// dispatched yet.
+type pendingBatch struct {
+	// due is when the batch is dispatched even if it is not full.
+	due     time.Time
+	records []*kgo.Record
+}
+
+// pauseReason is a reason for pausing a partition. A partition paused for
+// several reasons is resumed once none of them applies anymore.
+type pauseReason uint8
+
+const (
+	// pauseInFlight pauses a partition with maxInFlight records dispatched
+	// but not handled yet.
+	pauseInFlight pauseReason = 1 << iota
+	// pauseNotDue pauses a retry topic partition until its next record is
+	// due.
+	pauseNotDue
+	// pauseUncommitted pauses a partition with more than maxInFlight records
+	// tracked from a record that is not handled, e.g. because its handler
+	// hangs, so records that cannot be committed do not pile up.
+	pauseUncommitted
+)
+
+// partitionOffsets tracks the records of a partition that were dispatched
+// but not committed yet.
+type partitionOffsets struct {
+	// records are the tracked records by offset.
+	records map[int64]*kgo.Record
+	// handled holds the offsets of tracked records handled successfully.
+	handled map[int64]bool
+	// last is the last record of the handled prefix that was not returned
+	// by committable or release yet.
+	last *kgo.Record
+	// order holds the offsets of tracked records in dispatch order, starting
+	// with the first record that is not handled. A record that could not be
+	// handled stays first, so the partition's commits stop before it.
+	order []int64
+}
+
+// dispatcher hands polled records to the handler, or collects them into
+// batches for handleBatch. Records and batches of the same lane are handled
+// in offset order and lanes run in parallel, limited by the number of
+// workers. Partitions with maxInFlight records dispatched but not
+// handled yet are paused until half of them are handled, so a slow partition
+// does not pile up records while the others keep flowing. Likewise,
+// partitions with more than maxInFlight records tracked from their first
+// record that is not handled are paused until the gap halves, so a record
+// that never completes holds back a bounded number of records. Retry topic
+// partitions are also paused by hold until their next record is due; paused
+// tracks every reason, so none resumes a partition another still holds.
+// changed is closed and replaced whenever a record is done.
+type dispatcher struct {
+	cl          partitionPauser
+	handle      func(ctx context.Context, record *kgo.Record) bool
+	handleBatch func(ctx context.Context, records []*kgo.Record) bool
+	sem         chan struct{}
+	lanes       map[laneKey][]unit
+	batches     map[topicPartition]*pendingBatch
+	inFlight    map[topicPartition]int
+	paused      map[topicPartition]pauseReason
+	// holds resume the partitions held until their next record is due.
+	holds       map[topicPartition]*time.Timer
+	revoked     map[topicPartition]bool
+	offsets     map[topicPartition]*partitionOffsets
+	changed     chan struct{}
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	ordering    Ordering
+	maxInFlight int
+}
+
+func newDispatcher(
+	cl partitionPauser,
+	handle func(ctx context.Context, record *kgo.Record) bool,
+	workers int,
+	ordering Ordering,
+	maxInFlight int,
+) *dispatcher {
+	return &dispatcher{
+		cl:          cl,
+		handle:      handle,
+		handleBatch: nil,
+		sem:         make(chan struct{}, workers),
+		lanes:       make(map[laneKey][]unit),
+		batches:     make(map[topicPartition]*pendingBatch),
+		inFlight:    make(map[topicPartition]int),
+		paused:      make(map[topicPartition]pauseReason),
+		holds:       make(map[topicPartition]*time.Timer),
+		revoked:     make(map[topicPartition]bool),
+		offsets:     make(map[topicPartition]*partitionOffsets),
+		changed:     make(chan struct{}),
+		wg:          sync.WaitGroup{},
+		mu:          sync.Mutex{},
+		ordering:    ordering,
+		maxInFlight: maxInFlight,
+	}
+}
+
+// dispatch schedules record for handling. Without ordering it blocks until
+// a worker is free. It returns false if ctx is done before.
+func (d *dispatcher) dispatch(ctx context.Context, record *kgo.Record) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if d.ordering == OrderingNone {
+		select {
+		case <-ctx.Done():
+			return false
+		case d.sem <- struct{}{}:
+		}
+
+		d.mu.Lock()
+		if d.revoked[topicPartition{topic: record.Topic, partition: record.Partition}] {
+			d.mu.Unlock()
+			<-d.sem
+			return true
+		}
+		d.track(record)
+		d.mu.Unlock()
+
+		d.wg.Go(func() {
+			ok := d.handle(ctx, record)
+			<-d.sem
+			d.done(record, ok)
+		})
+		return true
+	}
+
+	key := d.laneOf(record)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.revoked[key.tp] {
+		return true
+	}
+	d.track(record)
+	d.enqueue(ctx, key, unit{records: []*kgo.Record{record}, batch: false})
+	return true
+}
+
+// collect adds record to the batch of its partition for handleBatch. The
+// batch is dispatched once it holds maxSize records, or by flush once linger
+// passed since its first record was collected. It returns false if ctx is
+// done before.
+func (d *dispatcher) collect(ctx context.Context, record *kgo.Record, maxSize int, linger time.Duration, now time.Time) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	tp := topicPartition{topic: record.Topic, partition: record.Partition}
+
+	d.mu.Lock()
+	if d.revoked[tp] {
+		d.mu.Unlock()
+		return true
+	}
+	d.track(record)
+	batch, ok := d.batches[tp]
+	if !ok {
+		batch = &pendingBatch{due: now.Add(linger), records: nil}
+		d.batches[tp] = batch
+	}
+	batch.records = append(batch.records, record)
+	full := len(batch.records) >= maxSize
+	if full {
+		delete(d.batches, tp)
+	}
+	d.mu.Unlock()
+
+	if !full {
+		return true
+	}
+	return d.dispatchBatch(ctx, batch.records)
+}
+
+// flush dispatches the collected batches that are due at now. It returns
+// false if ctx is done before.
+func (d *dispatcher) flush(ctx context.Context, now time.Time) bool {
+	d.mu.Lock()
+	var due [][]*kgo.Record
+	for tp, batch := range d.batches {
+		if !batch.due.After(now) {
+			due = append(due, batch.records)
+			delete(d.batches, tp)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, records := range due {
+		if !d.dispatchBatch(ctx, records) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextFlush returns when the next collected batch is due, or false if no
+// batch is being collected.
+func (d *dispatcher) nextFlush() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var next time.Time
+	for _, batch := range d.batches {
+		if next.IsZero() || batch.due.Before(next) {
+			next = batch.due
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// dispatchBatch schedules records, a collected batch of one partition that
+// is already tracked, for handleBatch. Without ordering it blocks until a
+// worker is free. It returns false if ctx is done before.
+func (d *dispatcher) dispatchBatch(ctx context.Context, records []*kgo.Record) bool {
+	batch := unit{records: records, batch: true}
+	key := laneKey{key: "", tp: topicPartition{topic: records[0].Topic, partition: records[0].Partition}}
+	if d.ordering == OrderingNone {
+		select {
+		case <-ctx.Done():
+			return false
+		case d.sem <- struct{}{}:
+		}
+
+		d.wg.Go(func() {
+			ok := !d.isRevoked(key.tp) && d.run(ctx, batch)
+			<-d.sem
+			d.finish(batch, ok)
+		})
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.enqueue(ctx, key, batch)
+	return true
+}
+
+// enqueue appends u to the queue of the lane key and starts handling the
+// lane if it is not running. d.mu must be held.
+func (d *dispatcher) enqueue(ctx context.Context, key laneKey, u unit) {
+	queue, running := d.lanes[key]
+	d.lanes[key] = append(queue, u)
+	if !running {
+		d.wg.Go(func() { d.runLane(ctx, key) })
+	}
+}
+
+// run hands u to its handler and reports whether it was handled.
+func (d *dispatcher) run(ctx context.Context, u unit) bool {
+	if u.batch {
+		return d.handleBatch(ctx, u.records)
+	}
+	return d.handle(ctx, u.records[0])
+}
+
+// wait blocks until every dispatched record is handled or abandoned because
+// ctx is done.
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
+
+// committable returns, per partition, the last record of the contiguous
+// prefix of successfully handled records that was not returned before.
+// Committing these records never skips a record that is still being handled
+// or could not be handled.
+func (d *dispatcher) committable() []*kgo.Record {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var records []*kgo.Record
+	for tp, offsets := range d.offsets {
+		if offsets.last != nil {
+			records = append(records, offsets.last)
+			offsets.last = nil
+		}
+		if len(offsets.order) == 0 {
+			delete(d.offsets, tp)
+		}
+	}
+	return records
+}
+
+// release stops tracking the given partitions, e.g. because they were
+// revoked, and returns the last record of their handled prefix like
+// committable. Records of these partitions still being handled are ignored
+// once done, and paused partitions are resumed, with their holds stopped, so
+// they are fetched again when assigned anew.
+func (d *dispatcher) release(partitions map[string][]int32) []*kgo.Record {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var records []*kgo.Record
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			tp := topicPartition{topic: topic, partition: partition}
+			if offsets, ok := d.offsets[tp]; ok {
+				if offsets.last != nil {
+					records = append(records, offsets.last)
+				}
+				delete(d.offsets, tp)
+			}
+			if timer, ok := d.holds[tp]; ok {
+				timer.Stop()
+				delete(d.holds, tp)
+			}
+			if d.paused[tp] != 0 {
+				delete(d.paused, tp)
+				d.cl.ResumeFetchPartitions(map[string][]int32{topic: {partition}})
+			}
+		}
+	}
+	return records
+}
+
+// revoke stops handling records of the given partitions: records polled,
+// queued or collected into batches for them are dropped, so only those being
+// handled remain. They
+// are handled again once assign is called for the partitions.
+func (d *dispatcher) revoke(partitions map[string][]int32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			d.revoked[topicPartition{topic: topic, partition: partition}] = true
+		}
+	}
+	for key, queue := range d.lanes {
+		if !d.revoked[key.tp] || len(queue) == 0 {
+			continue
+		}
+		d.lanes[key] = nil
+		for _, u := range queue {
+			for _, record := range u.records {
+				d.complete(record, false)
+			}
+		}
+	}
+	for tp, batch := range d.batches {
+		if !d.revoked[tp] {
+			continue
+		}
+		delete(d.batches, tp)
+		for _, record := range batch.records {
+			d.complete(record, false)
+		}
+	}
+}
+
+// assign handles records of the given partitions again after revoke.
+func (d *dispatcher) assign(partitions map[string][]int32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			delete(d.revoked, topicPartition{topic: topic, partition: partition})
+		}
+	}
+}
+
+// drain waits until no record of the given partitions is being handled, or
+// ctx is done.
+func (d *dispatcher) drain(ctx context.Context, partitions map[string][]int32) error {
+	for {
+		d.mu.Lock()
+		busy := false
+		for topic, ps := range partitions {
+			for _, partition := range ps {
+				busy = busy || d.inFlight[topicPartition{topic: topic, partition: partition}] > 0
+			}
+		}
+		changed := d.changed
+		d.mu.Unlock()
+
+		if !busy {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (d *dispatcher) isRevoked(tp topicPartition) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.revoked[tp]
+}
+
+// laneOf returns the lane record is handled in.
+func (d *dispatcher) laneOf(record *kgo.Record) laneKey {
+	key := laneKey{key: "", tp: topicPartition{topic: record.Topic, partition: record.Partition}}
+	if d.ordering == OrderingKey {
+		key.key = string(record.Key)
+	}
+	return key
+}
+
+// runLane handles the records and batches queued for key one after another
+// until the queue is empty.
+func (d *dispatcher) runLane(ctx context.Context, key laneKey) {
+	for {
+		d.mu.Lock()
+		queue := d.lanes[key]
+		if len(queue) == 0 || ctx.Err() != nil {
+			delete(d.lanes, key)
+			d.mu.Unlock()
+			return
+		}
+		u := queue[0]
+		d.lanes[key] = queue[1:]
+		d.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			continue
+		case d.sem <- struct{}{}:
+		}
+		if d.isRevoked(key.tp) {
+			<-d.sem
+			d.finish(u, false)
+			continue
+		}
+		ok := d.run(ctx, u)
+		<-d.sem
+		d.finish(u, ok)
+	}
+}
+
+// track registers record as dispatched and pauses its partition when it
+// reaches maxInFlight records in flight or exceeds maxInFlight uncommitted
+// records. d.mu must be held.
+func (d *dispatcher) track(record *kgo.Record) {
+	tp := topicPartition{topic: record.Topic, partition: record.Partition}
+	offsets, ok := d.offsets[tp]
+	if !ok {
+		offsets = &partitionOffsets{
+			records: make(map[int64]*kgo.Record),
+			handled: make(map[int64]bool),
+			last:    nil,
+			order:   nil,
+		}
+		d.offsets[tp] = offsets
+	}
+	offsets.records[record.Offset] = record
+	offsets.order = append(offsets.order, record.Offset)
+	if len(offsets.order) > d.maxInFlight {
+		d.pause(tp, pauseUncommitted)
+	}
+
+	d.inFlight[tp]++
+	if d.inFlight[tp] >= d.maxInFlight {
+		// Pausing drops the partition's buffered records without advancing
+		// its fetch offset, so they are fetched again once it is resumed.
+		d.pause(tp, pauseInFlight)
+	}
+}
+
+// hold pauses the partition of record, a retry topic record that is not due
+// yet, and rewinds it to record, so it is fetched again once it is resumed
+// after wait. Partitions that are revoked are left alone.
+func (d *dispatcher) hold(record *kgo.Record, wait time.Duration) {
+	tp := topicPartition{topic: record.Topic, partition: record.Partition}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.revoked[tp] {
+		return
+	}
+	d.pause(tp, pauseNotDue)
+	d.cl.SetOffsets(map[string]map[int32]kgo.EpochOffset{
+		record.Topic: {record.Partition: {Epoch: record.LeaderEpoch, Offset: record.Offset}},
+	})
+
+	if timer, ok := d.holds[tp]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		// A hold that was stopped or replaced no longer owns the pause.
+		if d.holds[tp] != timer {
+			return
+		}
+		delete(d.holds, tp)
+		d.resume(tp, pauseNotDue)
+	})
+	d.holds[tp] = timer
+}
+
+// stop stops the holds of every partition, e.g. because the client is
+// closing. Their partitions stay paused.
+func (d *dispatcher) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for tp, timer := range d.holds {
+		timer.Stop()
+		delete(d.holds, tp)
+	}
+}
+
+// pause pauses the partition tp for reason, fetching it no longer if it was
+// not paused yet. d.mu must be held.
+func (d *dispatcher) pause(tp topicPartition, reason pauseReason) {
+	if d.paused[tp] == 0 {
+		d.cl.PauseFetchPartitions(map[string][]int32{tp.topic: {tp.partition}})
+	}
+	d.paused[tp] |= reason
+}
+
+// resume lifts the pause of the partition tp for reason, fetching it again
+// once no other reason keeps it paused. d.mu must be held.
+func (d *dispatcher) resume(tp topicPartition, reason pauseReason) {
+	if d.paused[tp]&reason == 0 {
+		return
+	}
+	d.paused[tp] &^= reason
+	if d.paused[tp] == 0 {
+		delete(d.paused, tp)
+		d.cl.ResumeFetchPartitions(map[string][]int32{tp.topic: {tp.partition}})
+	}
+}
+
+// done marks record as handled, successfully if ok, and resumes its
+// partition once half of the in-flight records are handled.
+func (d *dispatcher) done(record *kgo.Record, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.complete(record, ok)
+}
+
+// finish marks the records of u as handled, successfully if ok.
+func (d *dispatcher) finish(u unit, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, record := range u.records {
+		d.complete(record, ok)
+	}
+}
+
+// complete implements done. d.mu must be held.
+func (d *dispatcher) complete(record *kgo.Record, ok bool) {
+	tp := topicPartition{topic: record.Topic, partition: record.Partition}
+	close(d.changed)
+	d.changed = make(chan struct{})
+
+	// Records of released partitions are no longer tracked, even if the
+	// partition was assigned again and the offset is tracked anew.
+	if offsets, tracked := d.offsets[tp]; tracked && offsets.records[record.Offset] == record && ok {
+		offsets.handled[record.Offset] = true
+		offsets.advance()
+		if len(offsets.order) <= d.maxInFlight/2 {
+			d.resume(tp, pauseUncommitted)
+		}
+	}
+
+	d.inFlight[tp]--
+	if d.inFlight[tp] <= 0 {
+		delete(d.inFlight, tp)
+	}
+	if d.inFlight[tp] <= d.maxInFlight/2 {
+		d.resume(tp, pauseInFlight)
+	}
+}
+
+// advance removes the contiguous prefix of successfully handled records,
+// keeping its last record in last.
+func (o *partitionOffsets) advance() {
+	for len(o.order) > 0 && o.handled[o.order[0]] {
+		offset := o.order[0]
+		o.last = o.records[offset]
+		delete(o.records, offset)
+		delete(o.handled, offset)
+		o.order = o.order[1:]
+	}
+}
@@ -0,0 +1,350 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+func newFakePauser() *fakePauser {
+	return &fakePauser{
+		paused:  make(map[string][]int32),
+		offsets: make(map[string]map[int32]kgo.EpochOffset),
+		resumed: make(chan map[string][]int32, 16),
+	}
+}
+
+// offsetsOf returns the offsets of records.
+func offsetsOf(records []*kgo.Record) []int64 {
+	offsets := make([]int64, len(records))
+	for i, r := range records {
+		offsets[i] = r.Offset
+	}
+	return offsets
+}
+
+func TestDispatcherOrdering(t *testing.T) {
+	records := []*kgo.Record{
+		{Topic: "orders", Partition: 0, Offset: 0, Key: []byte("a")},
+		{Topic: "orders", Partition: 0, Offset: 1, Key: []byte("b")},
+		{Topic: "orders", Partition: 1, Offset: 0, Key: []byte("a")},
+		{Topic: "orders", Partition: 0, Offset: 2, Key: []byte("a")},
+		{Topic: "orders", Partition: 0, Offset: 3, Key: []byte("b")},
+		{Topic: "orders", Partition: 1, Offset: 1, Key: []byte("a")},
+	}
+
+	tests := map[string]struct {
+		want     map[laneKey][]int64
+		blocked  laneKey
+		ordering Ordering
+	}{
+		"partition": {
+			want: map[laneKey][]int64{
+				{key: "", tp: topicPartition{topic: "orders", partition: 0}}: {0, 1, 2, 3},
+				{key: "", tp: topicPartition{topic: "orders", partition: 1}}: {0, 1},
+			},
+			blocked:  laneKey{key: "", tp: topicPartition{topic: "orders", partition: 0}},
+			ordering: OrderingPartition,
+		},
+		"key": {
+			want: map[laneKey][]int64{
+				{key: "a", tp: topicPartition{topic: "orders", partition: 0}}: {0, 2},
+				{key: "b", tp: topicPartition{topic: "orders", partition: 0}}: {1, 3},
+				{key: "a", tp: topicPartition{topic: "orders", partition: 1}}: {0, 1},
+			},
+			blocked:  laneKey{key: "a", tp: topicPartition{topic: "orders", partition: 0}},
+			ordering: OrderingKey,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := make(map[laneKey][]int64)
+			othersDone := make(chan struct{})
+			others := len(records) - len(tt.want[tt.blocked])
+
+			var d *dispatcher
+			d = newDispatcher(newFakePauser(), func(_ context.Context, record *kgo.Record) bool {
+				key := d.laneOf(record)
+				if key == tt.blocked && record.Offset == tt.want[tt.blocked][0] {
+					// The other lanes must make progress while this one is
+					// blocked.
+					select {
+					case <-othersDone:
+					case <-time.After(5 * time.Second):
+						t.Error("other lanes did not finish while one was blocked")
+					}
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				got[key] = append(got[key], record.Offset)
+				if key != tt.blocked {
+					others--
+					if others == 0 {
+						close(othersDone)
+					}
+				}
+				return true
+			}, 4, tt.ordering, 100)
+
+			for _, record := range records {
+				require.True(t, d.dispatch(t.Context(), record), "dispatch offset %d", record.Offset)
+			}
+			d.wait()
+
+			assert.Equal(t, got, tt.want, "handled offsets per lane")
+		})
+	}
+}
+
+func TestDispatcherBackPressure(t *testing.T) {
+	pauser := newFakePauser()
+	release := make(chan struct{})
+	d := newDispatcher(pauser, func(context.Context, *kgo.Record) bool {
+		<-release
+		return true
+	}, 1, OrderingPartition, 2)
+
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 0}), "dispatch")
+	assert.Equal(t, pauser.paused, map[string][]int32{}, "paused before reaching the limit")
+
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 1}), "dispatch")
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 1, Offset: 0}), "dispatch")
+	assert.Equal(t, pauser.paused, map[string][]int32{"orders": {0}}, "paused at the limit")
+
+	close(release)
+	d.wait()
+
+	select {
+	case resumed := <-pauser.resumed:
+		assert.Equal(t, resumed, map[string][]int32{"orders": {0}}, "resumed partitions")
+	default:
+		t.Fatal("partition was not resumed")
+	}
+	assert.SliceLen(t, d.committable(), 2, "committable records")
+}
+
+func TestDispatcherPauseReasons(t *testing.T) {
+	partition := map[string][]int32{"orders.retry.5s": {0}}
+	held := &kgo.Record{Topic: "orders.retry.5s", Partition: 0, Offset: 2}
+
+	t.Run("hold outlasts back-pressure", func(t *testing.T) {
+		pauser := newFakePauser()
+		release := make(chan struct{})
+		d := newDispatcher(pauser, func(context.Context, *kgo.Record) bool {
+			<-release
+			return true
+		}, 1, OrderingPartition, 2)
+
+		for offset := range int64(2) {
+			require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders.retry.5s", Partition: 0, Offset: offset}), "dispatch")
+		}
+		d.hold(held, time.Hour)
+		close(release)
+		d.wait()
+
+		assert.Equal(t, pauser.paused, partition, "paused once")
+		assert.SliceLen(t, pauser.resumedPartitions(), 0, "held partition resumed by back-pressure")
+		d.release(partition)
+		assert.Equal(t, pauser.resumedPartitions(), []map[string][]int32{partition}, "released partition resumed")
+	})
+
+	t.Run("back-pressure outlasts hold", func(t *testing.T) {
+		pauser := newFakePauser()
+		release := make(chan struct{})
+		d := newDispatcher(pauser, func(context.Context, *kgo.Record) bool {
+			<-release
+			return true
+		}, 1, OrderingPartition, 2)
+
+		for offset := range int64(2) {
+			require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders.retry.5s", Partition: 0, Offset: offset}), "dispatch")
+		}
+		d.hold(held, time.Millisecond)
+		awaitHoldsDone(t, d)
+
+		assert.SliceLen(t, pauser.resumedPartitions(), 0, "saturated partition resumed by hold")
+		close(release)
+		d.wait()
+		assert.Equal(t, pauser.resumedPartitions(), []map[string][]int32{partition}, "partition resumed once handled")
+	})
+}
+
+func TestDispatcherStopsHolds(t *testing.T) {
+	partition := map[string][]int32{"orders.retry.5s": {0}}
+	held := &kgo.Record{Topic: "orders.retry.5s", Partition: 0, Offset: 2}
+
+	tests := map[string]func(d *dispatcher){
+		"release": func(d *dispatcher) { d.release(partition) },
+		"stop":    func(d *dispatcher) { d.stop() },
+	}
+
+	for name, stop := range tests {
+		t.Run(name, func(t *testing.T) {
+			pauser := newFakePauser()
+			d := newDispatcher(pauser, func(context.Context, *kgo.Record) bool { return true }, 1, OrderingPartition, 2)
+
+			d.hold(held, 20*time.Millisecond)
+			stop(d)
+			pauser.resumedPartitions()
+			time.Sleep(50 * time.Millisecond)
+
+			assert.SliceLen(t, pauser.resumedPartitions(), 0, "partitions resumed by a stopped hold")
+		})
+	}
+}
+
+// awaitHoldsDone waits until no hold of d is pending.
+func awaitHoldsDone(t *testing.T, d *dispatcher) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d.mu.Lock()
+		pending := len(d.holds)
+		d.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hold did not end")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcherCommittable(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan int64, 3)
+	d := newDispatcher(newFakePauser(), func(_ context.Context, record *kgo.Record) bool {
+		if record.Offset == 0 {
+			<-release
+		}
+		handled <- record.Offset
+		return true
+	}, 2, OrderingKey, 100)
+
+	for offset, key := range []string{"a", "b", "a"} {
+		require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: int64(offset), Key: []byte(key)}), "dispatch")
+	}
+
+	assert.Equal(t, <-handled, int64(1), "handled while offset 0 is blocked")
+	assert.SliceLen(t, d.committable(), 0, "nothing committable before offset 0 is handled")
+
+	close(release)
+	d.wait()
+
+	assert.Equal(t, offsetsOf(d.committable()), []int64{2}, "last record of the handled prefix")
+	assert.SliceLen(t, d.committable(), 0, "records are returned once")
+}
+
+func TestDispatcherCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	d := newDispatcher(newFakePauser(), func(ctx context.Context, record *kgo.Record) bool {
+		if record.Offset == 1 {
+			close(started)
+			<-ctx.Done()
+			return false
+		}
+		return true
+	}, 1, OrderingPartition, 100)
+
+	for offset := range int64(4) {
+		require.True(t, d.dispatch(ctx, &kgo.Record{Topic: "orders", Partition: 0, Offset: offset}), "dispatch")
+	}
+	<-started
+	cancel()
+	d.wait()
+
+	assert.Equal(t, offsetsOf(d.committable()), []int64{0}, "records interrupted or not handled are not committable")
+	assert.False(t, d.dispatch(ctx, &kgo.Record{Topic: "orders", Partition: 1, Offset: 0}), "dispatch after cancel")
+}
+
+func TestDispatcherFailedRecord(t *testing.T) {
+	d := newDispatcher(newFakePauser(), func(_ context.Context, record *kgo.Record) bool {
+		return record.Offset != 2
+	}, 2, OrderingKey, 100)
+
+	for offset, key := range []string{"a", "b", "a", "b", "a"} {
+		require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: int64(offset), Key: []byte(key)}), "dispatch")
+	}
+	d.wait()
+	assert.Equal(t, offsetsOf(d.committable()), []int64{1}, "prefix before the failed record")
+
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 5, Key: []byte("b")}), "dispatch")
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 1, Offset: 0, Key: []byte("a")}), "dispatch")
+	d.wait()
+	committable := d.committable()
+	require.SliceLen(t, committable, 1, "committable records")
+	assert.Equal(t, committable[0].Partition, int32(1), "failed partition stays blocked")
+}
+
+func TestDispatcherPausesBehindUnhandledRecord(t *testing.T) {
+	partition := map[string][]int32{"orders": {0}}
+	pauser := newFakePauser()
+	d := newDispatcher(pauser, func(_ context.Context, record *kgo.Record) bool {
+		return record.Offset != 0
+	}, 1, OrderingNone, 4)
+
+	for offset := range int64(4) {
+		require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: offset}), "dispatch")
+	}
+	d.wait()
+	assert.Equal(t, pauser.paused, map[string][]int32{}, "paused before exceeding the limit")
+
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 4}), "dispatch")
+	d.wait()
+	assert.Equal(t, pauser.paused, partition, "paused behind the unhandled record")
+	assert.SliceLen(t, pauser.resumedPartitions(), 0, "resumed while the gap stays open")
+	assert.SliceLen(t, d.committable(), 0, "nothing committable past the unhandled record")
+
+	d.release(partition)
+	assert.Equal(t, pauser.resumedPartitions(), []map[string][]int32{partition}, "released partition resumed")
+}
+
+func TestDispatcherRelease(t *testing.T) {
+	pauser := newFakePauser()
+	revoked := &kgo.Record{Topic: "orders", Partition: 0, Offset: 1, Key: []byte("b")}
+	reassigned := &kgo.Record{Topic: "orders", Partition: 0, Offset: 1, Key: []byte("b")}
+	handled := make(chan int64, 1)
+	release, hold, started := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	d := newDispatcher(pauser, func(_ context.Context, record *kgo.Record) bool {
+		switch record {
+		case revoked:
+			<-release
+		case reassigned:
+			close(started)
+			<-hold
+		default:
+			handled <- record.Offset
+		}
+		return true
+	}, 2, OrderingKey, 2)
+
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 0, Key: []byte("a")}), "dispatch")
+	require.True(t, d.dispatch(t.Context(), revoked), "dispatch")
+	assert.Equal(t, <-handled, int64(0), "handled offset")
+	assert.Equal(t, pauser.paused, map[string][]int32{"orders": {0}}, "paused at the limit")
+
+	assert.Equal(t, offsetsOf(d.release(map[string][]int32{"orders": {0}})), []int64{0}, "handled prefix of released partition")
+	assert.Equal(t, <-pauser.resumed, map[string][]int32{"orders": {0}}, "released partition resumed")
+
+	// The partition is assigned again and its uncommitted record redelivered
+	// while the revoked copy is still being handled.
+	require.True(t, d.dispatch(t.Context(), reassigned), "dispatch")
+	close(release)
+	<-started
+	assert.SliceLen(t, d.committable(), 0, "revoked record does not complete the redelivered one")
+
+	close(hold)
+	d.wait()
+	assert.Equal(t, offsetsOf(d.committable()), []int64{1}, "redelivered record committable")
+}
@@ -4,7 +4,11 @@ package kafka_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"go-services/library/kafka"
 	"go-services/library/require"
 
+	"github.com/twmb/franz-go/pkg/kadm"
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
@@ -170,3 +175,638 @@ func TestKafkaConsumerAddTopic(t *testing.T) {
 		}
 	})
 }
+
+func TestKafkaDeadLetterTopic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-dlq-%d", time.Now().UnixNano())
+	dlqTopic := topic + ".dlq"
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+	require.NoError(t, testKafka.CreateTopic(ctx, dlqTopic), "failed to create dead-letter topic")
+
+	var attempts atomic.Int32
+	handler := func(ctx context.Context, record *kgo.Record) error {
+		attempts.Add(1)
+		return errors.New("downstream unavailable")
+	}
+
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		fmt.Sprintf("test-group-dlq-%d", time.Now().UnixNano()),
+		kafka.WithTopic(topic, handler),
+		kafka.WithRetry(kafka.RetryPolicy{
+			IsRetryable:    nil,
+			MaxAttempts:    3,
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     50 * time.Millisecond,
+			Multiplier:     2,
+			Jitter:         0,
+		}),
+		kafka.WithDeadLetterTopic(dlqTopic),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	go func() {
+		if runErr := client.Consumer.Run(ctx); runErr != nil && ctx.Err() == nil {
+			t.Errorf("consumer run failed: %v", runErr)
+		}
+	}()
+
+	err = client.Producer.ProduceSync(ctx, &kgo.Record{Topic: topic, Key: []byte("key"), Value: []byte("poison")})
+	require.NoError(t, err, "failed to produce message")
+
+	dlqReader, err := kgo.NewClient(
+		kgo.SeedBrokers(testKafka.PlainBrokers...),
+		kgo.ConsumeTopics(dlqTopic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	require.NoError(t, err, "failed to create dead-letter reader")
+	defer dlqReader.Close()
+
+	fetches := dlqReader.PollRecords(ctx, 1)
+	require.NoError(t, fetches.Err(), "failed to read dead-letter topic")
+	records := fetches.Records()
+	require.SliceLen(t, records, 1, "dead-lettered records")
+
+	headers := make(map[string]string)
+	for _, h := range records[0].Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	assert.Equal(t, string(records[0].Value), "poison", "dead-letter value")
+	assert.Equal(t, headers[kafka.HeaderOriginalTopic], topic, "original topic header")
+	assert.Equal(t, headers[kafka.HeaderError], "downstream unavailable", "error header")
+	assert.Equal(t, headers[kafka.HeaderAttempts], "3", "attempts header")
+	assert.Equal(t, attempts.Load(), 3, "handler attempts")
+}
+
+func TestKafkaRetryTopics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-retry-topics-%d", time.Now().UnixNano())
+	retryTopic := kafka.RetryTopic(topic, time.Second)
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+	require.NoError(t, testKafka.CreateTopic(ctx, retryTopic), "failed to create retry topic")
+
+	type delivery struct {
+		at      time.Time
+		topic   string
+		attempt int
+	}
+	deliveries := make(chan delivery, 2)
+	handler := func(ctx context.Context, record *kgo.Record) error {
+		deliveries <- delivery{at: time.Now(), topic: record.Topic, attempt: kafka.Attempt(ctx)}
+		if kafka.Attempt(ctx) == 1 {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	}
+
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		fmt.Sprintf("test-group-retry-topics-%d", time.Now().UnixNano()),
+		kafka.WithTopic(topic, handler),
+		kafka.WithRetryTopics(time.Second),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	go func() {
+		if runErr := client.Consumer.Run(ctx); runErr != nil && ctx.Err() == nil {
+			t.Errorf("consumer run failed: %v", runErr)
+		}
+	}()
+
+	err = client.Producer.ProduceSync(ctx, &kgo.Record{Topic: topic, Value: []byte("retry-me")})
+	require.NoError(t, err, "failed to produce message")
+
+	var got []delivery
+	for len(got) < 2 {
+		select {
+		case d := <-deliveries:
+			got = append(got, d)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for deliveries, got %d", len(got))
+		}
+	}
+
+	assert.Equal(t, got[0].topic, topic, "first delivery topic")
+	assert.Equal(t, got[0].attempt, 1, "first attempt")
+	assert.Equal(t, got[1].topic, retryTopic, "second delivery topic")
+	assert.Equal(t, got[1].attempt, 2, "second attempt")
+	assert.True(t, got[1].at.Sub(got[0].at) >= time.Second, "retry delayed by the tier delay")
+}
+
+func TestKafkaOrderingByKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-ordering-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	const perKey = 10
+	keys := []string{"user-a", "user-b", "user-c"}
+
+	type delivery struct {
+		key   string
+		value string
+	}
+	deliveries := make(chan delivery, perKey*len(keys))
+	handler := func(_ context.Context, record *kgo.Record) error {
+		// Later records finish faster, so they would overtake earlier ones
+		// without ordering.
+		seq, err := strconv.Atoi(string(record.Value))
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(perKey-seq) * 5 * time.Millisecond)
+		deliveries <- delivery{key: string(record.Key), value: string(record.Value)}
+		return nil
+	}
+
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		fmt.Sprintf("test-group-ordering-%d", time.Now().UnixNano()),
+		kafka.WithTopic(topic, handler),
+		kafka.WithWorkers(4),
+		kafka.WithOrdering(kafka.OrderingKey),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	records := make([]*kgo.Record, 0, perKey*len(keys))
+	want := make(map[string][]string, len(keys))
+	for i := range perKey {
+		for _, key := range keys {
+			value := strconv.Itoa(i)
+			records = append(records, &kgo.Record{Topic: topic, Key: []byte(key), Value: []byte(value)})
+			want[key] = append(want[key], value)
+		}
+	}
+	for _, record := range records {
+		require.NoError(t, client.Producer.ProduceSync(ctx, record), "failed to produce message")
+	}
+
+	go func() {
+		if runErr := client.Consumer.Run(ctx); runErr != nil && ctx.Err() == nil {
+			t.Errorf("consumer run failed: %v", runErr)
+		}
+	}()
+
+	got := make(map[string][]string, len(keys))
+	for range records {
+		select {
+		case d := <-deliveries:
+			got[d.key] = append(got[d.key], d.value)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for deliveries, got %v", got)
+		}
+	}
+
+	assert.Equal(t, got, want, "values handled per key")
+}
+
+// committedOffset returns the offset committed by group for the single
+// partition of topic, or -1 if there is none.
+func committedOffset(ctx context.Context, t *testing.T, group, topic string) int64 {
+	t.Helper()
+	cl, err := kgo.NewClient(kgo.SeedBrokers(testKafka.PlainBrokers...))
+	require.NoError(t, err, "failed to create admin client")
+	defer cl.Close()
+
+	offsets, err := kadm.NewClient(cl).FetchOffsets(ctx, group)
+	require.NoError(t, err, "failed to fetch committed offsets")
+	committed, ok := offsets.Lookup(topic, 0)
+	if !ok {
+		return -1
+	}
+	return committed.At
+}
+
+// produceValues produces one record per value to topic.
+func produceValues(ctx context.Context, t *testing.T, producer *kafka.Producer, topic string, values ...string) {
+	t.Helper()
+	for _, value := range values {
+		err := producer.ProduceSync(ctx, &kgo.Record{Topic: topic, Value: []byte(value)})
+		require.NoError(t, err, "failed to produce message")
+	}
+}
+
+// runConsumer runs client's consumer until the returned function is called,
+// which waits for Run to return.
+func runConsumer(ctx context.Context, t *testing.T, client *kafka.Client) func() {
+	t.Helper()
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if runErr := client.Consumer.Run(runCtx); runErr != nil && runCtx.Err() == nil {
+			t.Errorf("consumer run failed: %v", runErr)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestKafkaCommitsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-commit-shutdown-%d", time.Now().UnixNano())
+	group := fmt.Sprintf("test-group-commit-shutdown-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	handled := make(chan string, 3)
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		group,
+		kafka.WithTopic(topic, func(_ context.Context, record *kgo.Record) error {
+			handled <- string(record.Value)
+			return nil
+		}),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	produceValues(ctx, t, client.Producer, topic, "a", "b", "c")
+	stop := runConsumer(ctx, t, client)
+	for range 3 {
+		select {
+		case <-handled:
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for records")
+		}
+	}
+	stop()
+
+	assert.Equal(t, committedOffset(ctx, t, group, topic), int64(3), "committed offset after shutdown")
+}
+
+func TestKafkaFailedRecordWithoutDeadLetterTopicIsDropped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-commit-failed-%d", time.Now().UnixNano())
+	group := fmt.Sprintf("test-group-commit-failed-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	handled := make(chan string, 6)
+	handler := func(_ context.Context, record *kgo.Record) error {
+		handled <- string(record.Value)
+		if string(record.Value) == "poison" {
+			return kafka.Permanent(errors.New("cannot handle record"))
+		}
+		return nil
+	}
+	newClient := func() *kafka.Client {
+		client, err := kafka.New(
+			testKafka.PlainBrokers,
+			group,
+			kafka.WithTopic(topic, handler),
+			kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+		)
+		require.NoError(t, err, "failed to create kafka client")
+		return client
+	}
+	receive := func(n int) []string {
+		var got []string
+		for len(got) < n {
+			select {
+			case value := <-handled:
+				got = append(got, value)
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for records, got %v", got)
+			}
+		}
+		return got
+	}
+
+	first := newClient()
+	produceValues(ctx, t, first.Producer, topic, "a", "poison", "b")
+	stop := runConsumer(ctx, t, first)
+	assert.Equal(t, receive(3), []string{"a", "poison", "b"}, "first delivery")
+	stop()
+	first.Close()
+
+	assert.Equal(t, committedOffset(ctx, t, group, topic), int64(3), "committed offset past the dropped record")
+
+	second := newClient()
+	defer second.Close()
+	produceValues(ctx, t, second.Producer, topic, "c")
+	stop = runConsumer(ctx, t, second)
+	defer stop()
+	assert.Equal(t, receive(1), []string{"c"}, "dropped record is not redelivered")
+}
+
+func TestKafkaCommitsOnRevoke(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-commit-revoke-%d", time.Now().UnixNano())
+	group := fmt.Sprintf("test-group-commit-revoke-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	handled := make(chan string, 2)
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		group,
+		kafka.WithTopic(topic, func(_ context.Context, record *kgo.Record) error {
+			handled <- string(record.Value)
+			return nil
+		}),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+
+	produceValues(ctx, t, client.Producer, topic, "a", "b")
+	stop := runConsumer(ctx, t, client)
+	defer stop()
+	for range 2 {
+		select {
+		case <-handled:
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for records")
+		}
+	}
+
+	// Closing the client leaves the group, which revokes the partition while
+	// the consumer is still running.
+	client.Close()
+
+	assert.Equal(t, committedOffset(ctx, t, group, topic), int64(2), "committed offset after revocation")
+}
+
+func TestKafkaRebalanceHooks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-rebalance-%d", time.Now().UnixNano())
+	group := fmt.Sprintf("test-group-rebalance-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	assigned := make(chan map[string][]int32, 1)
+	revoked := make(chan bool, 1)
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		group,
+		kafka.WithTopic(topic, func(context.Context, *kgo.Record) error {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			finished.Store(true)
+			return nil
+		}),
+		kafka.WithOnAssigned(func(_ context.Context, partitions map[string][]int32) {
+			assigned <- partitions
+		}),
+		kafka.WithOnRevoked(func(context.Context, map[string][]int32) {
+			revoked <- finished.Load()
+		}),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+
+	produceValues(ctx, t, client.Producer, topic, "slow")
+	stop := runConsumer(ctx, t, client)
+	defer stop()
+
+	select {
+	case partitions := <-assigned:
+		assert.Equal(t, partitions, map[string][]int32{topic: {0}}, "assigned partitions")
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for assignment")
+	}
+	assert.Equal(t, client.Consumer.Assignment(), map[string][]int32{topic: {0}}, "consumer assignment")
+
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the record")
+	}
+	// Leaving the group revokes the partition while the record is handled.
+	client.Close()
+
+	select {
+	case handled := <-revoked:
+		assert.True(t, handled, "record handled before the revoke hook")
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for revocation")
+	}
+	assert.Equal(t, committedOffset(ctx, t, group, topic), int64(1), "committed offset after revocation")
+}
+
+// produceInput produces one record per value to topic with a
+// non-transactional client.
+func produceInput(ctx context.Context, t *testing.T, topic string, values ...string) {
+	t.Helper()
+	cl, err := kgo.NewClient(kgo.SeedBrokers(testKafka.PlainBrokers...))
+	require.NoError(t, err, "failed to create producer client")
+	defer cl.Close()
+
+	for _, value := range values {
+		err := cl.ProduceSync(ctx, &kgo.Record{Topic: topic, Value: []byte(value)}).FirstErr()
+		require.NoError(t, err, "failed to produce message")
+	}
+}
+
+// readCommitted reads the committed records of topic until at least want
+// records were read and no more arrive within a second, and returns their
+// sorted values.
+func readCommitted(ctx context.Context, t *testing.T, topic string, want int) []string {
+	t.Helper()
+	reader, err := kgo.NewClient(
+		kgo.SeedBrokers(testKafka.PlainBrokers...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+	)
+	require.NoError(t, err, "failed to create reader")
+	defer reader.Close()
+
+	var values []string
+	for {
+		pollCtx, cancel := ctx, context.CancelFunc(func() {})
+		if len(values) >= want {
+			pollCtx, cancel = context.WithTimeout(ctx, time.Second)
+		}
+		fetches := reader.PollFetches(pollCtx)
+		done := len(values) >= want && pollCtx.Err() != nil
+		cancel()
+		if done {
+			slices.Sort(values)
+			return values
+		}
+		require.NoError(t, fetches.Err(), "failed to read %s, got %v", topic, values)
+		for _, record := range fetches.Records() {
+			values = append(values, string(record.Value))
+		}
+	}
+}
+
+func TestKafkaTransactionAbortsFailedBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	input := fmt.Sprintf("test-txn-input-%d", time.Now().UnixNano())
+	output := input + ".out"
+	group := fmt.Sprintf("test-group-txn-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, input), "failed to create input topic")
+	require.NoError(t, testKafka.CreateTopic(ctx, output), "failed to create output topic")
+
+	var calls atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	var client *kafka.Client
+	handler := func(ctx context.Context, record *kgo.Record) error {
+		calls.Add(1)
+		err := client.Producer.ProduceSync(ctx, &kgo.Record{Topic: output, Value: record.Value})
+		if err != nil {
+			return err
+		}
+		if string(record.Value) == "c" && failing.CompareAndSwap(true, false) {
+			return errors.New("induced failure")
+		}
+		return nil
+	}
+
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		group,
+		kafka.WithTopic(input, handler),
+		kafka.WithTransactionalID(group+"-0"),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	produceInput(ctx, t, input, "a", "b", "c", "d", "e")
+	stop := runConsumer(ctx, t, client)
+	defer stop()
+
+	got := readCommitted(ctx, t, output, 5)
+	assert.Equal(t, got, []string{"a", "b", "c", "d", "e"}, "committed output records")
+	assert.Greater(t, calls.Load(), int32(5), "handler calls, including the aborted batch")
+	assert.Equal(t, committedOffset(ctx, t, group, input), int64(5), "committed input offset")
+}
+
+func TestKafkaTransactionResumesAfterRestart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	input := fmt.Sprintf("test-txn-restart-input-%d", time.Now().UnixNano())
+	output := input + ".out"
+	group := fmt.Sprintf("test-group-txn-restart-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, input), "failed to create input topic")
+	require.NoError(t, testKafka.CreateTopic(ctx, output), "failed to create output topic")
+
+	blocked := make(chan struct{})
+	var blocking atomic.Bool
+	blocking.Store(true)
+	newClient := func() *kafka.Client {
+		var client *kafka.Client
+		handler := func(ctx context.Context, record *kgo.Record) error {
+			err := client.Producer.ProduceSync(ctx, &kgo.Record{Topic: output, Value: record.Value})
+			if err != nil {
+				return err
+			}
+			if string(record.Value) == "c" && blocking.CompareAndSwap(true, false) {
+				// The consumer stops while the transaction is open.
+				close(blocked)
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		}
+
+		client, err := kafka.New(
+			testKafka.PlainBrokers,
+			group,
+			kafka.WithTopic(input, handler),
+			kafka.WithTransactionalID(group+"-0"),
+			kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+		)
+		require.NoError(t, err, "failed to create kafka client")
+		return client
+	}
+
+	produceInput(ctx, t, input, "a", "b", "c", "d", "e")
+
+	first := newClient()
+	stop := runConsumer(ctx, t, first)
+	select {
+	case <-blocked:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the blocking record")
+	}
+	stop()
+	first.Close()
+
+	second := newClient()
+	defer second.Close()
+	stop = runConsumer(ctx, t, second)
+	defer stop()
+
+	got := readCommitted(ctx, t, output, 5)
+	assert.Equal(t, got, []string{"a", "b", "c", "d", "e"}, "committed output records")
+	assert.Equal(t, committedOffset(ctx, t, group, input), int64(5), "committed input offset")
+}
+
+func TestKafkaBatchTopic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("test-batch-%d", time.Now().UnixNano())
+	group := fmt.Sprintf("test-group-batch-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	batches := make(chan []string, 10)
+	client, err := kafka.New(
+		testKafka.PlainBrokers,
+		group,
+		kafka.WithBatchTopic(topic, func(_ context.Context, records []*kgo.Record) error {
+			values := make([]string, len(records))
+			for i, record := range records {
+				values[i] = string(record.Value)
+			}
+			batches <- values
+			return nil
+		}),
+		kafka.WithBatchSize(4),
+		kafka.WithBatchLinger(200*time.Millisecond),
+		kafka.WithOrdering(kafka.OrderingPartition),
+		kafka.WithKgoOptions(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())),
+	)
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	values := make([]string, 10)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	produceValues(ctx, t, client.Producer, topic, values...)
+	stop := runConsumer(ctx, t, client)
+
+	var got []string
+	for len(got) < len(values) {
+		select {
+		case batch := <-batches:
+			assert.LessOrEqual(t, len(batch), 4, "batch size")
+			got = append(got, batch...)
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for batches, got %v", got)
+		}
+	}
+	stop()
+
+	assert.Equal(t, got, values, "records in offset order")
+	assert.Equal(t, committedOffset(ctx, t, group, topic), int64(10), "committed offset")
+}
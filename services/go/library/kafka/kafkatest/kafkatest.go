@@ -0,0 +1,334 @@
+// Package kafkatest provides an in-process Kafka cluster for tests, based on
+// franz-go's kfake. Unlike testenv.SetupKafka, it needs no containers, so
+// tests using it run without the integration build tag. It supports consumer
+// groups, transactions and SASL/SCRAM authentication.
+package kafkatest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"go-services/library/kafka"
+)
+
+const (
+	// scramMechanism is the SASL mechanism of the cluster's superuser.
+	scramMechanism = "SCRAM-SHA-512"
+	defaultUser    = "admin"
+	defaultPass    = "password"
+	// produceTimeout bounds producing fixtures.
+	produceTimeout = 10 * time.Second
+)
+
+// Option configures a Cluster.
+type Option func(*config)
+
+type config struct {
+	topics     map[string]int32
+	partitions int
+	sasl       bool
+}
+
+// WithSASL requires clients to authenticate with SASL/SCRAM-SHA-512 as the
+// cluster's superuser, whose credentials are Cluster.Username and
+// Cluster.Password.
+func WithSASL() Option {
+	return func(c *config) {
+		c.sasl = true
+	}
+}
+
+// WithTopics creates topics with the given number of partitions when the
+// cluster starts.
+func WithTopics(partitions int32, topics ...string) Option {
+	return func(c *config) {
+		for _, topic := range topics {
+			c.topics[topic] = partitions
+		}
+	}
+}
+
+// WithPartitions sets the number of partitions of topics created by
+// CreateTopic or by producing to a topic that does not exist. The default is
+// one, so records are consumed in the order they are produced.
+func WithPartitions(partitions int) Option {
+	return func(c *config) {
+		c.partitions = partitions
+	}
+}
+
+// Cluster is an in-process Kafka cluster.
+type Cluster struct {
+	cluster *kfake.Cluster
+	// Username is the SCRAM-SHA-512 username of the superuser, if the
+	// cluster was started WithSASL.
+	Username string
+	// Password is the SCRAM-SHA-512 password of the superuser, if the
+	// cluster was started WithSASL.
+	Password string
+	sasl     bool
+}
+
+// NewCluster starts a cluster that is closed when the test ends.
+func NewCluster(tb testing.TB, opts ...Option) *Cluster {
+	tb.Helper()
+
+	cfg := &config{
+		topics:     make(map[string]int32),
+		partitions: 1,
+		sasl:       false,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	kfakeOpts := []kfake.Opt{
+		kfake.AllowAutoTopicCreation(),
+		kfake.DefaultNumPartitions(cfg.partitions),
+	}
+	for topic, partitions := range cfg.topics {
+		kfakeOpts = append(kfakeOpts, kfake.SeedTopics(partitions, topic))
+	}
+	c := &Cluster{
+		cluster:  nil,
+		Username: "",
+		Password: "",
+		sasl:     cfg.sasl,
+	}
+	if cfg.sasl {
+		c.Username, c.Password = defaultUser, defaultPass
+		kfakeOpts = append(kfakeOpts,
+			kfake.EnableSASL(),
+			kfake.Superuser(scramMechanism, c.Username, c.Password),
+		)
+	}
+
+	cluster, err := kfake.NewCluster(kfakeOpts...)
+	if err != nil {
+		tb.Fatalf("failed to start kafka cluster: %v", err)
+	}
+	tb.Cleanup(cluster.Close)
+	c.cluster = cluster
+
+	return c
+}
+
+// Brokers returns the addresses of the cluster's brokers.
+func (c *Cluster) Brokers() []string {
+	return c.cluster.ListenAddrs()
+}
+
+// NewClient returns a client of the cluster that is closed when the test
+// ends. It authenticates as the superuser if the cluster requires SASL,
+// creates the topics it produces to, and starts consuming new groups at the
+// earliest offset, so records produced before the consumer joins are consumed
+// too. opts may override these defaults.
+func (c *Cluster) NewClient(tb testing.TB, groupID string, opts ...kafka.Option) *kafka.Client {
+	tb.Helper()
+
+	defaults := []kafka.Option{
+		kafka.WithKgoOptions(
+			kgo.AllowAutoTopicCreation(),
+			kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		),
+	}
+	if c.sasl {
+		defaults = append(defaults, kafka.WithAuth(c.Username, c.Password, kafka.AuthMechanismScram512))
+	}
+
+	client, err := kafka.New(c.Brokers(), groupID, slices.Concat(defaults, opts)...)
+	if err != nil {
+		tb.Fatalf("failed to create kafka client: %v", err)
+	}
+	tb.Cleanup(client.Close)
+	return client
+}
+
+// CreateTopic creates topic with the cluster's default number of partitions.
+func (c *Cluster) CreateTopic(tb testing.TB, topic string) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(tb.Context(), produceTimeout)
+	defer cancel()
+
+	admin := kadm.NewClient(c.kgoClient(tb))
+	resp, err := admin.CreateTopic(ctx, -1, -1, nil, topic)
+	if err == nil {
+		err = resp.Err
+	}
+	if err != nil {
+		tb.Fatalf("failed to create topic %q: %v", topic, err)
+	}
+}
+
+// Produce produces records synchronously, failing the test if any of them
+// is not acknowledged.
+func (c *Cluster) Produce(tb testing.TB, records ...*kgo.Record) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(tb.Context(), produceTimeout)
+	defer cancel()
+
+	if err := c.kgoClient(tb).ProduceSync(ctx, records...).FirstErr(); err != nil {
+		tb.Fatalf("failed to produce records: %v", err)
+	}
+}
+
+// ProduceValues produces one record per value to topic, in order.
+func (c *Cluster) ProduceValues(tb testing.TB, topic string, values ...string) {
+	tb.Helper()
+
+	records := make([]*kgo.Record, 0, len(values))
+	for _, value := range values {
+		records = append(records, &kgo.Record{Topic: topic, Value: []byte(value)})
+	}
+	c.Produce(tb, records...)
+}
+
+// Consume reads the first n committed records of topic, without a consumer
+// group, e.g. to assert what a producer or a transactional consumer wrote. It
+// fails the test if fewer records arrive within timeout.
+func (c *Cluster) Consume(tb testing.TB, topic string, n int, timeout time.Duration) []*kgo.Record {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(tb.Context(), timeout)
+	defer cancel()
+
+	cl := c.kgoClient(tb,
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+	)
+	records := make([]*kgo.Record, 0, n)
+	for len(records) < n {
+		fetches := cl.PollFetches(ctx)
+		if ctx.Err() != nil {
+			tb.Fatalf("timed out after %s consuming %q: got %d of %d records", timeout, topic, len(records), n)
+		}
+		if err := fetches.Err(); err != nil {
+			tb.Fatalf("failed to consume %q: %v", topic, err)
+		}
+		records = append(records, fetches.Records()...)
+	}
+	return records[:n]
+}
+
+// kgoClient returns a franz-go client of the cluster that is closed when the
+// test ends.
+func (c *Cluster) kgoClient(tb testing.TB, opts ...kgo.Opt) *kgo.Client {
+	tb.Helper()
+
+	opts = append([]kgo.Opt{kgo.SeedBrokers(c.Brokers()...), kgo.AllowAutoTopicCreation()}, opts...)
+	if c.sasl {
+		auth := scram.Auth{User: c.Username, Pass: c.Password}
+		opts = append(opts, kgo.SASL(auth.AsSha512Mechanism()))
+	}
+
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		tb.Fatalf("failed to create franz-go client: %v", err)
+	}
+	tb.Cleanup(cl.Close)
+	return cl
+}
+
+// Run runs client's consumer in the background until the test ends, failing
+// the test if it stops with an error.
+func Run(tb testing.TB, client *kafka.Client) {
+	tb.Helper()
+
+	// The test's context is canceled before its cleanup functions run.
+	ctx := tb.Context()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := client.Consumer.Run(ctx); err != nil && ctx.Err() == nil {
+			tb.Errorf("consumer run failed: %v", err)
+		}
+	}()
+	tb.Cleanup(func() { <-done })
+}
+
+// Recorder records the records passed to its Handle method, a kafka.Handler,
+// so tests can await their consumption.
+type Recorder struct {
+	// err is returned by Handle, if set.
+	err error
+	// notify is closed and replaced whenever a record is recorded.
+	notify  chan struct{}
+	records []*kgo.Record
+	mu      sync.Mutex
+}
+
+// NewRecorder returns a Recorder whose Handle returns err, or succeeds if err
+// is nil.
+func NewRecorder(err error) *Recorder {
+	return &Recorder{
+		err:     err,
+		notify:  make(chan struct{}),
+		records: nil,
+		mu:      sync.Mutex{},
+	}
+}
+
+// Handle records record and returns the recorder's error.
+func (r *Recorder) Handle(_ context.Context, record *kgo.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, record)
+	close(r.notify)
+	r.notify = make(chan struct{})
+	return r.err
+}
+
+// Records returns the records handled so far, in the order they were
+// handled.
+func (r *Recorder) Records() []*kgo.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.records)
+}
+
+// Await waits until at least n records were handled and returns the first
+// n. It fails the test if they are not handled within timeout.
+func (r *Recorder) Await(tb testing.TB, n int, timeout time.Duration) []*kgo.Record {
+	tb.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		records, notify := slices.Clone(r.records), r.notify
+		r.mu.Unlock()
+		if len(records) >= n {
+			return records[:n]
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			tb.Fatalf("timed out after %s awaiting records: got %d of %d", timeout, len(records), n)
+			return nil
+		}
+	}
+}
+
+// Values returns the values of records as strings.
+func Values(records []*kgo.Record) []string {
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		values = append(values, string(record.Value))
+	}
+	return values
+}
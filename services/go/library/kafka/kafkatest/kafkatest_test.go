@@ -0,0 +1,120 @@
+package kafkatest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/kafka"
+	"go-services/library/kafka/kafkatest"
+	"go-services/library/require"
+)
+
+const timeout = 10 * time.Second
+
+func TestConsumerHandlesFixtures(t *testing.T) {
+	tests := map[string]struct {
+		opts []kafkatest.Option
+	}{
+		"no-auth": {opts: nil},
+		"sasl":    {opts: []kafkatest.Option{kafkatest.WithSASL()}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cluster := kafkatest.NewCluster(t, tt.opts...)
+			cluster.ProduceValues(t, "orders", "a", "b", "c")
+
+			recorder := kafkatest.NewRecorder(nil)
+			client := cluster.NewClient(t, "group", kafka.WithTopic("orders", recorder.Handle))
+			kafkatest.Run(t, client)
+
+			records := recorder.Await(t, 3, timeout)
+			assert.Equal(t, kafkatest.Values(records), []string{"a", "b", "c"}, "handled values")
+		})
+	}
+}
+
+func TestProducerOutputIsConsumed(t *testing.T) {
+	cluster := kafkatest.NewCluster(t, kafkatest.WithSASL(), kafkatest.WithTopics(1, "events"))
+	client := cluster.NewClient(t, "group")
+
+	for _, value := range []string{"created", "deleted"} {
+		err := client.Producer.ProduceSync(t.Context(), &kgo.Record{Topic: "events", Value: []byte(value)})
+		require.NoError(t, err, "failed to produce %q", value)
+	}
+
+	records := cluster.Consume(t, "events", 2, timeout)
+	assert.Equal(t, kafkatest.Values(records), []string{"created", "deleted"}, "consumed values")
+}
+
+func TestSASLAuthentication(t *testing.T) {
+	tests := map[string]struct {
+		password string
+		wantErr  bool
+	}{
+		"superuser":      {password: "", wantErr: false},
+		"wrong password": {password: "wrong", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cluster := kafkatest.NewCluster(t, kafkatest.WithSASL())
+			password := cluster.Password
+			if tt.password != "" {
+				password = tt.password
+			}
+			client := cluster.NewClient(t, "group", kafka.WithAuth(cluster.Username, password, kafka.AuthMechanismScram512))
+
+			ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+			defer cancel()
+			err := client.Producer.ProduceSync(ctx, &kgo.Record{Topic: "events", Value: []byte("created")})
+
+			if tt.wantErr {
+				assert.Error(t, err, "produce with a wrong password")
+				return
+			}
+			assert.NoError(t, err, "produce as the superuser")
+		})
+	}
+}
+
+func TestTransactionalConsumer(t *testing.T) {
+	cluster := kafkatest.NewCluster(t)
+	cluster.CreateTopic(t, "input")
+	cluster.CreateTopic(t, "output")
+	cluster.ProduceValues(t, "input", "a", "b")
+
+	var client *kafka.Client
+	handler := func(ctx context.Context, record *kgo.Record) error {
+		return client.Producer.ProduceSync(ctx, &kgo.Record{Topic: "output", Value: record.Value})
+	}
+	client = cluster.NewClient(t, "group",
+		kafka.WithTopic("input", handler),
+		kafka.WithTransactionalID("group-0"),
+	)
+	kafkatest.Run(t, client)
+
+	records := cluster.Consume(t, "output", 2, timeout)
+	assert.Equal(t, kafkatest.Values(records), []string{"a", "b"}, "committed output values")
+}
+
+func TestRecorder(t *testing.T) {
+	errUnavailable := errors.New("downstream unavailable")
+	recorder := kafkatest.NewRecorder(errUnavailable)
+
+	go func() {
+		for _, value := range []string{"a", "b"} {
+			err := recorder.Handle(context.Background(), &kgo.Record{Value: []byte(value)})
+			assert.ErrorIs(t, err, errUnavailable, "handle error")
+		}
+	}()
+
+	records := recorder.Await(t, 2, timeout)
+	assert.Equal(t, kafkatest.Values(records), []string{"a", "b"}, "awaited values")
+	assert.Equal(t, kafkatest.Values(recorder.Records()), []string{"a", "b"}, "recorded values")
+}
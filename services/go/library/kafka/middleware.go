@@ -0,0 +1,126 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Middleware wraps a Handler to add behavior to every record it handles,
+// such as logging, timeouts or panic recovery.
+type Middleware func(Handler) Handler
+
+// chain wraps handler with middleware. The first middleware is the
+// outermost, so it sees every record first and every error last.
+func chain(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// ErrPanic is matched by errors returned from handlers wrapped with Recover
+// that panicked.
+var ErrPanic = errors.New("handler panicked")
+
+// Recover returns a middleware that recovers from panics in the handler, so
+// a single record cannot crash the service. The panic value and stack trace
+// are logged at the error level together with the record's coordinates, and
+// the record fails with a permanent error matching ErrPanic, so it is
+// dead-lettered instead of retried.
+//
+// Recover should be the first, outermost middleware so it also covers the
+// middleware after it.
+func Recover(log *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, record *kgo.Record) (err error) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+
+				log.ErrorContext(ctx, "Kafka handler panicked",
+					"topic", record.Topic,
+					"partition", record.Partition,
+					"offset", record.Offset,
+					"err", fmt.Sprint(rec),
+					"stack", string(debug.Stack()))
+				err = Permanent(fmt.Errorf("%w: %v", ErrPanic, rec))
+			}()
+
+			return next(ctx, record)
+		}
+	}
+}
+
+// Timeout returns a middleware that cancels the context of every handler
+// call after d. A handler failing because of it returns an error matching
+// context.DeadlineExceeded, which IsRetryable classifies as retryable. The
+// handler must respect its context for the timeout to take effect.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, record *kgo.Record) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			err := next(timeoutCtx, record)
+			if err != nil && ctx.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("handler timed out after %s: %w", d, err)
+			}
+			return err
+		}
+	}
+}
+
+// Logging returns a middleware that logs every handler call with the
+// record's coordinates, its attempt and the time it took: successful calls
+// at the debug level and failed ones at the warn level.
+func Logging(log *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, record *kgo.Record) error {
+			start := time.Now()
+			err := next(ctx, record)
+
+			attrs := []any{
+				"topic", record.Topic,
+				"partition", record.Partition,
+				"offset", record.Offset,
+				"attempt", Attempt(ctx),
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				log.WarnContext(ctx, "Kafka record failed", append(attrs, "err", err)...)
+				return err
+			}
+			log.DebugContext(ctx, "Kafka record handled", attrs...)
+			return nil
+		}
+	}
+}
+
+// Classify returns a middleware that passes every handler error through
+// classify before the consumer sees it. classify returns the error wrapped
+// with Permanent or Skip to stop retrying it, or any other error, e.g.
+//
+//	kafka.Classify(func(err error) error {
+//		if errors.Is(err, pgx.ErrNoRows) {
+//			return kafka.Skip(err)
+//		}
+//		return err
+//	})
+func Classify(classify func(err error) error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, record *kgo.Record) error {
+			if err := next(ctx, record); err != nil {
+				return classify(err)
+			}
+			return nil
+		}
+	}
+}
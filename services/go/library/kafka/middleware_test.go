@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+var errUnavailable = errors.New("downstream unavailable")
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, record *kgo.Record) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, record)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+	handler := func(context.Context, *kgo.Record) error {
+		calls = append(calls, "handler")
+		return nil
+	}
+
+	err := chain(handler, []Middleware{trace("outer"), trace("inner")})(t.Context(), &kgo.Record{})
+
+	require.NoError(t, err, "handle record")
+	assert.Equal(t, calls, []string{"outer before", "inner before", "handler", "inner after", "outer after"}, "call order")
+}
+
+func TestRecover(t *testing.T) {
+	tests := map[string]struct {
+		handler   Handler
+		wantErr   error
+		wantPanic bool
+	}{
+		"no panic": {
+			handler:   func(context.Context, *kgo.Record) error { return nil },
+			wantErr:   nil,
+			wantPanic: false,
+		},
+		"error": {
+			handler:   func(context.Context, *kgo.Record) error { return errUnavailable },
+			wantErr:   errUnavailable,
+			wantPanic: false,
+		},
+		"panic": {
+			handler:   func(context.Context, *kgo.Record) error { panic("nil map") },
+			wantErr:   ErrPanic,
+			wantPanic: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, capture := testlogger.New()
+			record := &kgo.Record{Topic: "orders", Partition: 1, Offset: 42}
+
+			err := Recover(log)(tt.handler)(t.Context(), record)
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err, "handle record")
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr, "handle record")
+			}
+			if !tt.wantPanic {
+				testlogger.Assert(t, capture.GetOutput()).Empty("panic logs")
+				return
+			}
+			assert.ErrorContains(t, err, "handler panicked: nil map", "error message")
+			assert.False(t, IsRetryable(err), "panics are not retried")
+			testlogger.Assert(t, capture.GetOutput()).
+				Count(1, "panic logs").
+				AtIndex(0, slog.LevelError, "Kafka handler panicked", "panic log").
+				HasField(0, "topic", "orders", "panic log topic")
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := map[string]struct {
+		handler Handler
+		wantErr string
+	}{
+		"done in time": {
+			handler: func(context.Context, *kgo.Record) error { return nil },
+			wantErr: "",
+		},
+		"timed out": {
+			handler: func(ctx context.Context, _ *kgo.Record) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantErr: "handler timed out after 10ms: context deadline exceeded",
+		},
+		"other error": {
+			handler: func(context.Context, *kgo.Record) error { return errUnavailable },
+			wantErr: errUnavailable.Error(),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Timeout(10*time.Millisecond)(tt.handler)(t.Context(), &kgo.Record{})
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err, "handle record")
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr, "handle record")
+			assert.Equal(t, errors.Is(err, context.DeadlineExceeded), name == "timed out", "deadline exceeded")
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	tests := map[string]struct {
+		err       error
+		wantMsg   string
+		wantLevel slog.Level
+	}{
+		"handled": {
+			err:       nil,
+			wantMsg:   "Kafka record handled",
+			wantLevel: slog.LevelDebug,
+		},
+		"failed": {
+			err:       errUnavailable,
+			wantMsg:   "Kafka record failed",
+			wantLevel: slog.LevelWarn,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, capture := testlogger.New()
+			handler := func(context.Context, *kgo.Record) error { return tt.err }
+			record := &kgo.Record{Topic: "orders", Partition: 1, Offset: 42}
+
+			err := Logging(log)(handler)(t.Context(), record)
+
+			assert.ErrorIs(t, err, tt.err, "handle record")
+			testlogger.Assert(t, capture.GetOutput()).
+				Count(1, "record logs").
+				AtIndex(0, tt.wantLevel, tt.wantMsg, "record log").
+				HasField(0, "topic", "orders", "record log topic")
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	errIrrelevant := errors.New("irrelevant record")
+	classify := Classify(func(err error) error {
+		if errors.Is(err, errIrrelevant) {
+			return Skip(err)
+		}
+		return err
+	})
+
+	tests := map[string]struct {
+		err           error
+		wantSkip      bool
+		wantRetryable bool
+	}{
+		"nil":       {err: nil, wantSkip: false, wantRetryable: true},
+		"skipped":   {err: errIrrelevant, wantSkip: true, wantRetryable: false},
+		"unchanged": {err: errUnavailable, wantSkip: false, wantRetryable: true},
+		"permanent": {err: Permanent(errUnavailable), wantSkip: false, wantRetryable: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := func(context.Context, *kgo.Record) error { return tt.err }
+
+			err := classify(handler)(t.Context(), &kgo.Record{})
+
+			assert.ErrorIs(t, err, tt.err, "handle record")
+			assert.Equal(t, errors.Is(err, ErrSkip), tt.wantSkip, "skipped")
+			if err != nil {
+				assert.Equal(t, IsRetryable(err), tt.wantRetryable, "retryable")
+			}
+		})
+	}
+}
+
+func TestConsumerAppliesMiddleware(t *testing.T) {
+	var wrapped []string
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	WithHandlerMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, record *kgo.Record) error {
+			wrapped = append(wrapped, record.Topic)
+			return next(ctx, record)
+		}
+	})(cfg)
+	cfg.topicRouter["startup"] = func(context.Context, *kgo.Record) error { return nil }
+
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+	err = consumer.AddTopic("runtime", func(context.Context, *kgo.Record) error { return nil })
+	require.NoError(t, err, "failed to add topic")
+
+	for _, topic := range []string{"startup", "runtime"} {
+		handler, ok := consumer.handlerForTopic(topic)
+		require.True(t, ok, "handler for %s", topic)
+		require.NoError(t, handler(t.Context(), &kgo.Record{Topic: topic}), "handle %s record", topic)
+	}
+	assert.Equal(t, wrapped, []string{"startup", "runtime"}, "records seen by the middleware")
+}
@@ -1,14 +1,19 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
 // AuthConfig contains credentials and mechanism for SASL authentication.
 type AuthConfig struct {
+	// Provider, if set, supplies the username and password instead of
+	// Username and Password.
+	Provider CredentialsProvider
 	// Username for authentication.
 	Username string
 	// Password for authentication.
@@ -17,38 +22,93 @@ type AuthConfig struct {
 	Mechanism AuthMechanism
 }
 
+// CredentialsProvider returns the SASL username and password. It is called
+// whenever a connection authenticates, so rotated credentials are used by new
+// connections without recreating the client.
+type CredentialsProvider func(ctx context.Context) (username, password string, err error)
+
 // config holds the configuration for a Kafka consumer.
 type config struct {
 	// auth is the SASL authentication configuration.
 	auth *AuthConfig
 	// logger is the logger used by the consumer.
 	logger *slog.Logger
+	// onAssigned, onRevoked and onLost are called on group rebalances.
+	onAssigned PartitionsHook
+	onRevoked  PartitionsHook
+	onLost     PartitionsHook
+	// topicRouter stores startup topic registrations that are applied when the
+	// consumer is constructed. Runtime additions live on Consumer itself.
+	topicRouter map[string]Handler
+	// batchRouter stores startup batch topic registrations like topicRouter.
+	batchRouter map[string]BatchHandler
+	// middleware wraps the handler of every registered topic.
+	middleware []Middleware
 	// groupId is the Kafka consumer group ID.
 	groupId string
+	// retryDelays are the delays of the retry topics records move through
+	// before they are dead-lettered.
+	retryDelays []time.Duration
+	// deadLetterTopic receives records that failed permanently or ran out of
+	// retries. Empty disables dead-lettering.
+	deadLetterTopic string
+	// transactionalID enables the transactional mode. Empty disables it.
+	transactionalID string
 	// brokers is the list of seed brokers.
 	brokers []string
-	// topicRouter stores startup topic registrations that are applied when the
-	// consumer is constructed. Runtime additions live on Consumer itself.
-	topicRouter map[string]Handler
 	// kgoOpts are additional franz-go client options.
 	kgoOpts []kgo.Opt
+	// retry configures retries of records whose handler fails.
+	retry RetryPolicy
 	// workers is the number of parallel workers for processing records.
 	workers int
+	// ordering determines which records are handled one after another.
+	ordering Ordering
+	// batchSize is the maximum number of records of a batch.
+	batchSize int
+	// batchLinger is how long a batch waits for more records.
+	batchLinger time.Duration
+	// maxInFlight is the number of records of a partition that may be
+	// dispatched but not handled yet before the partition is paused.
+	maxInFlight int
 	// ackMode determines when records are committed.
 	ackMode AckMode
 }
 
+// defaultMaxInFlight is the default of WithMaxInFlight.
+const defaultMaxInFlight = 500
+
 // newConfig creates a new kafka onfig with default values.
 func newConfig(brokers []string, groupId string) *config {
 	return &config{
 		groupId:     groupId,
 		topicRouter: make(map[string]Handler),
+		batchRouter: make(map[string]BatchHandler),
+		middleware:  nil,
+		batchSize:   defaultBatchSize,
+		batchLinger: defaultBatchLinger,
 		workers:     1,
+		ordering:    OrderingNone,
+		maxInFlight: defaultMaxInFlight,
 		ackMode:     AckModeAtLeastOnce,
 		logger:      slog.Default(),
 		auth:        nil,
 		brokers:     brokers,
 		kgoOpts:     []kgo.Opt{},
+		retry: RetryPolicy{
+			IsRetryable:    nil,
+			MaxAttempts:    1,
+			InitialBackoff: 0,
+			MaxBackoff:     0,
+			Multiplier:     0,
+			Jitter:         0,
+		},
+		retryDelays:     nil,
+		deadLetterTopic: "",
+		transactionalID: "",
+		onAssigned:      nil,
+		onRevoked:       nil,
+		onLost:          nil,
 	}
 }
 
@@ -62,11 +122,26 @@ func WithAuth(username, password string, mechanism AuthMechanism) Option {
 			Username:  username,
 			Password:  password,
 			Mechanism: mechanism,
+			Provider:  nil,
 		}
 		c.auth = auth
 	}
 }
 
+// WithAuthProvider sets the SASL authentication configuration with
+// credentials obtained from provider, e.g. to follow a mounted secret file
+// that is rotated while the service runs.
+func WithAuthProvider(provider CredentialsProvider, mechanism AuthMechanism) Option {
+	return func(c *config) {
+		c.auth = &AuthConfig{
+			Username:  "",
+			Password:  "",
+			Mechanism: mechanism,
+			Provider:  provider,
+		}
+	}
+}
+
 // WithLogger sets the logger.
 func WithLogger(logger *slog.Logger) Option {
 	return func(c *config) {
@@ -99,18 +174,184 @@ func WithAckMode(mode AckMode) Option {
 	}
 }
 
+// WithOrdering sets which records are handled one after another while
+// others are handled in parallel by the workers (Consumer only). The default,
+// OrderingNone, gives no ordering guarantee with more than one worker.
+func WithOrdering(ordering Ordering) Option {
+	return func(c *config) {
+		c.ordering = ordering
+	}
+}
+
+// WithMaxInFlight limits the records of a partition that are polled but not
+// handled yet (Consumer only). A partition reaching the limit is paused until
+// half of them are handled, so one slow partition or key does not hold up
+// the others or buffer without bound. A partition is paused as well while
+// more than the limit of its records wait to be committed behind a record
+// that is not handled yet. It also caps the records taken per poll. The
+// default is 500.
+func WithMaxInFlight(records int) Option {
+	return func(c *config) {
+		if records > 0 {
+			c.maxInFlight = records
+		}
+	}
+}
+
 // WithTopic registers a processing handler for a specific Kafka topic during
 // consumer construction. For runtime registration after New, use Consumer.AddTopic.
 // If a handler is already registered for the given topic, this function will panic.
 func WithTopic(topic string, handler Handler) Option {
 	return func(c *config) {
-		if _, ok := c.topicRouter[topic]; ok {
-			panic(fmt.Sprintf("topic handler already registered for %q", topic))
-		}
+		c.checkUnregistered(topic)
 		c.topicRouter[topic] = handler
 	}
 }
 
+// WithBatchTopic registers a batch handler for a specific Kafka topic during
+// consumer construction (Consumer only). Records of the topic are collected
+// per partition and handed to handler in batches of up to WithBatchSize
+// records, or fewer once WithBatchLinger passed since the first record of
+// the batch was polled. Batches are committed like single records, and
+// panics are recovered as described by BatchHandler. For runtime
+// registration after New, use Consumer.AddBatchTopic. If a handler is
+// already registered for the given topic, this function will panic.
+func WithBatchTopic(topic string, handler BatchHandler) Option {
+	return func(c *config) {
+		c.checkUnregistered(topic)
+		c.batchRouter[topic] = handler
+	}
+}
+
+// checkUnregistered panics if a handler is registered for topic.
+func (c *config) checkUnregistered(topic string) {
+	_, single := c.topicRouter[topic]
+	_, batch := c.batchRouter[topic]
+	if single || batch {
+		panic(fmt.Sprintf("topic handler already registered for %q", topic))
+	}
+}
+
+// WithBatchSize sets the maximum number of records of the batches passed to
+// batch handlers (Consumer only). The default is 100.
+func WithBatchSize(records int) Option {
+	return func(c *config) {
+		if records > 0 {
+			c.batchSize = records
+		}
+	}
+}
+
+// WithBatchLinger sets how long a batch that is not full waits for more
+// records of its partition before it is passed to its batch handler
+// (Consumer only). In the transactional mode, batches do not wait for
+// records of later polls. The default is 100ms.
+func WithBatchLinger(linger time.Duration) Option {
+	return func(c *config) {
+		if linger >= 0 {
+			c.batchLinger = linger
+		}
+	}
+}
+
+// WithHandlerMiddleware wraps the handler of every topic, including those
+// added with Consumer.AddTopic, in middleware (Consumer only). The first
+// middleware is the outermost. Middleware runs for every handler call,
+// including in-place retries. Repeated options append to the chain, e.g.
+//
+//	kafka.WithHandlerMiddleware(kafka.Recover(log), kafka.Logging(log), kafka.Timeout(30*time.Second))
+func WithHandlerMiddleware(middleware ...Middleware) Option {
+	return func(c *config) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithRetry retries records whose handler fails with a retryable error
+// according to policy, blocking the worker between attempts (Consumer only).
+// Without it, every record is handled once.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *config) {
+		c.retry = policy
+	}
+}
+
+// WithRetryTopics enables non-blocking retries through a ladder of retry
+// topics, one per delay, named by RetryTopic, e.g. "orders.retry.5s" (Consumer
+// only). A record still failing with a retryable error after the in-place
+// retries of WithRetry is published to the next retry topic with a
+// HeaderNotBefore header and committed. The consumer subscribes to the retry
+// topics of every registered topic and hands their records to the same
+// handler once due, pausing the partition instead of blocking a worker in
+// the meantime. Records failing on the last tier go to the dead-letter
+// topic. The retry topics must exist.
+func WithRetryTopics(delays ...time.Duration) Option {
+	return func(c *config) {
+		c.retryDelays = delays
+	}
+}
+
+// WithDeadLetterTopic publishes records that failed permanently or ran out of
+// retries to topic, using the client's Producer, before they are committed
+// (Consumer only). The published records keep the original key, value and
+// headers and add the Header* headers describing the failure. Without a
+// dead-letter topic such records are logged at the error level and committed,
+// so they are lost.
+func WithDeadLetterTopic(topic string) Option {
+	return func(c *config) {
+		c.deadLetterTopic = topic
+	}
+}
+
+// WithTransactionalID enables the transactional mode with the given
+// transactional ID, which must be unique to each running instance of a
+// service and stable across its restarts (Consumer and Producer). Polled
+// records are handled in batches, each in a Kafka transaction that commits
+// the records the handlers produced through the client's Producer together
+// with the batch's offsets. The consumer reads only committed records of
+// other transactions.
+//
+// If a record of a batch is not handled, e.g. because forwarding it to the
+// dead-letter topic failed, the transaction is aborted and the whole batch is
+// consumed again. After five transactions in a row aborted at the same offset
+// of a partition, Consumer.Run fails instead of consuming the batch again.
+// Records forwarded to the dead-letter topic are part of the transaction as
+// well. Transactions are also aborted when partitions are revoked while
+// handling a batch. Outside of handlers, the Producer cannot produce. The
+// mode requires AckModeAtLeastOnce and does not support WithRetryTopics.
+func WithTransactionalID(id string) Option {
+	return func(c *config) {
+		c.transactionalID = id
+	}
+}
+
+// WithOnAssigned sets a hook called after partitions are assigned to the
+// consumer in a group rebalance (Consumer only).
+func WithOnAssigned(hook PartitionsHook) Option {
+	return func(c *config) {
+		c.onAssigned = hook
+	}
+}
+
+// WithOnRevoked sets a hook called when partitions are revoked from the
+// consumer in a group rebalance or when it leaves the group (Consumer only).
+// It runs after the records of the partitions being handled finished and the
+// handled records were committed, and before the partitions are assigned to
+// another member.
+func WithOnRevoked(hook PartitionsHook) Option {
+	return func(c *config) {
+		c.onRevoked = hook
+	}
+}
+
+// WithOnLost sets a hook called when partitions were lost without being
+// revoked, e.g. because the consumer missed its session timeout (Consumer
+// only). Their records can no longer be committed.
+func WithOnLost(hook PartitionsHook) Option {
+	return func(c *config) {
+		c.onLost = hook
+	}
+}
+
 // --- Producer Specific Options ---
 
 // WithProducerAcks sets the required acknowledgments for the producer.
@@ -0,0 +1,19 @@
+package kafka
+
+// Ordering determines which records the consumer handles one after another.
+type Ordering int
+
+const (
+	// OrderingNone handles records in parallel regardless of their partition
+	// or key. Records are only handled in order with a single worker.
+	OrderingNone Ordering = iota
+
+	// OrderingPartition handles the records of a partition in offset order,
+	// while records of different partitions are handled in parallel.
+	OrderingPartition
+
+	// OrderingKey handles records with the same key in a partition in offset
+	// order, while records with different keys are handled in parallel.
+	// Records without a key are ordered with each other.
+	OrderingKey
+)
@@ -7,6 +7,9 @@ import (
 )
 
 // Producer is a wrapper around franz-go kgo.Client for producing records.
+// In the transactional mode of WithTransactionalID, records can only be
+// produced from handlers and are part of the transaction handling the
+// consumed record.
 type Producer struct {
 	cfg    *config
 	client *Client
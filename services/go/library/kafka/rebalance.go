@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// PartitionsHook is called with the partitions, by topic, whose assignment
+// to the consumer changed in a group rebalance.
+type PartitionsHook func(ctx context.Context, partitions map[string][]int32)
+
+// Assignment returns the partitions, by topic, currently assigned to the
+// consumer.
+func (c *Consumer) Assignment() map[string][]int32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	assignment := make(map[string][]int32)
+	for tp := range c.assignment {
+		assignment[tp.topic] = append(assignment[tp.topic], tp.partition)
+	}
+	for _, partitions := range assignment {
+		slices.Sort(partitions)
+	}
+	return assignment
+}
+
+// onPartitionsAssigned resumes handling records of the assigned partitions
+// and calls the OnAssigned hook.
+func (c *Consumer) onPartitionsAssigned(ctx context.Context, _ *kgo.Client, assigned map[string][]int32) {
+	if len(assigned) == 0 {
+		return
+	}
+	if d := c.currentDispatcher(); d != nil {
+		d.assign(assigned)
+	}
+	total := c.updateAssignment(assigned, true)
+	c.log.InfoContext(ctx, "Kafka partitions assigned",
+		"groupId", c.cfg.groupId,
+		"partitions", assigned,
+		"count", countPartitions(assigned),
+		"assigned", total)
+
+	if c.cfg.onAssigned != nil {
+		c.cfg.onAssigned(ctx, assigned)
+	}
+}
+
+// onPartitionsRevoked stops handling records of the revoked partitions,
+// waits for those being handled and synchronously commits the handled
+// records before the partitions are handed to another group member. The
+// OnRevoked hook is called afterwards.
+func (c *Consumer) onPartitionsRevoked(ctx context.Context, cl *kgo.Client, revoked map[string][]int32) {
+	if len(revoked) == 0 {
+		return
+	}
+	if d := c.currentDispatcher(); d != nil {
+		start := time.Now()
+		d.revoke(revoked)
+		if err := d.drain(ctx, revoked); err != nil {
+			c.log.WarnContext(ctx, "Kafka revoke did not wait for records being handled", "err", err)
+		}
+		c.commit(ctx, cl, func() []*kgo.Record { return d.release(revoked) })
+		c.log.DebugContext(ctx, "Kafka revoked partitions drained", "duration", time.Since(start))
+	}
+	total := c.updateAssignment(revoked, false)
+	c.log.InfoContext(ctx, "Kafka partitions revoked",
+		"groupId", c.cfg.groupId,
+		"partitions", revoked,
+		"count", countPartitions(revoked),
+		"assigned", total)
+
+	if c.cfg.onRevoked != nil {
+		c.cfg.onRevoked(ctx, revoked)
+	}
+}
+
+// onPartitionsLost stops handling records of partitions that were lost
+// without a revocation, e.g. after a session timeout, and calls the OnLost
+// hook. Their records are not committed, as another member may own them.
+func (c *Consumer) onPartitionsLost(ctx context.Context, _ *kgo.Client, lost map[string][]int32) {
+	if len(lost) == 0 {
+		return
+	}
+	if d := c.currentDispatcher(); d != nil {
+		d.revoke(lost)
+		d.release(lost)
+	}
+	total := c.updateAssignment(lost, false)
+	c.log.WarnContext(ctx, "Kafka partitions lost",
+		"groupId", c.cfg.groupId,
+		"partitions", lost,
+		"count", countPartitions(lost),
+		"assigned", total)
+
+	if c.cfg.onLost != nil {
+		c.cfg.onLost(ctx, lost)
+	}
+}
+
+// updateAssignment adds or removes partitions from the assignment and
+// returns the number of partitions assigned afterwards.
+func (c *Consumer) updateAssignment(partitions map[string][]int32, assigned bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			tp := topicPartition{topic: topic, partition: partition}
+			if assigned {
+				c.assignment[tp] = true
+			} else {
+				delete(c.assignment, tp)
+			}
+		}
+	}
+	return len(c.assignment)
+}
+
+// countPartitions returns the number of partitions in partitions.
+func countPartitions(partitions map[string][]int32) int {
+	n := 0
+	for _, ps := range partitions {
+		n += len(ps)
+	}
+	return n
+}
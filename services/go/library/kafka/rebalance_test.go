@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+func TestDispatcherRevoke(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled []int64
+	d := newDispatcher(newFakePauser(), func(_ context.Context, record *kgo.Record) bool {
+		if record.Offset == 0 {
+			close(started)
+			<-release
+		}
+		handled = append(handled, record.Offset)
+		return true
+	}, 1, OrderingPartition, 100)
+
+	for offset := range int64(3) {
+		require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: offset}), "dispatch")
+	}
+	<-started
+
+	revoked := map[string][]int32{"orders": {0}}
+	d.revoke(revoked)
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 3}), "dispatch")
+
+	drainCtx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.drain(drainCtx, revoked), context.DeadlineExceeded, "drain while a record is handled")
+
+	close(release)
+	require.NoError(t, d.drain(t.Context(), revoked), "drain")
+	assert.Equal(t, offsetsOf(d.release(revoked)), []int64{0}, "handled prefix of revoked partition")
+	d.wait()
+	assert.Equal(t, handled, []int64{0}, "only the record being handled finished")
+
+	d.assign(revoked)
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 1}), "dispatch")
+	d.wait()
+	assert.Equal(t, handled, []int64{0, 1}, "records handled again after assign")
+}
+
+func TestConsumerRebalanceHooks(t *testing.T) {
+	var calls []string
+	hook := func(name string) PartitionsHook {
+		return func(_ context.Context, partitions map[string][]int32) {
+			calls = append(calls, name)
+		}
+	}
+
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	cfg.onAssigned = hook("assigned")
+	cfg.onRevoked = hook("revoked")
+	cfg.onLost = hook("lost")
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+
+	consumer.onPartitionsAssigned(t.Context(), nil, map[string][]int32{"orders": {2, 0, 1}, "payments": {0}})
+	assert.Equal(t, consumer.Assignment(), map[string][]int32{"orders": {0, 1, 2}, "payments": {0}}, "assignment after assign")
+
+	consumer.onPartitionsRevoked(t.Context(), nil, map[string][]int32{"orders": {1}})
+	consumer.onPartitionsRevoked(t.Context(), nil, map[string][]int32{})
+	assert.Equal(t, consumer.Assignment(), map[string][]int32{"orders": {0, 2}, "payments": {0}}, "assignment after revoke")
+
+	consumer.onPartitionsLost(t.Context(), nil, map[string][]int32{"orders": {0, 2}, "payments": {0}})
+	assert.Equal(t, consumer.Assignment(), map[string][]int32{}, "assignment after loss")
+
+	assert.Equal(t, calls, []string{"assigned", "revoked", "lost"}, "hooks called")
+}
+
+func TestConsumerRevokeDrainsBeforeHook(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	finished := false
+
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	cfg.ackMode = AckModeAtMostOnce
+	cfg.onRevoked = func(context.Context, map[string][]int32) {
+		assert.True(t, finished, "record finished before the revoke hook")
+	}
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+
+	d := newDispatcher(newFakePauser(), func(context.Context, *kgo.Record) bool {
+		close(started)
+		<-release
+		finished = true
+		return true
+	}, 1, OrderingPartition, 100)
+	consumer.setDispatcher(d)
+
+	require.True(t, d.dispatch(t.Context(), &kgo.Record{Topic: "orders", Partition: 0, Offset: 0}), "dispatch")
+	<-started
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	consumer.onPartitionsRevoked(t.Context(), nil, map[string][]int32{"orders": {0}})
+	d.wait()
+}
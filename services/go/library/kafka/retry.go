@@ -0,0 +1,168 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"go-services/library/apperror"
+)
+
+// ErrPermanent is matched by errors returned from Permanent.
+var ErrPermanent = errors.New("permanent failure")
+
+// permanentError marks a handler error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() []error {
+	return []error{e.err, ErrPermanent}
+}
+
+// Permanent wraps err so the consumer does not retry the record, e.g. because
+// it cannot be decoded. It returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// ErrSkip is matched by errors returned from Skip.
+var ErrSkip = errors.New("record skipped")
+
+// skipError marks a handler error after which the record is committed
+// without further handling.
+type skipError struct {
+	err error
+}
+
+func (e *skipError) Error() string {
+	return e.err.Error()
+}
+
+func (e *skipError) Unwrap() []error {
+	return []error{e.err, ErrSkip}
+}
+
+// Skip wraps err so the consumer logs the record and commits it instead of
+// retrying or dead-lettering it, e.g. for records that are irrelevant to the
+// service. It returns nil if err is nil.
+func Skip(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &skipError{err: err}
+}
+
+// permanentCodes are the apperror codes describing records that fail the
+// same way however often they are retried.
+var permanentCodes = map[apperror.ErrorCode]bool{
+	apperror.CodeInvalidInput:         true,
+	apperror.CodeMissingField:         true,
+	apperror.CodeInvalidFormat:        true,
+	apperror.CodeTooLarge:             true,
+	apperror.CodeOutOfRange:           true,
+	apperror.CodeForbidden:            true,
+	apperror.CodeDuplicateRecord:      true,
+	apperror.CodeUnsupportedMediaType: true,
+	apperror.CodeNotAcceptable:        true,
+	apperror.CodeNotImplemented:       true,
+	apperror.CodeSerializationError:   true,
+	apperror.CodeDataCorruption:       true,
+}
+
+// IsRetryable is the default error classification of RetryPolicy. Errors
+// wrapped with Permanent or Skip and apperror errors with a code describing invalid
+// or unprocessable data are permanent; all other errors, such as timeouts and
+// unavailable dependencies, are retryable.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrPermanent) || errors.Is(err, ErrSkip) {
+		return false
+	}
+	if appErr, ok := apperror.As(err); ok {
+		return !permanentCodes[appErr.Code]
+	}
+	return true
+}
+
+// RetryPolicy configures how often and how fast a record whose handler fails
+// is retried before the consumer gives up on it.
+type RetryPolicy struct {
+	// IsRetryable reports whether a handler error may succeed on retry. It
+	// defaults to IsRetryable.
+	IsRetryable func(err error) bool
+	// MaxAttempts is the number of handler calls per record, including the
+	// first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every retry.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it, e.g. 0.2
+	// for ±20%, so failing consumers do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns a policy of 5 attempts with exponential backoff
+// from 100ms up to 10s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		IsRetryable:    IsRetryable,
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// retryable reports whether err may be retried under the policy.
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, ErrSkip) {
+		return false
+	}
+	if p.IsRetryable == nil {
+		return IsRetryable(err)
+	}
+	return p.IsRetryable(err)
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		delay *= max(p.Multiplier, 1)
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,235 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+	"go-services/library/testlogger"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"plain error":          {err: errors.New("boom"), want: true},
+		"permanent":            {err: Permanent(errors.New("bad record")), want: false},
+		"wrapped permanent":    {err: fmt.Errorf("handle: %w", Permanent(errors.New("bad record"))), want: false},
+		"invalid format":       {err: apperror.New(apperror.CodeInvalidFormat, "bad json"), want: false},
+		"serialization error":  {err: apperror.New(apperror.CodeSerializationError, "bad avro"), want: false},
+		"service unavailable":  {err: apperror.New(apperror.CodeServiceUnavailable, "down"), want: true},
+		"wrapped db timeout":   {err: fmt.Errorf("save: %w", apperror.New(apperror.CodeDBTimeout, "slow")), want: true},
+		"external service err": {err: apperror.New(apperror.CodeExternalService, "keycloak"), want: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, IsRetryable(tt.err), tt.want, "IsRetryable(%v)", tt.err)
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("bad record")
+	err := Permanent(cause)
+
+	assert.ErrorIs(t, err, cause, "wraps cause")
+	assert.ErrorIs(t, err, ErrPermanent, "marked permanent")
+	assert.Equal(t, err.Error(), "bad record", "message")
+	assert.NoError(t, Permanent(nil), "nil stays nil")
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		IsRetryable:    nil,
+		MaxAttempts:    10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0,
+	}
+
+	got := make([]time.Duration, 6)
+	for i := range got {
+		got[i] = policy.backoff(i + 1)
+	}
+	assert.Equal(t, got, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, "backoff")
+
+	policy.Jitter = 0.5
+	for range 100 {
+		delay := policy.backoff(1)
+		assert.True(t, delay >= 50*time.Millisecond && delay <= 150*time.Millisecond, "jittered delay %v out of range", delay)
+	}
+}
+
+func TestConsumerProcess(t *testing.T) {
+	errTransient := errors.New("transient")
+	errBadRecord := Permanent(errors.New("bad record"))
+
+	tests := map[string]struct {
+		wantErr      error
+		errs         []error
+		maxAttempts  int
+		wantAttempts int
+	}{
+		"success": {
+			wantErr:      nil,
+			errs:         nil,
+			maxAttempts:  3,
+			wantAttempts: 1,
+		},
+		"succeeds after retries": {
+			wantErr:      nil,
+			errs:         []error{errTransient, errTransient},
+			maxAttempts:  3,
+			wantAttempts: 3,
+		},
+		"exhausted": {
+			wantErr:      errTransient,
+			errs:         []error{errTransient, errTransient, errTransient, errTransient},
+			maxAttempts:  3,
+			wantAttempts: 3,
+		},
+		"permanent error is not retried": {
+			wantErr:      ErrPermanent,
+			errs:         []error{errBadRecord},
+			maxAttempts:  3,
+			wantAttempts: 1,
+		},
+		"retries disabled": {
+			wantErr:      errTransient,
+			errs:         []error{errTransient},
+			maxAttempts:  1,
+			wantAttempts: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig([]string{"broker:9092"}, "group")
+			cfg.retry = RetryPolicy{
+				IsRetryable:    nil,
+				MaxAttempts:    tt.maxAttempts,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+				Multiplier:     2,
+				Jitter:         0,
+			}
+			consumer, err := newConsumer(cfg, nil)
+			require.NoError(t, err, "failed to create consumer")
+
+			calls := 0
+			handler := func(context.Context, *kgo.Record) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}
+
+			attempts, err := consumer.process(t.Context(), handler, &kgo.Record{Topic: "topic"})
+			if tt.wantErr == nil {
+				assert.NoError(t, err, "process")
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr, "process")
+			}
+			assert.Equal(t, attempts, tt.wantAttempts, "attempts")
+			assert.Equal(t, calls, tt.wantAttempts, "handler calls")
+		})
+	}
+}
+
+func TestConsumerProcessStopsOnCancel(t *testing.T) {
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	cfg.retry = DefaultRetryPolicy()
+	cfg.retry.InitialBackoff = time.Hour
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	handler := func(context.Context, *kgo.Record) error {
+		cancel()
+		return errors.New("transient")
+	}
+
+	attempts, err := consumer.process(ctx, handler, &kgo.Record{Topic: "topic"})
+	assert.Error(t, err, "process")
+	assert.Equal(t, attempts, 1, "attempts")
+}
+
+func TestConsumerDropsFailedRecordWithoutDeadLetterTopic(t *testing.T) {
+	tests := map[string]struct {
+		err error
+	}{
+		"permanent":         {err: Permanent(errors.New("bad record"))},
+		"retries exhausted": {err: errors.New("transient")},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, capture := testlogger.New()
+			cfg := newConfig([]string{"broker:9092"}, "group")
+			WithLogger(log)(cfg)
+			calls := 0
+			cfg.topicRouter["orders"] = func(context.Context, *kgo.Record) error {
+				calls++
+				return tt.err
+			}
+			consumer, err := newConsumer(cfg, nil)
+			require.NoError(t, err, "failed to create consumer")
+
+			ok := consumer.handleRecord(t.Context(), &kgo.Record{Topic: "orders", Partition: 1, Offset: 7})
+
+			assert.True(t, ok, "dropped record is committed")
+			assert.Equal(t, calls, 1, "handler calls")
+			testlogger.Assert(t, capture.GetOutput()).
+				Count(2, "log entries").
+				AtIndex(1, slog.LevelError, "Record dropped, no dead-letter topic is configured", "drop log").
+				HasField(1, "offset", int64(7), "dropped offset")
+		})
+	}
+}
+
+func TestForwardedRecord(t *testing.T) {
+	record := &kgo.Record{
+		Topic:     "iam.user.event.v1",
+		Partition: 3,
+		Offset:    42,
+		Key:       []byte("user-1"),
+		Value:     []byte(`{"type":"created"}`),
+		Headers:   []kgo.RecordHeader{{Key: "trace-id", Value: []byte("abc")}},
+	}
+	consumer, err := newConsumer(newConfig([]string{"broker:9092"}, "group"), nil)
+	require.NoError(t, err, "failed to create consumer")
+
+	dlq := forwardedRecord("iam.user.event.v1.dlq", record, consumer.originOf(record), errors.New("boom"), 5)
+
+	assert.Equal(t, dlq.Topic, "iam.user.event.v1.dlq", "topic")
+	assert.Equal(t, dlq.Key, record.Key, "key")
+	assert.Equal(t, dlq.Value, record.Value, "value")
+	assert.Equal(t, dlq.Headers, []kgo.RecordHeader{
+		{Key: "trace-id", Value: []byte("abc")},
+		{Key: HeaderOriginalTopic, Value: []byte("iam.user.event.v1")},
+		{Key: HeaderOriginalPartition, Value: []byte("3")},
+		{Key: HeaderOriginalOffset, Value: []byte("42")},
+		{Key: HeaderError, Value: []byte("boom")},
+		{Key: HeaderAttempts, Value: []byte("5")},
+	}, "headers")
+	assert.SliceLen(t, record.Headers, 1, "original headers untouched")
+}
@@ -0,0 +1,203 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// HeaderNotBefore holds the Unix time in milliseconds before which a record
+// on a retry topic must not be handled.
+const HeaderNotBefore = "x-not-before"
+
+// RetryTopic returns the name of the retry topic of topic with the given
+// delay, e.g. "orders.retry.5s" or "orders.retry.10m".
+func RetryTopic(topic string, delay time.Duration) string {
+	return topic + ".retry." + formatDelay(delay)
+}
+
+// formatDelay formats d in its largest whole unit.
+func formatDelay(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d >= time.Second && d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+}
+
+// attemptKey is the context key of the attempt number.
+type attemptKey struct{}
+
+// Attempt returns the number of the current handler call for the record
+// being handled, starting at 1 and including calls made on earlier retry
+// topics. It returns 0 outside of a handler.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// retryTier locates a retry topic in the ladder of its source topic.
+type retryTier struct {
+	source string
+	index  int
+}
+
+// retryTopics returns the retry topics of topic, one per configured delay.
+func (c *config) retryTopics(topic string) []string {
+	topics := make([]string, len(c.retryDelays))
+	for i, delay := range c.retryDelays {
+		topics[i] = RetryTopic(topic, delay)
+	}
+	return topics
+}
+
+// consumeTopics returns the startup topics together with their retry topics.
+func (c *config) consumeTopics() []string {
+	var topics []string
+	for topic := range c.topicRouter {
+		topics = append(topics, topic)
+		topics = append(topics, c.retryTopics(topic)...)
+	}
+	for topic := range c.batchRouter {
+		topics = append(topics, topic)
+		topics = append(topics, c.retryTopics(topic)...)
+	}
+	return topics
+}
+
+// nextRetryTopic returns the retry topic a failed record moves to and its
+// delay, or false if the record is on the last tier or there are none.
+func (c *Consumer) nextRetryTopic(record *kgo.Record) (string, time.Duration, bool) {
+	source, next := record.Topic, 0
+	if tier, ok := c.retryTierOf(record.Topic); ok {
+		source, next = tier.source, tier.index+1
+	}
+	if next >= len(c.cfg.retryDelays) {
+		return "", 0, false
+	}
+	delay := c.cfg.retryDelays[next]
+	return RetryTopic(source, delay), delay, true
+}
+
+func (c *Consumer) retryTierOf(topic string) (retryTier, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tier, ok := c.retryTiers[topic]
+	return tier, ok
+}
+
+// previousAttempts returns the number of handler calls made for record on
+// earlier tiers of the retry ladder.
+func (c *Consumer) previousAttempts(record *kgo.Record) int {
+	if _, ok := c.retryTierOf(record.Topic); !ok {
+		return 0
+	}
+	value, ok := headerValue(record, HeaderAttempts)
+	if !ok {
+		return 0
+	}
+	attempts, err := strconv.Atoi(value)
+	if err != nil || attempts < 0 {
+		return 0
+	}
+	return attempts
+}
+
+// retryRecord returns a forwarded copy of record for the retry topic, due at
+// notBefore.
+func retryRecord(
+	topic string,
+	record *kgo.Record,
+	origin recordOrigin,
+	err error,
+	attempts int,
+	notBefore time.Time,
+) *kgo.Record {
+	out := forwardedRecord(topic, record, origin, err, attempts)
+	out.Headers = append(out.Headers, kgo.RecordHeader{
+		Key:   HeaderNotBefore,
+		Value: []byte(strconv.FormatInt(notBefore.UnixMilli(), 10)),
+	})
+	return out
+}
+
+// notBefore returns the time from the HeaderNotBefore header of record.
+func notBefore(record *kgo.Record) (time.Time, bool) {
+	value, ok := headerValue(record, HeaderNotBefore)
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// partitionPauser is the part of kgo.Client used to pause partitions and to
+// rewind retry topic partitions held until their next record is due.
+type partitionPauser interface {
+	PauseFetchPartitions(topicPartitions map[string][]int32) map[string][]int32
+	ResumeFetchPartitions(topicPartitions map[string][]int32)
+	SetOffsets(setOffsets map[string]map[int32]kgo.EpochOffset)
+}
+
+// topicPartition identifies a partition.
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// holdUntilDue returns records without those of retry topics that are not
+// due yet and every later record of their partitions. Such partitions are
+// held by d: paused and rewound to the first held record, and resumed once
+// it is due, so the records are fetched again instead of blocking a worker.
+func (c *Consumer) holdUntilDue(ctx context.Context, d *dispatcher, records []*kgo.Record, now time.Time) []*kgo.Record {
+	held := make(map[topicPartition]bool)
+	due := make([]*kgo.Record, 0, len(records))
+	for _, record := range records {
+		tp := topicPartition{topic: record.Topic, partition: record.Partition}
+		if held[tp] {
+			continue
+		}
+		if _, ok := c.retryTierOf(record.Topic); !ok {
+			due = append(due, record)
+			continue
+		}
+		at, ok := notBefore(record)
+		if !ok || !at.After(now) {
+			due = append(due, record)
+			continue
+		}
+
+		held[tp] = true
+		wait := at.Sub(now)
+		d.hold(record, wait)
+		c.log.DebugContext(ctx, "Retry topic partition paused until record is due",
+			"topic", record.Topic,
+			"partition", record.Partition,
+			"offset", record.Offset,
+			"wait", wait)
+	}
+	return due
+}
+
+// headerValue returns the value of the last header named key.
+func headerValue(record *kgo.Record, key string) (string, bool) {
+	for _, h := range slices.Backward(record.Headers) {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
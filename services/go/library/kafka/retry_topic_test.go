@@ -0,0 +1,207 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+func TestRetryTopic(t *testing.T) {
+	tests := map[string]struct {
+		want  string
+		delay time.Duration
+	}{
+		"milliseconds": {want: "orders.retry.500ms", delay: 500 * time.Millisecond},
+		"seconds":      {want: "orders.retry.5s", delay: 5 * time.Second},
+		"minutes":      {want: "orders.retry.1m", delay: time.Minute},
+		"mixed":        {want: "orders.retry.90s", delay: 90 * time.Second},
+		"hours":        {want: "orders.retry.2h", delay: 2 * time.Hour},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, RetryTopic("orders", tt.delay), tt.want, "retry topic")
+		})
+	}
+}
+
+// newLadderConsumer returns a consumer with retry topics of 5s and 1m
+// registered for the "orders" topic.
+func newLadderConsumer(t *testing.T, handler Handler) *Consumer {
+	t.Helper()
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	cfg.retryDelays = []time.Duration{5 * time.Second, time.Minute}
+	cfg.topicRouter["orders"] = handler
+
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+	return consumer
+}
+
+func TestConsumerRegistersRetryTopics(t *testing.T) {
+	consumer := newLadderConsumer(t, func(context.Context, *kgo.Record) error { return nil })
+
+	for _, topic := range []string{"orders", "orders.retry.5s", "orders.retry.1m"} {
+		_, ok := consumer.handlerForTopic(topic)
+		assert.True(t, ok, "handler registered for %s", topic)
+	}
+	assert.ErrorContains(
+		t,
+		consumer.AddTopic("orders.retry.5s", func(context.Context, *kgo.Record) error { return nil }),
+		`topic handler already registered for "orders.retry.5s"`,
+		"retry topic taken",
+	)
+}
+
+func TestConsumerNextRetryTopic(t *testing.T) {
+	consumer := newLadderConsumer(t, func(context.Context, *kgo.Record) error { return nil })
+
+	tests := map[string]struct {
+		topic     string
+		wantTopic string
+		wantDelay time.Duration
+		wantOK    bool
+	}{
+		"source topic":   {topic: "orders", wantTopic: "orders.retry.5s", wantDelay: 5 * time.Second, wantOK: true},
+		"first tier":     {topic: "orders.retry.5s", wantTopic: "orders.retry.1m", wantDelay: time.Minute, wantOK: true},
+		"last tier":      {topic: "orders.retry.1m", wantTopic: "", wantDelay: 0, wantOK: false},
+		"no retry topic": {topic: "payments", wantTopic: "payments.retry.5s", wantDelay: 5 * time.Second, wantOK: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			topic, delay, ok := consumer.nextRetryTopic(&kgo.Record{Topic: tt.topic})
+			assert.Equal(t, topic, tt.wantTopic, "topic")
+			assert.Equal(t, delay, tt.wantDelay, "delay")
+			assert.Equal(t, ok, tt.wantOK, "ok")
+		})
+	}
+}
+
+func TestRetryRecordKeepsOrigin(t *testing.T) {
+	consumer := newLadderConsumer(t, func(context.Context, *kgo.Record) error { return nil })
+	notBefore := time.UnixMilli(1_700_000_000_000)
+
+	first := &kgo.Record{Topic: "orders", Partition: 1, Offset: 7, Value: []byte("v")}
+	tier1 := retryRecord("orders.retry.5s", first, consumer.originOf(first), errors.New("first"), 3, notBefore)
+	tier1.Partition, tier1.Offset = 0, 99
+
+	tier2 := retryRecord("orders.retry.1m", tier1, consumer.originOf(tier1), errors.New("second"), 6, notBefore)
+
+	assert.Equal(t, tier2.Headers, []kgo.RecordHeader{
+		{Key: HeaderOriginalTopic, Value: []byte("orders")},
+		{Key: HeaderOriginalPartition, Value: []byte("1")},
+		{Key: HeaderOriginalOffset, Value: []byte("7")},
+		{Key: HeaderError, Value: []byte("second")},
+		{Key: HeaderAttempts, Value: []byte("6")},
+		{Key: HeaderNotBefore, Value: []byte("1700000000000")},
+	}, "headers")
+}
+
+func TestConsumerProcessCountsEarlierAttempts(t *testing.T) {
+	var attempts []int
+	consumer := newLadderConsumer(t, func(ctx context.Context, _ *kgo.Record) error {
+		attempts = append(attempts, Attempt(ctx))
+		return errors.New("transient")
+	})
+	consumer.cfg.retry = RetryPolicy{
+		IsRetryable:    nil,
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     1,
+		Jitter:         0,
+	}
+	handler, _ := consumer.handlerForTopic("orders.retry.5s")
+
+	record := &kgo.Record{
+		Topic:   "orders.retry.5s",
+		Headers: []kgo.RecordHeader{{Key: HeaderAttempts, Value: []byte("2")}},
+	}
+	total, err := consumer.process(t.Context(), handler, record)
+
+	assert.Error(t, err, "process")
+	assert.Equal(t, total, 4, "total attempts")
+	assert.Equal(t, attempts, []int{3, 4}, "attempt numbers seen by handler")
+	assert.Equal(t, Attempt(t.Context()), 0, "no attempt outside handler")
+}
+
+type fakePauser struct {
+	paused  map[string][]int32
+	offsets map[string]map[int32]kgo.EpochOffset
+	resumed chan map[string][]int32
+}
+
+func (p *fakePauser) PauseFetchPartitions(topicPartitions map[string][]int32) map[string][]int32 {
+	for topic, partitions := range topicPartitions {
+		p.paused[topic] = append(p.paused[topic], partitions...)
+	}
+	return p.paused
+}
+
+func (p *fakePauser) ResumeFetchPartitions(topicPartitions map[string][]int32) {
+	p.resumed <- topicPartitions
+}
+
+// resumedPartitions returns the partitions resumed since the last call.
+func (p *fakePauser) resumedPartitions() []map[string][]int32 {
+	var resumed []map[string][]int32
+	for {
+		select {
+		case partitions := <-p.resumed:
+			resumed = append(resumed, partitions)
+		default:
+			return resumed
+		}
+	}
+}
+
+func (p *fakePauser) SetOffsets(setOffsets map[string]map[int32]kgo.EpochOffset) {
+	for topic, offsets := range setOffsets {
+		p.offsets[topic] = offsets
+	}
+}
+
+func TestConsumerHoldUntilDue(t *testing.T) {
+	consumer := newLadderConsumer(t, func(context.Context, *kgo.Record) error { return nil })
+	now := time.Now()
+	at := func(d time.Duration) []kgo.RecordHeader {
+		return []kgo.RecordHeader{{Key: HeaderNotBefore, Value: []byte(strconv.FormatInt(now.Add(d).UnixMilli(), 10))}}
+	}
+
+	records := []*kgo.Record{
+		{Topic: "orders", Partition: 0, Offset: 1},
+		{Topic: "orders.retry.5s", Partition: 0, Offset: 10, Headers: at(-time.Second)},
+		{Topic: "orders.retry.5s", Partition: 0, Offset: 11, Headers: at(50 * time.Millisecond), LeaderEpoch: 4},
+		{Topic: "orders.retry.5s", Partition: 0, Offset: 12, Headers: at(-time.Second)},
+		{Topic: "orders.retry.5s", Partition: 1, Offset: 20, Headers: at(-time.Second)},
+	}
+	pauser := newFakePauser()
+	d := newDispatcher(pauser, func(context.Context, *kgo.Record) bool { return true }, 1, OrderingPartition, 100)
+
+	due := consumer.holdUntilDue(t.Context(), d, records, now)
+
+	offsets := make([]int64, len(due))
+	for i, r := range due {
+		offsets[i] = r.Offset
+	}
+	assert.Equal(t, offsets, []int64{1, 10, 20}, "due records")
+	assert.Equal(t, pauser.paused, map[string][]int32{"orders.retry.5s": {0}}, "paused partitions")
+	assert.Equal(t, pauser.offsets, map[string]map[int32]kgo.EpochOffset{
+		"orders.retry.5s": {0: {Epoch: 4, Offset: 11}},
+	}, "rewound offsets")
+
+	select {
+	case resumed := <-pauser.resumed:
+		assert.Equal(t, resumed, map[string][]int32{"orders.retry.5s": {0}}, "resumed partitions")
+	case <-time.After(5 * time.Second):
+		t.Fatal("partition was not resumed")
+	}
+}
@@ -0,0 +1,268 @@
+// Package schemaregistry is a client for Confluent compatible schema
+// registries and a kafka.Codec that enforces registered JSON Schemas on
+// record values in the registry wire format.
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-services/library/apperror"
+)
+
+// contentType is the media type of registry requests and responses.
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// SchemaType is the format of a schema.
+type SchemaType string
+
+const (
+	// SchemaTypeAvro is the registry's default schema type.
+	SchemaTypeAvro SchemaType = "AVRO"
+	// SchemaTypeJSON is JSON Schema.
+	SchemaTypeJSON SchemaType = "JSON"
+	// SchemaTypeProtobuf is a Protobuf schema.
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+)
+
+// Schema is a schema as stored in the registry.
+type Schema struct {
+	// Type is the format of Schema. The registry treats an empty type as
+	// SchemaTypeAvro.
+	Type SchemaType `json:"schemaType,omitempty"`
+	// Schema is the schema document.
+	Schema string `json:"schema"`
+}
+
+// SubjectSchema is a version of a schema registered under a subject.
+type SubjectSchema struct {
+	Subject string `json:"subject"`
+	Schema
+	ID      int `json:"id"`
+	Version int `json:"version"`
+}
+
+// ValueSubject returns the subject of the values of topic under the
+// registry's default topic name strategy, e.g. "user-events-value".
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client talks to a schema registry. Schemas looked up by ID are cached, as
+// they never change.
+type Client struct {
+	httpClient httpDoer
+	schemas    map[int]Schema
+	baseURL    string
+	username   string
+	password   string
+	mu         sync.RWMutex
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBasicAuth authenticates requests with HTTP basic authentication.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// NewClient returns a client for the registry at baseURL.
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	if strings.TrimSpace(baseURL) == "" {
+		return nil, fmt.Errorf("schema registry url must not be empty")
+	}
+
+	c := &Client{
+		httpClient: http.DefaultClient,
+		schemas:    make(map[int]Schema),
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		username:   "",
+		password:   "",
+		mu:         sync.RWMutex{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Register registers schema under subject and returns its ID. Registering a
+// schema that is already registered returns the existing ID.
+func (c *Client) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	var res struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schema, &res); err != nil {
+		return 0, fmt.Errorf("failed to register schema for subject %q: %w", subject, err)
+	}
+
+	c.mu.Lock()
+	c.schemas[res.ID] = schema
+	c.mu.Unlock()
+	return res.ID, nil
+}
+
+// SchemaByID returns the schema with the given ID.
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &schema); err != nil {
+		return Schema{}, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+
+	c.mu.Lock()
+	c.schemas[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// Latest returns the latest schema registered under subject.
+func (c *Client) Latest(ctx context.Context, subject string) (SubjectSchema, error) {
+	var schema SubjectSchema
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &schema); err != nil {
+		return SubjectSchema{}, fmt.Errorf("failed to get latest schema of subject %q: %w", subject, err)
+	}
+
+	c.mu.Lock()
+	c.schemas[schema.ID] = schema.Schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// CheckCompatibility returns an error if schema is incompatible with the
+// latest schema of subject under the subject's compatibility level. A
+// subject without schemas accepts any schema.
+func (c *Client) CheckCompatibility(ctx context.Context, subject string, schema Schema) error {
+	var res struct {
+		Messages     []string `json:"messages"`
+		IsCompatible bool     `json:"is_compatible"`
+	}
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest?verbose=true"
+	err := c.do(ctx, http.MethodPost, path, schema, &res)
+	if appErr, ok := apperror.As(err); ok && appErr.Code == apperror.CodeNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check compatibility with subject %q: %w", subject, err)
+	}
+	if !res.IsCompatible {
+		return apperror.New(
+			apperror.CodeConflict,
+			"schema is incompatible with subject %q: %s",
+			subject,
+			strings.Join(res.Messages, "; "),
+		)
+	}
+	return nil
+}
+
+// errorResponse is the body of registry error responses.
+type errorResponse struct {
+	Message   string `json:"message"`
+	ErrorCode int    `json:"error_code"`
+}
+
+// do sends a request with body encoded as JSON, if not nil, and decodes the
+// response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return apperror.Wrap(apperror.CodeSerializationError, err, "failed to encode schema registry request")
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return apperror.Wrap(apperror.CodeInternalError, err, "failed to build schema registry request")
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return apperror.Wrap(apperror.CodeExternalService, err, "schema registry request failed")
+	}
+	defer closeResponseBody(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		// The message is informative only, so a malformed body still
+		// reports the status.
+		var errRes errorResponse
+		if decodeErr := json.NewDecoder(res.Body).Decode(&errRes); decodeErr != nil {
+			errRes.Message = http.StatusText(res.StatusCode)
+		}
+		return apperror.New(
+			statusCode(res.StatusCode),
+			"schema registry responded with status %d (error code %d): %s",
+			res.StatusCode,
+			errRes.ErrorCode,
+			errRes.Message,
+		)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return apperror.Wrap(apperror.CodeSerializationError, err, "failed to decode schema registry response")
+	}
+	return nil
+}
+
+// statusCode maps registry response statuses to apperror codes.
+func statusCode(status int) apperror.ErrorCode {
+	switch status {
+	case http.StatusNotFound:
+		return apperror.CodeNotFound
+	case http.StatusConflict:
+		return apperror.CodeConflict
+	case http.StatusUnprocessableEntity:
+		return apperror.CodeInvalidInput
+	case http.StatusUnauthorized:
+		return apperror.CodeUnauthorized
+	case http.StatusForbidden:
+		return apperror.CodeForbidden
+	default:
+		return apperror.CodeExternalService
+	}
+}
+
+func closeResponseBody(body io.Closer) {
+	if err := body.Close(); err != nil {
+		return
+	}
+}
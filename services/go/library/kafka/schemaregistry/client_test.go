@@ -0,0 +1,103 @@
+package schemaregistry_test
+
+import (
+	"testing"
+
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/kafka/schemaregistry"
+	"go-services/library/kafka/schemaregistry/schemaregistrytest"
+	"go-services/library/require"
+)
+
+const (
+	schemaV1 = `{"type":"object","properties":{"id":{"type":"string"}},"required":["id"]}`
+	schemaV2 = `{"type":"object","properties":{"id":{"type":"string"},"name":{"type":"string"}},"required":["id"]}`
+)
+
+func TestClientRegister(t *testing.T) {
+	registry := schemaregistrytest.NewServer(t)
+	client := registry.Client(t)
+	subject := schemaregistry.ValueSubject("users")
+
+	id, err := client.Register(t.Context(), subject, schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV1})
+	require.NoError(t, err, "register")
+	again, err := client.Register(t.Context(), subject, schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV1})
+	require.NoError(t, err, "register again")
+	assert.Equal(t, again, id, "registering is idempotent")
+
+	v2, err := client.Register(t.Context(), subject, schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV2})
+	require.NoError(t, err, "register v2")
+
+	latest, err := client.Latest(t.Context(), subject)
+	require.NoError(t, err, "latest")
+	assert.Equal(t, latest, schemaregistry.SubjectSchema{
+		Subject: subject,
+		Schema:  schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV2},
+		ID:      v2,
+		Version: 2,
+	}, "latest schema")
+
+	schema, err := registry.Client(t).SchemaByID(t.Context(), id)
+	require.NoError(t, err, "schema by id")
+	assert.Equal(t, schema, schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV1}, "schema by id")
+}
+
+func TestClientNotFound(t *testing.T) {
+	client := schemaregistrytest.NewServer(t).Client(t)
+
+	_, err := client.SchemaByID(t.Context(), 42)
+	appErr, ok := apperror.As(err)
+	require.True(t, ok, "error is an apperror: %v", err)
+	assert.Equal(t, appErr.Code, apperror.CodeNotFound, "error code")
+
+	_, err = client.Latest(t.Context(), "unknown-value")
+	appErr, ok = apperror.As(err)
+	require.True(t, ok, "error is an apperror: %v", err)
+	assert.Equal(t, appErr.Code, apperror.CodeNotFound, "error code")
+}
+
+func TestClientCheckCompatibility(t *testing.T) {
+	tests := map[string]struct {
+		schema      string
+		wantMessage string
+	}{
+		"optional property added": {
+			schema:      schemaV2,
+			wantMessage: "",
+		},
+		"required property added": {
+			schema:      `{"type":"object","properties":{"id":{"type":"string"},"name":{"type":"string"}},"required":["id","name"]}`,
+			wantMessage: `property "name" is required but was not before`,
+		},
+		"property type changed": {
+			schema:      `{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}`,
+			wantMessage: `type of property "id" changed from string to integer`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			registry := schemaregistrytest.NewServer(t)
+			client := registry.Client(t)
+			registry.Register(t, "users-value", schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV1})
+
+			err := client.CheckCompatibility(t.Context(), "users-value", schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: tt.schema})
+			if tt.wantMessage == "" {
+				assert.NoError(t, err, "compatible schema")
+				return
+			}
+			appErr, ok := apperror.As(err)
+			require.True(t, ok, "error is an apperror: %v", err)
+			assert.Equal(t, appErr.Code, apperror.CodeConflict, "error code")
+			assert.ErrorContains(t, err, tt.wantMessage, "incompatibility")
+		})
+	}
+}
+
+func TestClientCheckCompatibilityNewSubject(t *testing.T) {
+	client := schemaregistrytest.NewServer(t).Client(t)
+
+	err := client.CheckCompatibility(t.Context(), "users-value", schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV1})
+	assert.NoError(t, err, "subject without schemas accepts any schema")
+}
@@ -0,0 +1,182 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"go-services/library/kafka"
+)
+
+// JSONCodec is a kafka.Codec for values of type T described by a JSON Schema
+// registered under a subject. Encoded values are validated against the
+// schema and carry its ID in the registry wire format. Decoded values are
+// validated against the schema their ID names, or against the codec's schema
+// if they have no header, so producers that do not use the registry yet are
+// still held to the contract.
+type JSONCodec[T any] struct {
+	client     *Client
+	schema     *jsonschema.Schema
+	validators map[int]*jsonschema.Schema
+	subject    string
+	id         int
+	mu         sync.RWMutex
+}
+
+var _ kafka.Codec[struct{}] = (*JSONCodec[struct{}])(nil)
+
+// RegisterJSONCodec returns a JSONCodec for schema, for producers. It checks
+// that schema is compatible with the latest schema of subject and registers
+// it, so an incompatible producer fails at startup instead of breaking its
+// consumers.
+func RegisterJSONCodec[T any](ctx context.Context, client *Client, subject, schema string) (*JSONCodec[T], error) {
+	s := Schema{Type: SchemaTypeJSON, Schema: schema}
+	if err := client.CheckCompatibility(ctx, subject, s); err != nil {
+		return nil, err
+	}
+	id, err := client.Register(ctx, subject, s)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONCodec[T](client, subject, id, schema)
+}
+
+// LookupJSONCodec returns a JSONCodec for the latest schema of subject, for
+// consumers.
+func LookupJSONCodec[T any](ctx context.Context, client *Client, subject string) (*JSONCodec[T], error) {
+	latest, err := client.Latest(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Type != SchemaTypeJSON {
+		return nil, fmt.Errorf("subject %q has schema type %s, not JSON", subject, schemaTypeName(latest.Type))
+	}
+	return newJSONCodec[T](client, subject, latest.ID, latest.Schema.Schema)
+}
+
+func newJSONCodec[T any](client *Client, subject string, id int, schema string) (*JSONCodec[T], error) {
+	validator, err := compileJSONSchema(id, schema)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONCodec[T]{
+		client:     client,
+		schema:     validator,
+		validators: map[int]*jsonschema.Schema{id: validator},
+		subject:    subject,
+		id:         id,
+		mu:         sync.RWMutex{},
+	}, nil
+}
+
+// ID returns the ID of the codec's schema.
+func (c *JSONCodec[T]) ID() int {
+	return c.id
+}
+
+// Encode implements kafka.Codec.
+func (c *JSONCodec[T]) Encode(v T) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode json: %w", err)
+	}
+	if err := validate(c.schema, payload); err != nil {
+		return nil, fmt.Errorf("value does not match schema %d of subject %q: %w", c.id, c.subject, err)
+	}
+	return AppendHeader(c.id, payload), nil
+}
+
+// Decode implements kafka.Codec. Schemas named by values are fetched from
+// the registry once.
+func (c *JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	validator := c.schema
+	id, payload, ok := ParseHeader(data)
+	if ok {
+		var err error
+		if validator, err = c.validator(id); err != nil {
+			return v, err
+		}
+	} else {
+		id = c.id
+	}
+
+	if err := validate(validator, payload); err != nil {
+		return v, fmt.Errorf("value does not match schema %d of subject %q: %w", id, c.subject, err)
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return v, fmt.Errorf("decode json: %w", err)
+	}
+	return v, nil
+}
+
+// validator returns the compiled schema with the given ID.
+func (c *JSONCodec[T]) validator(id int) (*jsonschema.Schema, error) {
+	c.mu.RLock()
+	validator, ok := c.validators[id]
+	c.mu.RUnlock()
+	if ok {
+		return validator, nil
+	}
+
+	// A detached context keeps a cancelled handler from caching a failure;
+	// the lookup is bounded by the client's HTTP timeouts.
+	schema, err := c.client.SchemaByID(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Type != SchemaTypeJSON {
+		return nil, fmt.Errorf("schema %d has schema type %s, not JSON", id, schemaTypeName(schema.Type))
+	}
+	validator, err = compileJSONSchema(id, schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.validators[id] = validator
+	c.mu.Unlock()
+	return validator, nil
+}
+
+// compileJSONSchema compiles the JSON Schema with the given ID.
+func compileJSONSchema(id int, schema string) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("parse json schema %d: %w", id, err)
+	}
+
+	url := "schema-" + strconv.Itoa(id) + ".json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("add json schema %d: %w", id, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("compile json schema %d: %w", id, err)
+	}
+	return compiled, nil
+}
+
+// validate validates the JSON document payload against schema.
+func validate(schema *jsonschema.Schema, payload []byte) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("parse json: %w", err)
+	}
+	return schema.Validate(doc)
+}
+
+// schemaTypeName returns the name of t, which the registry defaults to AVRO.
+func schemaTypeName(t SchemaType) string {
+	if t == "" {
+		return string(SchemaTypeAvro)
+	}
+	return string(t)
+}
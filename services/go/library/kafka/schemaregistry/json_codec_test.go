@@ -0,0 +1,88 @@
+package schemaregistry_test
+
+import (
+	"testing"
+
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/kafka/schemaregistry"
+	"go-services/library/kafka/schemaregistry/schemaregistrytest"
+	"go-services/library/require"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+func TestJSONCodec(t *testing.T) {
+	registry := schemaregistrytest.NewServer(t)
+	subject := schemaregistry.ValueSubject("users")
+
+	producer, err := schemaregistry.RegisterJSONCodec[user](t.Context(), registry.Client(t), subject, schemaV1)
+	require.NoError(t, err, "register codec")
+	consumer, err := schemaregistry.LookupJSONCodec[user](t.Context(), registry.Client(t), subject)
+	require.NoError(t, err, "lookup codec")
+	assert.Equal(t, consumer.ID(), producer.ID(), "consumer uses the registered schema")
+
+	data, err := producer.Encode(user{ID: "1", Name: "Ada"})
+	require.NoError(t, err, "encode")
+	id, _, ok := schemaregistry.ParseHeader(data)
+	assert.True(t, ok, "encoded value has a header")
+	assert.Equal(t, id, producer.ID(), "header schema id")
+
+	got, err := consumer.Decode(data)
+	require.NoError(t, err, "decode")
+	assert.Equal(t, got, user{ID: "1", Name: "Ada"}, "decoded value")
+
+	got, err = consumer.Decode([]byte(`{"id":"2"}`))
+	require.NoError(t, err, "decode value without header")
+	assert.Equal(t, got, user{ID: "2", Name: ""}, "decoded value without header")
+
+	_, err = consumer.Decode([]byte(`{"name":"Ada"}`))
+	assert.ErrorContains(t, err, "does not match schema", "value without required property")
+}
+
+func TestJSONCodecWriterSchema(t *testing.T) {
+	registry := schemaregistrytest.NewServer(t)
+	subject := schemaregistry.ValueSubject("users")
+
+	consumer, err := schemaregistry.RegisterJSONCodec[user](t.Context(), registry.Client(t), subject, schemaV1)
+	require.NoError(t, err, "register consumer codec")
+	producer, err := schemaregistry.RegisterJSONCodec[user](t.Context(), registry.Client(t), subject, schemaV2)
+	require.NoError(t, err, "register producer codec")
+
+	data, err := producer.Encode(user{ID: "1", Name: "Ada"})
+	require.NoError(t, err, "encode")
+	got, err := consumer.Decode(data)
+	require.NoError(t, err, "decode value written with a newer schema")
+	assert.Equal(t, got, user{ID: "1", Name: "Ada"}, "decoded value")
+
+	_, err = consumer.Decode(schemaregistry.AppendHeader(99, []byte(`{"id":"1"}`)))
+	appErr, ok := apperror.As(err)
+	require.True(t, ok, "error is an apperror: %v", err)
+	assert.Equal(t, appErr.Code, apperror.CodeNotFound, "unknown writer schema")
+}
+
+func TestRegisterJSONCodecIncompatible(t *testing.T) {
+	registry := schemaregistrytest.NewServer(t)
+	registry.Register(t, "users-value", schemaregistry.Schema{Type: schemaregistry.SchemaTypeJSON, Schema: schemaV1})
+
+	_, err := schemaregistry.RegisterJSONCodec[user](
+		t.Context(),
+		registry.Client(t),
+		"users-value",
+		`{"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]}`,
+	)
+	appErr, ok := apperror.As(err)
+	require.True(t, ok, "error is an apperror: %v", err)
+	assert.Equal(t, appErr.Code, apperror.CodeConflict, "incompatible schema rejected at startup")
+}
+
+func TestLookupJSONCodecNotJSON(t *testing.T) {
+	registry := schemaregistrytest.NewServer(t)
+	registry.Register(t, "users-value", schemaregistry.Schema{Type: "", Schema: `"string"`})
+
+	_, err := schemaregistry.LookupJSONCodec[user](t.Context(), registry.Client(t), "users-value")
+	assert.ErrorContains(t, err, "has schema type AVRO", "avro subject")
+}
@@ -0,0 +1,288 @@
+// Package schemaregistrytest provides an in-process schema registry for
+// tests. It implements the subset of the Confluent schema registry API used
+// by schemaregistry.Client and enforces backward compatibility of JSON
+// Schemas in a simplified form.
+package schemaregistrytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"go-services/library/kafka/schemaregistry"
+)
+
+// Registry error codes, as returned by the Confluent schema registry.
+const (
+	errorSubjectNotFound = 40401
+	errorSchemaNotFound  = 40403
+	errorIncompatible    = 409
+	errorInvalidSchema   = 42201
+	errorInvalidRequest  = 42200
+)
+
+// Server is an in-process schema registry.
+type Server struct {
+	server *httptest.Server
+	// subjects holds the schema IDs of each subject's versions.
+	subjects map[string][]int
+	// schemas holds the registered schemas; the ID of a schema is its index
+	// plus one.
+	schemas []schemaregistry.Schema
+	mu      sync.Mutex
+}
+
+// NewServer starts a registry that is closed when the test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := &Server{
+		server:   nil,
+		subjects: make(map[string][]int),
+		schemas:  nil,
+		mu:       sync.Mutex{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", s.register)
+	mux.HandleFunc("GET /subjects/{subject}/versions/latest", s.latest)
+	mux.HandleFunc("GET /schemas/ids/{id}", s.schemaByID)
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/latest", s.compatibility)
+	s.server = httptest.NewServer(mux)
+	tb.Cleanup(s.server.Close)
+
+	return s
+}
+
+// URL returns the base URL of the registry.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns a client for the registry.
+func (s *Server) Client(tb testing.TB) *schemaregistry.Client {
+	tb.Helper()
+
+	client, err := schemaregistry.NewClient(s.URL(), schemaregistry.WithHTTPClient(s.server.Client()))
+	if err != nil {
+		tb.Fatalf("failed to create schema registry client: %v", err)
+	}
+	return client
+}
+
+// Register registers schema under subject and returns its ID, failing the
+// test if the registry rejects it.
+func (s *Server) Register(tb testing.TB, subject string, schema schemaregistry.Schema) int {
+	tb.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, code, err := s.registerLocked(subject, schema)
+	if err != nil {
+		tb.Fatalf("failed to register schema for subject %q (error code %d): %v", subject, code, err)
+	}
+	return id
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	schema, ok := decodeSchema(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	id, code, err := s.registerLocked(r.PathValue("subject"), schema)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, code, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"id": id})
+}
+
+// registerLocked registers schema under subject and returns its ID, or the
+// registry error code on failure. s.mu must be held.
+func (s *Server) registerLocked(subject string, schema schemaregistry.Schema) (int, int, error) {
+	if schema.Type == "" {
+		schema.Type = schemaregistry.SchemaTypeAvro
+	}
+	if schema.Type == schemaregistry.SchemaTypeJSON {
+		if _, err := parseJSONSchema(schema.Schema); err != nil {
+			return 0, errorInvalidSchema, err
+		}
+	}
+
+	versions := s.subjects[subject]
+	for _, id := range versions {
+		if s.schemas[id-1] == schema {
+			return id, 0, nil
+		}
+	}
+	if len(versions) > 0 {
+		if messages := incompatibilities(s.schemas[versions[len(versions)-1]-1], schema); len(messages) > 0 {
+			return 0, errorIncompatible, fmt.Errorf("schema being registered is incompatible with an earlier schema: %v", messages)
+		}
+	}
+
+	id := slices.Index(s.schemas, schema) + 1
+	if id == 0 {
+		s.schemas = append(s.schemas, schema)
+		id = len(s.schemas)
+	}
+	s.subjects[subject] = append(versions, id)
+	return id, 0, nil
+}
+
+func (s *Server) latest(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+
+	s.mu.Lock()
+	versions := s.subjects[subject]
+	var res schemaregistry.SubjectSchema
+	if len(versions) > 0 {
+		id := versions[len(versions)-1]
+		res = schemaregistry.SubjectSchema{
+			Subject: subject,
+			Schema:  s.schemas[id-1],
+			ID:      id,
+			Version: len(versions),
+		}
+	}
+	s.mu.Unlock()
+
+	if len(versions) == 0 {
+		writeError(w, errorSubjectNotFound, fmt.Sprintf("Subject '%s' not found.", subject))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) schemaByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	found := err == nil && id > 0 && id <= len(s.schemas)
+	var schema schemaregistry.Schema
+	if found {
+		schema = s.schemas[id-1]
+	}
+	s.mu.Unlock()
+
+	if !found {
+		writeError(w, errorSchemaNotFound, fmt.Sprintf("Schema %s not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, schema)
+}
+
+func (s *Server) compatibility(w http.ResponseWriter, r *http.Request) {
+	schema, ok := decodeSchema(w, r)
+	if !ok {
+		return
+	}
+	if schema.Type == "" {
+		schema.Type = schemaregistry.SchemaTypeAvro
+	}
+	subject := r.PathValue("subject")
+
+	s.mu.Lock()
+	versions := s.subjects[subject]
+	var messages []string
+	if len(versions) > 0 {
+		messages = incompatibilities(s.schemas[versions[len(versions)-1]-1], schema)
+	}
+	s.mu.Unlock()
+
+	if len(versions) == 0 {
+		writeError(w, errorSubjectNotFound, fmt.Sprintf("Subject '%s' not found.", subject))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"is_compatible": len(messages) == 0,
+		"messages":      messages,
+	})
+}
+
+// jsonSchema is the part of a JSON Schema the compatibility check looks at.
+type jsonSchema struct {
+	Properties map[string]struct {
+		Type any `json:"type"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+func parseJSONSchema(schema string) (jsonSchema, error) {
+	var parsed jsonSchema
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return jsonSchema{}, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return parsed, nil
+}
+
+// incompatibilities returns why next is not backward compatible with prev,
+// i.e. why values written with prev cannot be read with next. Only top-level
+// JSON Schema properties are compared: next must not require properties
+// prev does not, nor change the type of a property. Schemas of other types
+// are compatible as long as the type does not change.
+func incompatibilities(prev, next schemaregistry.Schema) []string {
+	if prev.Type != next.Type {
+		return []string{fmt.Sprintf("schema type changed from %s to %s", prev.Type, next.Type)}
+	}
+	if next.Type != schemaregistry.SchemaTypeJSON {
+		return nil
+	}
+
+	prevSchema, prevErr := parseJSONSchema(prev.Schema)
+	nextSchema, nextErr := parseJSONSchema(next.Schema)
+	if prevErr != nil || nextErr != nil {
+		return []string{"schema is not valid JSON"}
+	}
+
+	var messages []string
+	for _, name := range nextSchema.Required {
+		if !slices.Contains(prevSchema.Required, name) {
+			messages = append(messages, fmt.Sprintf("property %q is required but was not before", name))
+		}
+	}
+	for name, property := range nextSchema.Properties {
+		prevProperty, ok := prevSchema.Properties[name]
+		if ok && fmt.Sprint(prevProperty.Type) != fmt.Sprint(property.Type) {
+			messages = append(messages, fmt.Sprintf("type of property %q changed from %v to %v", name, prevProperty.Type, property.Type))
+		}
+	}
+	slices.Sort(messages)
+	return messages
+}
+
+func decodeSchema(w http.ResponseWriter, r *http.Request) (schemaregistry.Schema, bool) {
+	var schema schemaregistry.Schema
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+		writeError(w, errorInvalidRequest, "invalid request body: "+err.Error())
+		return schemaregistry.Schema{}, false
+	}
+	return schema, true
+}
+
+// writeError writes a registry error response. The HTTP status is the first
+// three digits of the error code.
+func writeError(w http.ResponseWriter, code int, message string) {
+	status := code
+	for status >= 1000 {
+		status /= 10
+	}
+	writeJSON(w, status, map[string]any{"error_code": code, "message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return
+	}
+}
@@ -0,0 +1,28 @@
+package schemaregistry
+
+import "encoding/binary"
+
+const (
+	// magicByte starts values in the registry wire format.
+	magicByte = 0
+	// headerSize is the size of the magic byte and the schema ID.
+	headerSize = 5
+)
+
+// AppendHeader returns payload prefixed with the registry wire format
+// header: the magic byte and the big-endian schema ID.
+func AppendHeader(id int, payload []byte) []byte {
+	data := make([]byte, headerSize, headerSize+len(payload))
+	data[0] = magicByte
+	binary.BigEndian.PutUint32(data[1:headerSize], uint32(id))
+	return append(data, payload...)
+}
+
+// ParseHeader returns the schema ID and payload of data in the registry wire
+// format. ok is false if data has no header.
+func ParseHeader(data []byte) (id int, payload []byte, ok bool) {
+	if len(data) < headerSize || data[0] != magicByte {
+		return 0, data, false
+	}
+	return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], true
+}
@@ -0,0 +1,49 @@
+package schemaregistry_test
+
+import (
+	"testing"
+
+	"go-services/library/assert"
+	"go-services/library/kafka/schemaregistry"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := map[string]struct {
+		data        []byte
+		wantPayload []byte
+		wantID      int
+		wantOK      bool
+	}{
+		"header": {
+			data:        schemaregistry.AppendHeader(258, []byte(`{}`)),
+			wantPayload: []byte(`{}`),
+			wantID:      258,
+			wantOK:      true,
+		},
+		"plain json": {
+			data:        []byte(`{"id":1}`),
+			wantPayload: []byte(`{"id":1}`),
+			wantID:      0,
+			wantOK:      false,
+		},
+		"too short": {
+			data:        []byte{0, 0, 1},
+			wantPayload: []byte{0, 0, 1},
+			wantID:      0,
+			wantOK:      false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			id, payload, ok := schemaregistry.ParseHeader(tt.data)
+			assert.Equal(t, ok, tt.wantOK, "header found")
+			assert.Equal(t, id, tt.wantID, "schema id")
+			assert.Equal(t, payload, tt.wantPayload, "payload")
+		})
+	}
+}
+
+func TestAppendHeader(t *testing.T) {
+	assert.Equal(t, schemaregistry.AppendHeader(258, []byte("x")), []byte{0, 0, 0, 1, 2, 'x'}, "wire format")
+}
@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// maxTransactionAborts is how many transactions in a row may be aborted at
+// the same offset of a partition before runTransactional gives up.
+const maxTransactionAborts = 5
+
+// abortedAt counts the transactions aborted in a row at offset.
+type abortedAt struct {
+	offset int64
+	count  int
+}
+
+// transactional reports whether the consumer runs in the transactional mode
+// of WithTransactionalID.
+func (c *config) transactional() bool {
+	return c.transactionalID != ""
+}
+
+// validateTransactional returns an error if the options conflict with the
+// transactional mode.
+func (c *config) validateTransactional() error {
+	if !c.transactional() {
+		return nil
+	}
+	if c.groupId == "" {
+		return errors.New("transactional mode requires a consumer group")
+	}
+	if c.ackMode != AckModeAtLeastOnce {
+		return errors.New("transactional mode requires AckModeAtLeastOnce")
+	}
+	if len(c.retryDelays) > 0 {
+		return errors.New("transactional mode does not support retry topics")
+	}
+	return nil
+}
+
+// transactionalOpts returns the franz-go options of the transactional mode.
+// Consumers read only committed records, and fetching offsets waits for
+// pending transactional commits, so a new group member never starts before
+// the offsets of an in-flight transaction.
+func (c *config) transactionalOpts() []kgo.Opt {
+	return []kgo.Opt{
+		kgo.TransactionalID(c.transactionalID),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+		kgo.RequireStableFetchOffsets(),
+	}
+}
+
+// runTransactional polls records and handles each poll as a batch in its own
+// transaction until ctx is done or the client is closed. An error ending a
+// transaction is returned, as the session cannot recover from it, and so is
+// an error once maxTransactionAborts transactions in a row were aborted at
+// the same offset, since consuming the records again does not help.
+func (c *Consumer) runTransactional(ctx context.Context, sess *kgo.GroupTransactSession) error {
+	defer c.setDispatcher(nil)
+
+	aborts := make(map[topicPartition]abortedAt)
+	for {
+		fetches := sess.PollRecords(ctx, c.cfg.maxInFlight)
+		if fetches.IsClientClosed() {
+			return nil
+		}
+		if err := fetches.Err(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			c.log.Warn("Kafka poll error", "err", err)
+			continue
+		}
+
+		records := fetches.Records()
+		if len(records) == 0 {
+			continue
+		}
+		committed, err := c.transact(ctx, sess, records)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() == nil {
+			if err := countAborts(aborts, records, committed); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// countAborts tracks, per partition of records, the transactions aborted in a
+// row at the partition's first offset. It returns an error once a partition
+// reaches maxTransactionAborts.
+func countAborts(aborts map[topicPartition]abortedAt, records []*kgo.Record, committed bool) error {
+	first := make(map[topicPartition]int64)
+	for _, record := range records {
+		tp := topicPartition{topic: record.Topic, partition: record.Partition}
+		if _, ok := first[tp]; !ok {
+			first[tp] = record.Offset
+		}
+	}
+
+	for tp, offset := range first {
+		if committed {
+			delete(aborts, tp)
+			continue
+		}
+		aborted := aborts[tp]
+		if aborted.offset != offset {
+			aborted = abortedAt{offset: offset, count: 0}
+		}
+		aborted.count++
+		aborts[tp] = aborted
+		if aborted.count >= maxTransactionAborts {
+			return fmt.Errorf("transaction aborted %d times in a row at topic %s partition %d offset %d",
+				aborted.count, tp.topic, tp.partition, offset)
+		}
+	}
+	return nil
+}
+
+// transact handles records in a transaction and reports whether it was
+// committed. The transaction commits the records produced meanwhile and the
+// offsets of records if every record was handled; otherwise it is aborted
+// and the session rewinds to the committed offsets, so the records are
+// consumed again.
+func (c *Consumer) transact(ctx context.Context, sess *kgo.GroupTransactSession, records []*kgo.Record) (bool, error) {
+	if err := sess.Begin(); err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var failed atomic.Bool
+	check := func(ok bool) bool {
+		if !ok {
+			failed.Store(true)
+		}
+		return ok
+	}
+	d := newDispatcher(sess.Client(), func(ctx context.Context, record *kgo.Record) bool {
+		return check(c.handleRecord(ctx, record))
+	}, c.cfg.workers, c.cfg.ordering, c.cfg.maxInFlight)
+	d.handleBatch = func(ctx context.Context, records []*kgo.Record) bool {
+		return check(c.handleBatch(ctx, records))
+	}
+	c.setDispatcher(d)
+	for _, record := range records {
+		if !c.dispatch(ctx, d, record) {
+			failed.Store(true)
+			break
+		}
+	}
+	if !d.flush(ctx, time.Now()) {
+		failed.Store(true)
+	}
+	d.wait()
+
+	try := kgo.TryCommit
+	if failed.Load() || ctx.Err() != nil {
+		try = kgo.TryAbort
+	}
+	endCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalCommitTimeout)
+	defer cancel()
+	committed, err := sess.End(endCtx, try)
+	if err != nil {
+		return false, fmt.Errorf("failed to end transaction: %w", err)
+	}
+	if !committed {
+		c.log.WarnContext(ctx, "Kafka transaction aborted, its records are consumed again",
+			"records", len(records))
+	}
+	return committed, nil
+}
@@ -0,0 +1,56 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/kafka"
+	"go-services/library/kafka/kafkatest"
+	"go-services/library/require"
+)
+
+func TestTransactionalConsumerStopsAfterRepeatedAborts(t *testing.T) {
+	cluster := kafkatest.NewCluster(t, kafkatest.WithTopics(1, "orders"))
+	createTopic(t, cluster, "orders.dlq", map[string]*string{"max.message.bytes": kadm.StringPtr("1")})
+	cluster.ProduceValues(t, "orders", "poison", "valid")
+
+	var calls atomic.Int32
+	handler := func(context.Context, *kgo.Record) error {
+		calls.Add(1)
+		return kafka.Permanent(errors.New("cannot handle record"))
+	}
+	client := cluster.NewClient(t, "group",
+		kafka.WithTopic("orders", handler),
+		kafka.WithTransactionalID("orders-0"),
+		kafka.WithDeadLetterTopic("orders.dlq"),
+	)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+	err := client.Consumer.Run(ctx)
+
+	require.ErrorContains(t, err, "transaction aborted 5 times in a row at topic orders partition 0 offset 0", "run error")
+	assert.Equal(t, calls.Load(), int32(10), "each record is handled once per transaction")
+}
+
+// createTopic creates topic with a single partition and configs.
+func createTopic(t *testing.T, cluster *kafkatest.Cluster, topic string, configs map[string]*string) {
+	t.Helper()
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers(cluster.Brokers()...))
+	require.NoError(t, err, "failed to create admin client")
+	defer cl.Close()
+
+	resp, err := kadm.NewClient(cl).CreateTopic(t.Context(), 1, 1, configs, topic)
+	if err == nil {
+		err = resp.Err
+	}
+	require.NoError(t, err, "failed to create topic %q", topic)
+}
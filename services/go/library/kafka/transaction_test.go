@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"go-services/library/assert"
+)
+
+func TestValidateTransactional(t *testing.T) {
+	tests := map[string]struct {
+		groupId string
+		wantErr string
+		opts    []Option
+	}{
+		"not transactional": {
+			opts:    []Option{WithAckMode(AckModeAtMostOnce), WithRetryTopics(time.Second)},
+			groupId: "",
+			wantErr: "",
+		},
+		"transactional": {
+			opts:    []Option{WithTransactionalID("orders-0"), WithDeadLetterTopic("orders.dlq")},
+			groupId: "group",
+			wantErr: "",
+		},
+		"without group": {
+			opts:    []Option{WithTransactionalID("orders-0")},
+			groupId: "",
+			wantErr: "transactional mode requires a consumer group",
+		},
+		"at most once": {
+			opts:    []Option{WithTransactionalID("orders-0"), WithAckMode(AckModeAtMostOnce)},
+			groupId: "group",
+			wantErr: "transactional mode requires AckModeAtLeastOnce",
+		},
+		"retry topics": {
+			opts:    []Option{WithTransactionalID("orders-0"), WithRetryTopics(time.Second)},
+			groupId: "group",
+			wantErr: "transactional mode does not support retry topics",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig([]string{"broker:9092"}, tt.groupId)
+			for _, opt := range tt.opts {
+				opt(cfg)
+			}
+
+			err := cfg.validateTransactional()
+			if tt.wantErr == "" {
+				assert.NoError(t, err, "validate transactional")
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr, "validate transactional")
+		})
+	}
+}
+
+func TestNewRejectsInvalidTransactionalOptions(t *testing.T) {
+	client, err := New([]string{"broker:9092"}, "group",
+		WithTransactionalID("orders-0"),
+		WithAckMode(AckModeAtMostOnce))
+	assert.Nil(t, client, "client")
+	assert.ErrorContains(t, err, "requires AckModeAtLeastOnce", "should reject at most once in transactional mode")
+}
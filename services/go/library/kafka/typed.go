@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/apperror"
+)
+
+// TypedHandler handles a record value decoded into T.
+type TypedHandler[T any] func(ctx context.Context, value T) error
+
+// DecodeFailure determines what happens to records whose value cannot be
+// decoded.
+type DecodeFailure int
+
+const (
+	// DecodeFailureDeadLetter fails the record with a non-retryable
+	// apperror.CodeInvalidFormat error, so it goes to the dead-letter topic
+	// if one is configured.
+	DecodeFailureDeadLetter DecodeFailure = iota
+
+	// DecodeFailureSkip logs the record and commits it without handling it.
+	DecodeFailureSkip
+)
+
+// NewTypedHandler returns a Handler that decodes record values with codec
+// and passes them to handler. Records that cannot be decoded are treated
+// according to onDecodeFailure and never reach handler. Decode errors that
+// are retryable apperrors, e.g. a schema registry that cannot be reached,
+// are returned unchanged so the record is retried instead.
+func NewTypedHandler[T any](codec Codec[T], handler TypedHandler[T], onDecodeFailure DecodeFailure) Handler {
+	return func(ctx context.Context, record *kgo.Record) error {
+		value, err := codec.Decode(record.Value)
+		if err != nil {
+			if appErr, ok := apperror.As(err); ok && !permanentCodes[appErr.Code] {
+				return err
+			}
+			err = apperror.Wrap(apperror.CodeInvalidFormat, err, "failed to decode record value as %T", value)
+			if onDecodeFailure == DecodeFailureSkip {
+				return Skip(err)
+			}
+			return err
+		}
+		return handler(ctx, value)
+	}
+}
+
+// TypedProducer produces values of type T to a topic, encoded with a codec.
+type TypedProducer[T any] struct {
+	producer *Producer
+	codec    Codec[T]
+	key      func(T) []byte
+	headers  func(T) []kgo.RecordHeader
+	topic    string
+}
+
+// TypedProducerOption configures a TypedProducer.
+type TypedProducerOption[T any] func(*TypedProducer[T])
+
+// WithKeyFunc sets the function returning the record key of a value, e.g.
+// the ID of the entity, so that records of the same entity share a
+// partition. Without it, records have no key.
+func WithKeyFunc[T any](key func(T) []byte) TypedProducerOption[T] {
+	return func(p *TypedProducer[T]) {
+		p.key = key
+	}
+}
+
+// WithHeadersFunc sets the function returning the record headers of a value.
+func WithHeadersFunc[T any](headers func(T) []kgo.RecordHeader) TypedProducerOption[T] {
+	return func(p *TypedProducer[T]) {
+		p.headers = headers
+	}
+}
+
+// NewTypedProducer returns a TypedProducer producing to topic with producer.
+func NewTypedProducer[T any](producer *Producer, topic string, codec Codec[T], opts ...TypedProducerOption[T]) *TypedProducer[T] {
+	p := &TypedProducer[T]{
+		producer: producer,
+		codec:    codec,
+		key:      nil,
+		headers:  nil,
+		topic:    topic,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Record returns the record for value without producing it.
+func (p *TypedProducer[T]) Record(value T) (*kgo.Record, error) {
+	data, err := p.codec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record value for topic %q: %w", p.topic, err)
+	}
+
+	record := &kgo.Record{Topic: p.topic, Value: data}
+	if p.key != nil {
+		record.Key = p.key(value)
+	}
+	if p.headers != nil {
+		record.Headers = p.headers(value)
+	}
+	return record, nil
+}
+
+// Produce encodes value and sends it to Kafka. promise is called with the
+// encoding error, if any, or once the record is acknowledged.
+func (p *TypedProducer[T]) Produce(ctx context.Context, value T, promise func(*kgo.Record, error)) {
+	record, err := p.Record(value)
+	if err != nil {
+		promise(&kgo.Record{Topic: p.topic}, err)
+		return
+	}
+	p.producer.Produce(ctx, record, promise)
+}
+
+// ProduceSync encodes value, sends it to Kafka and waits for it to be
+// acknowledged.
+func (p *TypedProducer[T]) ProduceSync(ctx context.Context, value T) error {
+	record, err := p.Record(value)
+	if err != nil {
+		return err
+	}
+	return p.producer.ProduceSync(ctx, record)
+}
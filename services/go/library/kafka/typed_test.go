@@ -0,0 +1,123 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/apperror"
+	"go-services/library/assert"
+	"go-services/library/require"
+)
+
+type failingCodec struct{}
+
+func (failingCodec) Encode(testEvent) ([]byte, error) {
+	return nil, errors.New("cannot encode")
+}
+
+func (failingCodec) Decode([]byte) (testEvent, error) {
+	return testEvent{ID: "", Name: ""}, errors.New("cannot decode")
+}
+
+type unavailableCodec struct{}
+
+func (unavailableCodec) Encode(testEvent) ([]byte, error) {
+	return nil, apperror.New(apperror.CodeExternalService, "registry unavailable")
+}
+
+func (unavailableCodec) Decode([]byte) (testEvent, error) {
+	return testEvent{ID: "", Name: ""}, apperror.New(apperror.CodeExternalService, "registry unavailable")
+}
+
+func TestNewTypedHandler(t *testing.T) {
+	var got []testEvent
+	handler := func(_ context.Context, event testEvent) error {
+		got = append(got, event)
+		return nil
+	}
+
+	t.Run("decodes value", func(t *testing.T) {
+		got = nil
+		h := NewTypedHandler(JSONCodec[testEvent]{}, handler, DecodeFailureDeadLetter)
+
+		err := h(t.Context(), &kgo.Record{Value: []byte(`{"id":"1","name":"Ada"}`)})
+
+		require.NoError(t, err, "failed to handle record")
+		assert.Equal(t, got, []testEvent{{ID: "1", Name: "Ada"}}, "handled values")
+	})
+
+	tests := map[string]struct {
+		wantSkip        bool
+		onDecodeFailure DecodeFailure
+	}{
+		"dead letter": {wantSkip: false, onDecodeFailure: DecodeFailureDeadLetter},
+		"skip":        {wantSkip: true, onDecodeFailure: DecodeFailureSkip},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got = nil
+			h := NewTypedHandler(JSONCodec[testEvent]{}, handler, tt.onDecodeFailure)
+
+			err := h(t.Context(), &kgo.Record{Value: []byte(`{`)})
+
+			require.ErrorContains(t, err, "failed to decode record value as kafka.testEvent", "decode error")
+			assert.Equal(t, errors.Is(err, ErrSkip), tt.wantSkip, "skipped")
+			assert.False(t, IsRetryable(err), "decode errors are not retried")
+			appErr, ok := apperror.As(err)
+			require.True(t, ok, "decode error is an app error")
+			assert.Equal(t, appErr.Code, apperror.CodeInvalidFormat, "app error code")
+			assert.SliceLen(t, got, 0, "handler not called")
+		})
+	}
+
+	t.Run("retryable decode error", func(t *testing.T) {
+		got = nil
+		h := NewTypedHandler(unavailableCodec{}, handler, DecodeFailureSkip)
+
+		err := h(t.Context(), &kgo.Record{Value: []byte(`{}`)})
+
+		require.ErrorContains(t, err, "registry unavailable", "decode error")
+		assert.False(t, errors.Is(err, ErrSkip), "retryable decode errors are not skipped")
+		assert.True(t, IsRetryable(err), "retryable decode errors are retried")
+		assert.SliceLen(t, got, 0, "handler not called")
+	})
+}
+
+func TestConsumerHandleRecordSkip(t *testing.T) {
+	cfg := newConfig([]string{"broker:9092"}, "group")
+	cfg.retry = DefaultRetryPolicy()
+	calls := 0
+	cfg.topicRouter["orders"] = func(context.Context, *kgo.Record) error {
+		calls++
+		return Skip(errors.New("irrelevant record"))
+	}
+	consumer, err := newConsumer(cfg, nil)
+	require.NoError(t, err, "failed to create consumer")
+
+	assert.True(t, consumer.handleRecord(t.Context(), &kgo.Record{Topic: "orders"}), "skipped record is committed")
+	assert.Equal(t, calls, 1, "skipped record is not retried")
+}
+
+func TestTypedProducerRecord(t *testing.T) {
+	producer := NewTypedProducer(nil, "events", JSONCodec[testEvent]{},
+		WithKeyFunc(func(e testEvent) []byte { return []byte(e.ID) }),
+		WithHeadersFunc(func(e testEvent) []kgo.RecordHeader {
+			return []kgo.RecordHeader{{Key: "name", Value: []byte(e.Name)}}
+		}),
+	)
+
+	record, err := producer.Record(testEvent{ID: "1", Name: "Ada"})
+
+	require.NoError(t, err, "failed to build record")
+	assert.Equal(t, record.Topic, "events", "topic")
+	assert.Equal(t, string(record.Key), "1", "key")
+	assert.Equal(t, string(record.Value), `{"id":"1","name":"Ada"}`, "value")
+	assert.Equal(t, record.Headers, []kgo.RecordHeader{{Key: "name", Value: []byte("Ada")}}, "headers")
+
+	_, err = NewTypedProducer[testEvent](nil, "events", failingCodec{}).Record(testEvent{ID: "1", Name: "Ada"})
+	assert.ErrorContains(t, err, `failed to encode record value for topic "events"`, "encode error")
+}
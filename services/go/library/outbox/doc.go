@@ -0,0 +1,42 @@
+// Package outbox implements the transactional outbox pattern on top of
+// go-services/library/transactor and go-services/library/kafka.
+//
+// Services enqueue Kafka records inside the same database transaction as the
+// state change they announce, so either both are committed or neither is. A
+// Relay then publishes the enqueued records to Kafka and marks them sent.
+//
+// # Usage
+//
+// Apply the outbox migration at startup, next to the service's own
+// migrations:
+//
+//	if err := outbox.Migrate(ctx, pool); err != nil {
+//		return err
+//	}
+//
+// Enqueue records inside Transactor.Atomic:
+//
+//	box := outbox.New(transactor.NewTxAccessor[pgx.Tx]())
+//
+//	err := tr.Atomic(ctx, func(ctx context.Context) error {
+//		if err := repo.UpdateUser(ctx, user); err != nil {
+//			return err
+//		}
+//		return box.Enqueue(ctx, &kgo.Record{Topic: "user-events", Key: user.ID.Bytes(), Value: payload})
+//	})
+//
+// and run a Relay next to the service:
+//
+//	relay := outbox.NewRelay(log, pool, kafkaClient.Producer)
+//	go relay.Run(ctx)
+//
+// # Delivery Guarantees
+//
+// Records are delivered at least once: a relay that stops between producing
+// a record and marking it sent produces it again after a restart. Records
+// with the same topic and key are produced in the order they were enqueued,
+// provided the enqueuing transactions are serialized, e.g. by updating the
+// aggregate's row. A record that fails to be produced holds back the later
+// records of its key until it is produced. Only one relay publishes at a
+// time, so running several instances of a service is safe.
+package outbox
@@ -0,0 +1,48 @@
+//go:build integration
+
+package outbox_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"go-services/library/outbox"
+	"go-services/library/testenv"
+)
+
+var (
+	te        *testenv.TestEnv
+	pg        *testenv.Postgres
+	testKafka *testenv.Kafka
+)
+
+func TestMain(m *testing.M) {
+	te = testenv.New("library_outbox")
+
+	var err error
+	pg, err = testenv.SetupPostgres(te, testenv.WithMigrationTableName(outbox.MigrationTableName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up postgres: %v\n", err)
+		os.Exit(1)
+	}
+	if err := outbox.Migrate(context.Background(), pg.Pool); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to apply outbox migrations: %v\n", err)
+		te.Cleanup()
+		os.Exit(1)
+	}
+
+	testKafka, err = testenv.SetupKafka(te)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up kafka: %v\n", err)
+		te.Cleanup()
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	te.Cleanup()
+
+	os.Exit(code)
+}
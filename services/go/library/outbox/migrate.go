@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"go-services/library/internal/migrate"
+)
+
+// MigrationTableName is the version table of the outbox migrations. It is
+// separate from the service's own migration table, so both can be applied to
+// the same schema independently.
+const MigrationTableName = "outbox_db_version"
+
+//go:embed migrations/*.sql
+var embedMigrations embed.FS
+
+// Migrate applies the outbox migrations to the database of pool.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := fs.Sub(embedMigrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to open outbox migrations: %w", err)
+	}
+	if err := migrate.Apply(ctx, pool, migrations, MigrationTableName); err != nil {
+		return fmt.Errorf("outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox_messages (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    topic TEXT NOT NULL,
+    key BYTEA,
+    value BYTEA,
+    headers JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_messages_unsent_idx ON outbox_messages (id) WHERE sent_at IS NULL;
+CREATE INDEX outbox_messages_sent_at_idx ON outbox_messages (sent_at) WHERE sent_at IS NOT NULL;
+
+CREATE FUNCTION outbox_messages_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_messages', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_messages_notify
+    AFTER INSERT ON outbox_messages
+    FOR EACH STATEMENT EXECUTE FUNCTION outbox_messages_notify();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER outbox_messages_notify ON outbox_messages;
+DROP FUNCTION outbox_messages_notify();
+DROP TABLE outbox_messages;
+-- +goose StatementEnd
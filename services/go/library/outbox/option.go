@@ -0,0 +1,61 @@
+package outbox
+
+import "time"
+
+// relayConfig holds the settings of a Relay.
+type relayConfig struct {
+	pollInterval time.Duration
+	retention    time.Duration
+	batchSize    int
+}
+
+// Default relay settings.
+const (
+	defaultPollInterval = time.Second
+	defaultRetention    = 7 * 24 * time.Hour
+	defaultBatchSize    = 100
+)
+
+func newRelayConfig(opts ...RelayOption) relayConfig {
+	cfg := relayConfig{
+		pollInterval: defaultPollInterval,
+		retention:    defaultRetention,
+		batchSize:    defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// RelayOption configures a Relay.
+type RelayOption func(*relayConfig)
+
+// WithPollInterval sets how often the relay looks for unsent records when
+// it is not notified of new ones. It defaults to one second; non-positive
+// values are ignored.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		if d > 0 {
+			c.pollInterval = d
+		}
+	}
+}
+
+// WithBatchSize sets how many records the relay publishes per transaction.
+// It defaults to 100; non-positive values are ignored.
+func WithBatchSize(n int) RelayOption {
+	return func(c *relayConfig) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithRetention sets how long sent records are kept before the relay
+// deletes them. Zero keeps them forever. It defaults to seven days.
+func WithRetention(d time.Duration) RelayOption {
+	return func(c *relayConfig) {
+		c.retention = d
+	}
+}
@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/apperror"
+	"go-services/library/transactor"
+)
+
+// header is a record header as stored in the headers column.
+type header struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Outbox enqueues Kafka records in the transaction of the context.
+type Outbox struct {
+	accessor transactor.TXAccessor[pgx.Tx]
+}
+
+// New returns an Outbox that writes through the transactions accessor
+// retrieves.
+func New(accessor transactor.TXAccessor[pgx.Tx]) *Outbox {
+	return &Outbox{
+		accessor: accessor,
+	}
+}
+
+// Enqueue stores records to be produced once the transaction of ctx is
+// committed. It must be called inside Transactor.Atomic; records enqueued
+// by a transaction that is rolled back are never produced. Only the topic,
+// key, value and headers of records are kept.
+func (o *Outbox) Enqueue(ctx context.Context, records ...*kgo.Record) error {
+	tx, ok := o.accessor.GetTx(ctx)
+	if !ok {
+		return apperror.New(apperror.CodeDBTransaction, "outbox records must be enqueued inside a transaction")
+	}
+
+	batch := &pgx.Batch{}
+	for _, record := range records {
+		headers := make([]header, len(record.Headers))
+		for i, h := range record.Headers {
+			headers[i] = header{Key: h.Key, Value: h.Value}
+		}
+		encoded, err := json.Marshal(headers)
+		if err != nil {
+			return apperror.Wrap(apperror.CodeSerializationError, err, "failed to encode headers of outbox record")
+		}
+		batch.Queue(
+			"INSERT INTO outbox_messages (topic, key, value, headers) VALUES ($1, $2, $3, $4)",
+			record.Topic,
+			record.Key,
+			record.Value,
+			encoded,
+		)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return apperror.Wrap(apperror.CodeInternalError, err, "failed to enqueue outbox records")
+	}
+	return nil
+}
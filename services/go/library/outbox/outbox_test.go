@@ -0,0 +1,222 @@
+//go:build integration
+
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/kafka"
+	"go-services/library/outbox"
+	"go-services/library/require"
+	"go-services/library/transactor"
+)
+
+func newOutbox() (*transactor.PGTransactor, *outbox.Outbox) {
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return transactor.NewPGTransactor(log, pg.Pool), outbox.New(transactor.NewTxAccessor[pgx.Tx]())
+}
+
+func countUnsent(ctx context.Context, t *testing.T) int {
+	t.Helper()
+
+	var count int
+	err := pg.Pool.QueryRow(ctx, "SELECT count(*) FROM outbox_messages WHERE sent_at IS NULL").Scan(&count)
+	require.NoError(t, err, "failed to count unsent outbox records")
+	return count
+}
+
+// runRelay runs relay until the returned function is called.
+func runRelay(ctx context.Context, t *testing.T, relay *outbox.Relay) func() {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := relay.Run(ctx); err != nil {
+			t.Errorf("relay run failed: %v", err)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestOutboxEnqueue(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(pg.CleanupData)
+	tr, box := newOutbox()
+
+	err := tr.Atomic(ctx, func(ctx context.Context) error {
+		return box.Enqueue(ctx, &kgo.Record{Topic: "users", Key: []byte("1"), Value: []byte("created")})
+	})
+	require.NoError(t, err, "failed to enqueue in committed transaction")
+
+	err = tr.Atomic(ctx, func(ctx context.Context) error {
+		if err := box.Enqueue(ctx, &kgo.Record{Topic: "users", Key: []byte("2"), Value: []byte("created")}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err, "rolled back transaction")
+
+	assert.Error(t, box.Enqueue(ctx, &kgo.Record{Topic: "users"}), "enqueue outside a transaction")
+	assert.Equal(t, countUnsent(ctx, t), 1, "only records of committed transactions are stored")
+}
+
+func TestRelayPublishes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	t.Cleanup(pg.CleanupData)
+
+	topic := fmt.Sprintf("test-outbox-%d", time.Now().UnixNano())
+	require.NoError(t, testKafka.CreateTopic(ctx, topic), "failed to create test topic")
+
+	client, err := kafka.New(testKafka.PlainBrokers, "test-outbox")
+	require.NoError(t, err, "failed to create kafka client")
+	defer client.Close()
+
+	tr, box := newOutbox()
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	stop := runRelay(ctx, t, outbox.NewRelay(log, pg.Pool, client.Producer, outbox.WithBatchSize(2)))
+	defer stop()
+
+	for i := range 5 {
+		err := tr.Atomic(ctx, func(ctx context.Context) error {
+			return box.Enqueue(ctx, &kgo.Record{
+				Topic:   topic,
+				Key:     []byte("user-1"),
+				Value:   fmt.Appendf(nil, "event-%d", i),
+				Headers: []kgo.RecordHeader{{Key: "seq", Value: fmt.Appendf(nil, "%d", i)}},
+			})
+		})
+		require.NoError(t, err, "failed to enqueue record %d", i)
+	}
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(testKafka.PlainBrokers...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	require.NoError(t, err, "failed to create consumer")
+	defer consumer.Close()
+
+	var values []string
+	for len(values) < 5 {
+		fetches := consumer.PollFetches(ctx)
+		require.NoError(t, ctx.Err(), "timed out waiting for records, got %v", values)
+		fetches.EachRecord(func(record *kgo.Record) {
+			values = append(values, string(record.Value))
+			assert.Equal(t, string(record.Key), "user-1", "record key")
+			assert.Equal(t, record.Headers, []kgo.RecordHeader{
+				{Key: "seq", Value: record.Value[len("event-"):]},
+			}, "record headers")
+		})
+	}
+	assert.Equal(t, values, []string{"event-0", "event-1", "event-2", "event-3", "event-4"}, "records in enqueue order")
+
+	stop()
+	assert.Equal(t, countUnsent(ctx, t), 0, "unsent records after relaying")
+}
+
+// flakyProducer fails the first attempt to produce each record with the
+// value in failOnce.
+type flakyProducer struct {
+	failOnce map[string]bool
+	produced []string
+	mu       sync.Mutex
+}
+
+func (p *flakyProducer) Produce(_ context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+	p.mu.Lock()
+	value := string(record.Value)
+	p.produced = append(p.produced, value)
+	fail := p.failOnce[value]
+	delete(p.failOnce, value)
+	p.mu.Unlock()
+
+	if fail {
+		promise(record, errors.New("broker unavailable"))
+		return
+	}
+	promise(record, nil)
+}
+
+func (p *flakyProducer) producedValues() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.produced...)
+}
+
+func TestRelayRetriesInKeyOrder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	t.Cleanup(pg.CleanupData)
+
+	tr, box := newOutbox()
+	err := tr.Atomic(ctx, func(ctx context.Context) error {
+		return box.Enqueue(ctx,
+			&kgo.Record{Topic: "users", Key: []byte("a"), Value: []byte("a1")},
+			&kgo.Record{Topic: "users", Key: []byte("b"), Value: []byte("b1")},
+			&kgo.Record{Topic: "users", Key: []byte("a"), Value: []byte("a2")},
+		)
+	})
+	require.NoError(t, err, "failed to enqueue records")
+
+	producer := &flakyProducer{failOnce: map[string]bool{"a1": true}, produced: nil, mu: sync.Mutex{}}
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	stop := runRelay(ctx, t, outbox.NewRelay(log, pg.Pool, producer, outbox.WithPollInterval(10*time.Millisecond)))
+	for countUnsent(ctx, t) > 0 {
+		require.NoError(t, ctx.Err(), "timed out waiting for records to be sent")
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+
+	produced := producer.producedValues()
+	keyA := slices.DeleteFunc(slices.Clone(produced), func(value string) bool { return value[0] != 'a' })
+	assert.Equal(t, keyA, []string{"a1", "a1", "a2"}, "a2 is only produced after a1")
+	assert.SliceContains(t, produced, "b1", "records of other keys are produced")
+}
+
+func TestRelayRetention(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	t.Cleanup(pg.CleanupData)
+
+	_, err := pg.Pool.Exec(ctx, `
+		INSERT INTO outbox_messages (topic, value, sent_at) VALUES
+			('users', 'old', now() - interval '2 hours'),
+			('users', 'recent', now() - interval '10 minutes')`)
+	require.NoError(t, err, "failed to insert sent records")
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	relay := outbox.NewRelay(log, pg.Pool, &flakyProducer{failOnce: nil, produced: nil, mu: sync.Mutex{}}, outbox.WithRetention(time.Hour))
+	stop := runRelay(ctx, t, relay)
+	defer stop()
+
+	for {
+		rows, err := pg.Pool.Query(ctx, "SELECT convert_from(value, 'UTF8') FROM outbox_messages ORDER BY id")
+		require.NoError(t, err, "failed to query outbox records")
+		values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		require.NoError(t, err, "failed to read outbox records")
+		if len(values) == 1 {
+			assert.Equal(t, values, []string{"recent"}, "records kept")
+			return
+		}
+		require.NoError(t, ctx.Err(), "timed out waiting for old records to be deleted")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,282 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// notifyChannel is the channel the outbox_messages insert trigger notifies.
+const notifyChannel = "outbox_messages"
+
+// relayLockKey is the advisory lock held by the relay publishing a batch,
+// so only one relay publishes at a time and records with the same key stay
+// in order.
+const relayLockKey int64 = 0x6f7574626f78 // "outbox"
+
+// Producer produces records to Kafka. *kafka.Producer implements it.
+type Producer interface {
+	Produce(ctx context.Context, record *kgo.Record, promise func(*kgo.Record, error))
+}
+
+// message is an unsent outbox record.
+type message struct {
+	record *kgo.Record
+	id     int64
+}
+
+// orderKey identifies the records that must be produced in order.
+type orderKey struct {
+	topic string
+	key   string
+}
+
+// Relay publishes enqueued records to Kafka.
+type Relay struct {
+	log      *slog.Logger
+	pool     *pgxpool.Pool
+	producer Producer
+	cfg      relayConfig
+}
+
+// NewRelay returns a Relay publishing the records enqueued in the database
+// of pool with producer.
+func NewRelay(log *slog.Logger, pool *pgxpool.Pool, producer Producer, opts ...RelayOption) *Relay {
+	return &Relay{
+		log:      log,
+		pool:     pool,
+		producer: producer,
+		cfg:      newRelayConfig(opts...),
+	}
+}
+
+// Run publishes records until ctx is done. It publishes as soon as records
+// are enqueued, and every poll interval in case a notification was missed.
+// Failures are logged and retried on the next poll.
+func (r *Relay) Run(ctx context.Context) error {
+	r.log.InfoContext(ctx, "Starting outbox relay",
+		"poll_interval", r.cfg.pollInterval,
+		"batch_size", r.cfg.batchSize,
+		"retention", r.cfg.retention,
+	)
+
+	var listener *pgxpool.Conn
+	defer func() {
+		if listener != nil {
+			listener.Release()
+		}
+	}()
+
+	for {
+		sent, err := r.relayBatch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			r.log.ErrorContext(ctx, "Failed to relay outbox records", "err", err)
+		}
+		if err == nil && sent == r.cfg.batchSize {
+			continue
+		}
+		if err := r.prune(ctx); err != nil && ctx.Err() == nil {
+			r.log.ErrorContext(ctx, "Failed to delete sent outbox records", "err", err)
+		}
+
+		listener = r.wait(ctx, listener)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// wait blocks until records are enqueued or the poll interval passes. It
+// returns the connection listening for notifications, which it acquires if
+// listener is nil. Without a listening connection it only polls.
+func (r *Relay) wait(ctx context.Context, listener *pgxpool.Conn) *pgxpool.Conn {
+	if listener == nil {
+		var err error
+		if listener, err = r.listen(ctx); err != nil {
+			if ctx.Err() == nil {
+				r.log.WarnContext(ctx, "Failed to listen for outbox records, polling only", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.cfg.pollInterval):
+			}
+			return nil
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, r.cfg.pollInterval)
+	defer cancel()
+	_, err := listener.Conn().WaitForNotification(waitCtx)
+	if err != nil && waitCtx.Err() == nil {
+		// The connection is broken; listen on a new one next time.
+		r.log.WarnContext(ctx, "Lost outbox notification connection", "err", err)
+		listener.Release()
+		return nil
+	}
+	return listener
+}
+
+// listen acquires a connection listening for enqueued records.
+func (r *Relay) listen(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to listen on %s: %w", notifyChannel, err)
+	}
+	return conn, nil
+}
+
+// relayBatch publishes up to a batch of unsent records in enqueue order and
+// marks the produced ones sent. It returns how many records it marked. If
+// another relay is publishing, it returns immediately.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	sent := 0
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", relayLockKey).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to acquire relay lock: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		messages, err := r.unsent(ctx, tx)
+		if err != nil {
+			return err
+		}
+		ids := r.publish(ctx, messages)
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE outbox_messages SET sent_at = now() WHERE id = ANY($1)", ids); err != nil {
+			return fmt.Errorf("failed to mark outbox records sent: %w", err)
+		}
+		sent = len(ids)
+		return nil
+	})
+	return sent, err
+}
+
+// unsent returns the oldest unsent records.
+func (r *Relay) unsent(ctx context.Context, tx pgx.Tx) ([]message, error) {
+	rows, err := tx.Query(ctx,
+		"SELECT id, topic, key, value, headers FROM outbox_messages WHERE sent_at IS NULL ORDER BY id LIMIT $1",
+		r.cfg.batchSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox records: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []message
+	for rows.Next() {
+		var (
+			m       message
+			record  kgo.Record
+			headers []byte
+		)
+		if err := rows.Scan(&m.id, &record.Topic, &record.Key, &record.Value, &headers); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox record: %w", err)
+		}
+
+		var decoded []header
+		if err := json.Unmarshal(headers, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode headers of outbox record %d: %w", m.id, err)
+		}
+		for _, h := range decoded {
+			record.Headers = append(record.Headers, kgo.RecordHeader{Key: h.Key, Value: h.Value})
+		}
+
+		m.record = &record
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox records: %w", err)
+	}
+	return messages, nil
+}
+
+// publish produces messages and returns the IDs of those that can be marked
+// sent, in ascending order. Records with the same topic and key are produced
+// one after another, and those after a failed one are not produced at all,
+// so they are produced after it on the next attempt and consumers never see
+// them out of order. Records of different keys are produced concurrently.
+func (r *Relay) publish(ctx context.Context, messages []message) []int64 {
+	var sequences [][]message
+	index := make(map[orderKey]int)
+	for _, m := range messages {
+		key := orderKey{topic: m.record.Topic, key: string(m.record.Key)}
+		i, ok := index[key]
+		if !ok {
+			i = len(sequences)
+			index[key] = i
+			sequences = append(sequences, nil)
+		}
+		sequences[i] = append(sequences[i], m)
+	}
+
+	sent := make([][]int64, len(sequences))
+	var wg sync.WaitGroup
+	for i, sequence := range sequences {
+		wg.Go(func() { sent[i] = r.produceInOrder(ctx, sequence) })
+	}
+	wg.Wait()
+
+	ids := slices.Concat(sent...)
+	slices.Sort(ids)
+	return ids
+}
+
+// produceInOrder produces messages, which share a topic and key, one after
+// another until one fails, and returns the IDs of those produced.
+func (r *Relay) produceInOrder(ctx context.Context, messages []message) []int64 {
+	ids := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		done := make(chan error, 1)
+		r.producer.Produce(ctx, m.record, func(_ *kgo.Record, err error) {
+			done <- err
+		})
+		if err := <-done; err != nil {
+			if ctx.Err() == nil {
+				r.log.WarnContext(ctx, "Failed to produce outbox record",
+					"id", m.id,
+					"topic", m.record.Topic,
+					"err", err,
+				)
+			}
+			return ids
+		}
+		ids = append(ids, m.id)
+	}
+	return ids
+}
+
+// prune deletes records sent longer ago than the retention.
+func (r *Relay) prune(ctx context.Context) error {
+	if r.cfg.retention <= 0 {
+		return nil
+	}
+	_, err := r.pool.Exec(ctx,
+		"DELETE FROM outbox_messages WHERE sent_at < now() - make_interval(secs => $1)",
+		r.cfg.retention.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete sent outbox records: %w", err)
+	}
+	return nil
+}
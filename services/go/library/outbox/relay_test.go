@@ -0,0 +1,114 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"go-services/library/assert"
+	"go-services/library/testlogger"
+)
+
+// fakeProducer records produced records and fails those fail returns true
+// for.
+type fakeProducer struct {
+	fail     func(record *kgo.Record) bool
+	produced []*kgo.Record
+	mu       sync.Mutex
+}
+
+func (p *fakeProducer) Produce(_ context.Context, record *kgo.Record, promise func(*kgo.Record, error)) {
+	p.mu.Lock()
+	p.produced = append(p.produced, record)
+	p.mu.Unlock()
+	if p.fail != nil && p.fail(record) {
+		promise(record, errors.New("broker unavailable"))
+		return
+	}
+	promise(record, nil)
+}
+
+// producedByKey returns the values of records per topic and key, in the
+// order they were produced.
+func producedByKey(records []*kgo.Record) map[string][]string {
+	values := make(map[string][]string)
+	for _, record := range records {
+		key := record.Topic + "/" + string(record.Key)
+		values[key] = append(values[key], string(record.Value))
+	}
+	return values
+}
+
+func TestRelayPublish(t *testing.T) {
+	messages := []message{
+		{id: 1, record: &kgo.Record{Topic: "users", Key: []byte("a"), Value: []byte("a1")}},
+		{id: 2, record: &kgo.Record{Topic: "users", Key: []byte("b"), Value: []byte("b1")}},
+		{id: 3, record: &kgo.Record{Topic: "users", Key: []byte("a"), Value: []byte("a2")}},
+		{id: 4, record: &kgo.Record{Topic: "orders", Key: []byte("a"), Value: []byte("a1")}},
+		{id: 5, record: &kgo.Record{Topic: "users", Key: []byte("a"), Value: []byte("a3")}},
+		{id: 6, record: &kgo.Record{Topic: "users", Key: []byte("b"), Value: []byte("b2")}},
+	}
+
+	tests := map[string]struct {
+		fail         func(record *kgo.Record) bool
+		wantIDs      []int64
+		wantProduced int
+		wantLog      int
+	}{
+		"all produced": {
+			fail:         nil,
+			wantIDs:      []int64{1, 2, 3, 4, 5, 6},
+			wantProduced: 6,
+			wantLog:      0,
+		},
+		"failed record holds back later records of its key": {
+			fail: func(record *kgo.Record) bool {
+				return record.Topic == "users" && string(record.Value) == "a2"
+			},
+			wantIDs:      []int64{1, 2, 4, 6},
+			wantProduced: 5,
+			wantLog:      1,
+		},
+		"first record of a key failed": {
+			fail: func(record *kgo.Record) bool {
+				return string(record.Key) == "b"
+			},
+			wantIDs:      []int64{1, 3, 4, 5},
+			wantProduced: 5,
+			wantLog:      1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, capture := testlogger.New()
+			producer := &fakeProducer{fail: tt.fail, produced: nil, mu: sync.Mutex{}}
+			relay := NewRelay(log, nil, producer)
+
+			ids := relay.publish(t.Context(), messages)
+
+			assert.Equal(t, ids, tt.wantIDs, "ids marked sent")
+			assert.SliceLen(t, producer.produced, tt.wantProduced, "produced records")
+			for key, values := range producedByKey(producer.produced) {
+				assert.True(t, slices.IsSorted(values), "records of %s produced in order: %v", key, values)
+			}
+			logs := testlogger.Assert(t, capture.GetOutput()).Count(tt.wantLog, "failure logs")
+			if tt.wantLog > 0 {
+				logs.AtIndex(0, slog.LevelWarn, "Failed to produce outbox record", "failure log")
+			}
+		})
+	}
+}
+
+func TestRelayOptionsIgnoreNonPositiveValues(t *testing.T) {
+	cfg := newRelayConfig(WithBatchSize(0), WithBatchSize(-1), WithPollInterval(0), WithPollInterval(-time.Second))
+
+	assert.Equal(t, cfg.batchSize, defaultBatchSize, "batch size")
+	assert.Equal(t, cfg.pollInterval, defaultPollInterval, "poll interval")
+}